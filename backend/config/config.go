@@ -10,6 +10,15 @@ type Config struct {
 	Server   ServerConfig   `json:"server"`
 	Database DatabaseConfig `json:"database"`
 	Email    EmailConfig    `json:"email"`
+	Receiver ReceiverConfig `json:"receiver"`
+
+	ProvidersFile string `json:"providers_file"` // 邮箱服务商配置文件，在内置配置基础上追加或覆盖
+
+	SelfMonitor SelfMonitorConfig `json:"self_monitor"`
+
+	Timezone string `json:"timezone"` // 系统时区（IANA名称），用于展示时间和未单独配置时区的渠道/用户
+
+	Proxy string `json:"proxy"` // 全局代理（socks5://或http://），用于未单独配置代理的邮箱和通知渠道
 }
 
 // ServerConfig 服务器配置
@@ -42,6 +51,32 @@ type EmailConfig struct {
 	UseTLS       bool   `json:"use_tls"`
 }
 
+// ReceiverConfig 内置SMTP/LMTP接收服务配置
+type ReceiverConfig struct {
+	Enabled           bool   `json:"enabled"`             // 是否启用接收服务
+	SMTPAddr          string `json:"smtp_addr"`           // SMTP监听地址，为空时不启用SMTP
+	LMTPAddr          string `json:"lmtp_addr"`           // LMTP监听地址（TCP地址或Unix socket路径），为空时不启用LMTP
+	Hostname          string `json:"hostname"`            // 问候语中使用的主机名
+	MaxMessageBytes   int    `json:"max_message_bytes"`   // 单封邮件大小上限（字节）
+	MaxRecipients     int    `json:"max_recipients"`      // 单封邮件收件人数量上限
+	Username          string `json:"username"`            // 认证用户名，为空时不要求认证
+	Password          string `json:"password"`            // 认证密码
+	AllowedNetworks   string `json:"allowed_networks"`    // 允许连接的网段，逗号分隔，为空表示不限制
+	TLSCertFile       string `json:"tls_cert_file"`       // STARTTLS证书文件
+	TLSKeyFile        string `json:"tls_key_file"`        // STARTTLS私钥文件
+	AllowInsecureAuth bool   `json:"allow_insecure_auth"` // 是否允许在未加密连接上认证
+}
+
+// SelfMonitorConfig 平台自监控配置，平台自身异常时通过管理员渠道发送内部告警
+type SelfMonitorConfig struct {
+	Channels              string `json:"channels"`                // 接收平台告警的渠道ID，逗号分隔，为空时不启用
+	CheckInterval         int    `json:"check_interval"`          // 检查间隔（秒）
+	RepeatInterval        int    `json:"repeat_interval"`         // 问题持续存在时重复通知的间隔（分钟）
+	StallThreshold        int    `json:"stall_threshold"`         // 邮箱连续失败超过该时长视为监控停滞（分钟）
+	ChannelFailureWindow  int    `json:"channel_failure_window"`  // 渠道连续发送失败超过该时长时告警（分钟）
+	QueueThresholdPercent int    `json:"queue_threshold_percent"` // 分发队列使用率超过该百分比时告警
+}
+
 // getEnv 获取环境变量，如果不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -97,5 +132,30 @@ func LoadConfig() *Config {
 			UseSSL:       getEnvAsBool("SMTP_USE_SSL", false),
 			UseTLS:       getEnvAsBool("SMTP_USE_TLS", true),
 		},
+		Receiver: ReceiverConfig{
+			Enabled:           getEnvAsBool("SMTP_RECEIVER_ENABLED", false),
+			SMTPAddr:          getEnv("SMTP_RECEIVER_ADDR", ":2525"),
+			LMTPAddr:          getEnv("LMTP_RECEIVER_ADDR", ""),
+			Hostname:          getEnv("SMTP_RECEIVER_HOSTNAME", "localhost"),
+			MaxMessageBytes:   getEnvAsInt("SMTP_RECEIVER_MAX_SIZE", 10*1024*1024),
+			MaxRecipients:     getEnvAsInt("SMTP_RECEIVER_MAX_RECIPIENTS", 50),
+			Username:          getEnv("SMTP_RECEIVER_USERNAME", ""),
+			Password:          getEnv("SMTP_RECEIVER_PASSWORD", ""),
+			AllowedNetworks:   getEnv("SMTP_RECEIVER_ALLOWED_NETWORKS", ""),
+			TLSCertFile:       getEnv("SMTP_RECEIVER_TLS_CERT", ""),
+			TLSKeyFile:        getEnv("SMTP_RECEIVER_TLS_KEY", ""),
+			AllowInsecureAuth: getEnvAsBool("SMTP_RECEIVER_INSECURE_AUTH", false),
+		},
+		ProvidersFile: getEnv("MAIL_PROVIDERS_FILE", "./config/providers.json"),
+		SelfMonitor: SelfMonitorConfig{
+			Channels:              getEnv("SELF_MONITOR_CHANNELS", ""),
+			CheckInterval:         getEnvAsInt("SELF_MONITOR_INTERVAL", 60),
+			RepeatInterval:        getEnvAsInt("SELF_MONITOR_REPEAT_INTERVAL", 60),
+			StallThreshold:        getEnvAsInt("SELF_MONITOR_STALL_THRESHOLD", 30),
+			ChannelFailureWindow:  getEnvAsInt("SELF_MONITOR_CHANNEL_FAILURE_WINDOW", 60),
+			QueueThresholdPercent: getEnvAsInt("SELF_MONITOR_QUEUE_THRESHOLD", 80),
+		},
+		Timezone: getEnv("TIMEZONE", "Asia/Shanghai"),
+		Proxy:    getEnv("PROXY_URL", ""),
 	}
 }
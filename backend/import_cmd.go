@@ -0,0 +1,126 @@
+package main
+
+import (
+	"emailAlert/config"
+	"emailAlert/internal/repository"
+	"emailAlert/internal/service"
+	"emailAlert/pkg/email"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runImportCommand 命令行导入邮件文件
+// 用法: emailAlert import -mailbox 1 [-format auto|eml|mbox|maildir] [-folder INBOX] 路径...
+// 告警会写入数据库，通知由运行中的服务在处理待发送告警时发出
+func runImportCommand(args []string) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	mailboxID := flags.Uint("mailbox", 0, "导入到的邮箱ID（必填）")
+	format := flags.String("format", email.ImportFormatAuto, "文件格式：auto/eml/mbox/maildir，目录默认按Maildir处理")
+	folder := flags.String("folder", "INBOX", "邮件所属文件夹，用于folder条件匹配")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "用法: emailAlert import -mailbox <邮箱ID> [选项] <.eml/mbox文件或Maildir目录>...")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *mailboxID == 0 || flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg := config.LoadConfig()
+	setupTimezone(cfg)
+	db, err := repository.NewDatabase(cfg)
+	if err != nil {
+		log.Printf("数据库连接失败: %v", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := db.AutoMigrate(); err != nil {
+		log.Printf("数据库迁移失败: %v", err)
+		return 1
+	}
+
+	emailMonitorService := newImportEmailMonitorService(db)
+
+	total := &service.ImportResult{}
+	exitCode := 0
+	for _, path := range flags.Args() {
+		result, err := importPath(emailMonitorService, uint(*mailboxID), path, *format, *folder)
+		if result != nil {
+			total.Merge(result)
+			fmt.Printf("%s: 共 %d 封, 处理 %d 封, 匹配 %d 封, 跳过 %d 封, 失败 %d 封\n",
+				path, result.Total, result.Processed, result.Matched, result.Skipped, result.Failed)
+		}
+		if err != nil {
+			log.Printf("导入 %s 失败: %v", path, err)
+			exitCode = 1
+		}
+	}
+
+	for _, msg := range total.Errors {
+		fmt.Println("  " + msg)
+	}
+	fmt.Printf("导入完成: 共 %d 封, 处理 %d 封, 匹配 %d 封, 跳过 %d 封, 失败 %d 封\n",
+		total.Total, total.Processed, total.Matched, total.Skipped, total.Failed)
+	if total.Failed > 0 {
+		exitCode = 1
+	}
+	return exitCode
+}
+
+// importPath 导入单个文件或Maildir目录
+func importPath(emailMonitorService *service.EmailMonitorService, mailboxID uint, path, format, folder string) (*service.ImportResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() || format == email.ImportFormatMaildir {
+		return emailMonitorService.ImportMaildir(mailboxID, path, folder)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return emailMonitorService.ImportEmails(mailboxID, path, format, folder, file)
+}
+
+// newImportEmailMonitorService 创建导入使用的邮件监控服务（不启动监控）
+func newImportEmailMonitorService(db *repository.Database) *service.EmailMonitorService {
+	mailboxRepo := repository.NewMailboxRepository(db.GetDB())
+	alertRepo := repository.NewAlertRepository(db.GetDB())
+	templateRepo := repository.NewTemplateRepository(db.GetDB())
+	channelRepo := repository.NewChannelRepository(db.GetDB())
+	ruleChannelRepo := repository.NewRuleChannelRepository(db.GetDB())
+	notificationLogRepo := repository.NewNotificationLogRepository(db.GetDB())
+	ruleGroupRepo := repository.NewRuleGroupRepository(db.GetDB())
+	matchConditionRepo := repository.NewMatchConditionRepository(db.GetDB())
+	ruleGroupChannelRepo := repository.NewRuleGroupChannelRepository(db.GetDB())
+	checkpointRepo := repository.NewMailboxCheckpointRepository(db.GetDB())
+
+	enhancedRuleEngineService := service.NewEnhancedRuleEngineService(ruleGroupRepo, matchConditionRepo, *alertRepo)
+	notificationDispatcherService := service.NewNotificationDispatcherService(
+		ruleChannelRepo,
+		ruleGroupChannelRepo,
+		notificationLogRepo,
+		alertRepo,
+		service.NewChannelService(channelRepo),
+		service.NewTemplateService(templateRepo),
+	)
+
+	return service.NewEmailMonitorService(
+		mailboxRepo,
+		alertRepo,
+		checkpointRepo,
+		enhancedRuleEngineService,
+		notificationDispatcherService,
+	)
+}
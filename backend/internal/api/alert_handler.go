@@ -4,6 +4,7 @@ import (
 	"emailAlert/internal/service"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -55,6 +56,23 @@ func (h *AlertHandler) GetAlerts(c *gin.Context) {
 	if sender := c.Query("sender"); sender != "" {
 		filters["sender"] = sender
 	}
+	// 按标签筛选，格式为 label=名称:值，可以指定多个
+	if labelParams := c.QueryArray("label"); len(labelParams) > 0 {
+		labels := make(map[string]string)
+		for _, param := range labelParams {
+			name, value, ok := strings.Cut(param, ":")
+			if !ok || strings.TrimSpace(name) == "" {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"code":    400,
+					"message": "标签筛选格式应为 名称:值",
+					"data":    nil,
+				})
+				return
+			}
+			labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+		filters["labels"] = labels
+	}
 
 	// 获取排序参数
 	sortBy := c.DefaultQuery("sort_by", "created_at")
@@ -5,6 +5,7 @@ import (
 
 	"emailAlert/internal/model"
 	"emailAlert/internal/service"
+	"emailAlert/pkg/timezone"
 
 	"github.com/gin-gonic/gin"
 )
@@ -90,6 +91,7 @@ func (h *AuthHandler) Profile(c *gin.Context) {
 	c.JSON(http.StatusOK, SuccessResponse("获取用户信息成功", gin.H{
 		"username": user.Username,
 		"role":     user.Role,
+		"timezone": timezone.Resolve(user.Timezone).String(),
 	}))
 }
 
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"emailAlert/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BackfillHandler 历史回溯API处理器
+type BackfillHandler struct {
+	backfillService *service.BackfillService
+}
+
+// NewBackfillHandler 创建历史回溯API处理器实例
+func NewBackfillHandler(backfillService *service.BackfillService) *BackfillHandler {
+	return &BackfillHandler{
+		backfillService: backfillService,
+	}
+}
+
+// StartBackfill 创建历史回溯任务
+// @Summary 创建历史回溯任务
+// @Description 获取邮箱指定文件夹和时间范围内的邮件并交给规则引擎处理，支持试运行
+// @Tags 历史回溯
+// @Accept json
+// @Produce json
+// @Param id path int true "邮箱ID"
+// @Param request body service.BackfillRequest true "回溯参数"
+// @Success 200 {object} APIResponse
+// @Failure 400 {object} APIResponse
+// @Router /api/v1/mailboxes/{id}/backfill [post]
+func (h *BackfillHandler) StartBackfill(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "无效的邮箱ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	var req service.BackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "请求参数错误",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	job, err := h.backfillService.StartBackfill(uint(id), &req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "创建回溯任务失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "回溯任务已开始",
+		Data:    job,
+	})
+}
+
+// GetBackfillJobs 获取历史回溯任务列表
+// @Summary 获取历史回溯任务列表
+// @Description 获取历史回溯任务及其进度，可按邮箱过滤
+// @Tags 历史回溯
+// @Produce json
+// @Param mailbox_id query int false "邮箱ID"
+// @Success 200 {object} APIResponse
+// @Router /api/v1/backfill-jobs [get]
+func (h *BackfillHandler) GetBackfillJobs(c *gin.Context) {
+	var mailboxID uint64
+	if value := c.Query("mailbox_id"); value != "" {
+		var err error
+		if mailboxID, err = strconv.ParseUint(value, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse{
+				Code:    400,
+				Message: "无效的邮箱ID",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "获取回溯任务列表成功",
+		Data:    h.backfillService.ListJobs(uint(mailboxID)),
+	})
+}
+
+// GetBackfillJob 获取历史回溯任务详情
+// @Summary 获取历史回溯任务详情
+// @Description 获取历史回溯任务的进度和匹配明细
+// @Tags 历史回溯
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} APIResponse
+// @Failure 404 {object} APIResponse
+// @Router /api/v1/backfill-jobs/{id} [get]
+func (h *BackfillHandler) GetBackfillJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "无效的任务ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	job, err := h.backfillService.GetJob(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, APIResponse{
+			Code:    404,
+			Message: "获取回溯任务失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "获取回溯任务成功",
+		Data:    job,
+	})
+}
+
+// CancelBackfillJob 取消历史回溯任务
+// @Summary 取消历史回溯任务
+// @Description 取消正在运行的历史回溯任务，已处理的邮件和已创建的告警不会回滚
+// @Tags 历史回溯
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} APIResponse
+// @Failure 400 {object} APIResponse
+// @Router /api/v1/backfill-jobs/{id}/cancel [post]
+func (h *BackfillHandler) CancelBackfillJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "无效的任务ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := h.backfillService.CancelJob(uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "取消回溯任务失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "回溯任务正在取消",
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -30,6 +31,8 @@ type CreateChannelRequest struct {
 	Status      string `json:"status"`
 	Description string `json:"description"`
 	TemplateID  *uint  `json:"template_id"` // 关联的模版ID
+	Timezone    string `json:"timezone"`    // 展示时区，为空时使用系统时区
+	Proxy       string `json:"proxy"`       // 代理地址，为空时使用全局代理，direct表示直连
 }
 
 // UpdateChannelRequest 更新渠道请求
@@ -40,12 +43,15 @@ type UpdateChannelRequest struct {
 	Status      string `json:"status"`
 	Description string `json:"description"`
 	TemplateID  *uint  `json:"template_id"` // 关联的模版ID
+	Timezone    string `json:"timezone"`    // 展示时区，为空时使用系统时区
+	Proxy       string `json:"proxy"`       // 代理地址，为空时使用全局代理，direct表示直连
 }
 
 // TestChannelConfigRequest 测试渠道配置请求
 type TestChannelConfigRequest struct {
 	Type   string `json:"type" binding:"required"`
 	Config string `json:"config" binding:"required"`
+	Proxy  string `json:"proxy"` // 代理地址，为空时使用全局代理，direct表示直连
 }
 
 // UpdateChannelStatusRequest 更新渠道状态请求
@@ -121,6 +127,8 @@ func (h *ChannelHandler) CreateChannel(c *gin.Context) {
 		Status:      req.Status,
 		Description: req.Description,
 		TemplateID:  req.TemplateID,
+		Timezone:    req.Timezone,
+		Proxy:       strings.TrimSpace(req.Proxy),
 	}
 
 	if err := h.channelService.CreateChannel(channel); err != nil {
@@ -208,6 +216,8 @@ func (h *ChannelHandler) UpdateChannel(c *gin.Context) {
 	channel.Status = req.Status
 	channel.Description = req.Description
 	channel.TemplateID = req.TemplateID
+	channel.Timezone = req.Timezone
+	channel.Proxy = strings.TrimSpace(req.Proxy)
 
 	if err := h.channelService.UpdateChannel(channel); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -310,7 +320,7 @@ func (h *ChannelHandler) TestChannelConfig(c *gin.Context) {
 		return
 	}
 
-	if err := h.channelService.TestChannelConfig(req.Type, req.Config); err != nil {
+	if err := h.channelService.TestChannelConfig(req.Type, req.Config, strings.TrimSpace(req.Proxy)); err != nil {
 		// 记录详细错误日志
 		log.Printf("渠道配置测试失败 - Type: %s, Error: %v", req.Type, err)
 
@@ -2,8 +2,10 @@ package api
 
 import (
 	"emailAlert/internal/service"
+	"emailAlert/pkg/email"
 	"encoding/json"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -155,6 +157,87 @@ func (h *EmailMonitorHandler) GetEmailStats(c *gin.Context) {
 	})
 }
 
+// GetCheckpoints 获取邮箱检查点
+// @Summary 获取邮箱检查点
+// @Description 获取每个邮箱文件夹最后处理的UID和UIDVALIDITY，可通过mailbox_id过滤
+// @Tags 邮件监控
+// @Accept json
+// @Produce json
+// @Param mailbox_id query int false "邮箱ID"
+// @Success 200 {object} APIResponse
+// @Failure 400 {object} APIResponse
+// @Failure 500 {object} APIResponse
+// @Router /api/v1/monitor/checkpoints [get]
+func (h *EmailMonitorHandler) GetCheckpoints(c *gin.Context) {
+	var mailboxID uint64
+	if idStr := c.Query("mailbox_id"); idStr != "" {
+		var err error
+		mailboxID, err = strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse{
+				Code:    400,
+				Message: "无效的邮箱ID",
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	checkpoints, err := h.emailMonitorService.GetCheckpoints(uint(mailboxID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    500,
+			Message: "获取检查点失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "获取检查点成功",
+		Data:    checkpoints,
+	})
+}
+
+// ResetCheckpoint 重置邮箱检查点
+// @Summary 重置邮箱检查点
+// @Description 清除邮箱的检查点，下次检查时以当前最新邮件为基线重新开始；folder为空时重置全部文件夹
+// @Tags 邮件监控
+// @Accept json
+// @Produce json
+// @Param mailbox_id path int true "邮箱ID"
+// @Param folder query string false "文件夹名称"
+// @Success 200 {object} APIResponse
+// @Failure 400 {object} APIResponse
+// @Failure 500 {object} APIResponse
+// @Router /api/v1/monitor/checkpoints/{mailbox_id} [delete]
+func (h *EmailMonitorHandler) ResetCheckpoint(c *gin.Context) {
+	mailboxID, err := strconv.ParseUint(c.Param("mailbox_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "无效的邮箱ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := h.emailMonitorService.ResetCheckpoint(uint(mailboxID), c.Query("folder")); err != nil {
+		c.JSON(http.StatusInternalServerError, APIResponse{
+			Code:    500,
+			Message: "重置检查点失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "检查点已重置",
+	})
+}
+
 // GetMonitorLogs 获取实时监控日志 (Server-Sent Events)
 func (h *EmailMonitorHandler) GetMonitorLogs(c *gin.Context) {
 	// 设置SSE响应头
@@ -196,6 +279,86 @@ func (h *EmailMonitorHandler) GetMonitorLogs(c *gin.Context) {
 	}
 }
 
+// ImportEmails 导入邮件文件
+// @Summary 导入邮件文件
+// @Description 上传.eml或mbox文件（Maildir中的邮件文件可作为多个.eml上传），交给指定邮箱的处理流程，重复邮件会被跳过
+// @Tags 邮件监控
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "邮箱ID"
+// @Param files formData file true "邮件文件（可多个）"
+// @Param format formData string false "文件格式：auto/eml/mbox，默认auto"
+// @Param folder formData string false "邮件所属文件夹，默认INBOX"
+// @Success 200 {object} APIResponse
+// @Failure 400 {object} APIResponse
+// @Router /api/v1/mailboxes/{id}/import [post]
+func (h *EmailMonitorHandler) ImportEmails(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "无效的邮箱ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "请上传邮件文件",
+			Error:   "缺少files字段",
+		})
+		return
+	}
+
+	format := c.DefaultPostForm("format", email.ImportFormatAuto)
+	if format != email.ImportFormatAuto && format != email.ImportFormatEML && format != email.ImportFormatMbox {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "不支持的文件格式",
+			Error:   "format可选 auto/eml/mbox",
+		})
+		return
+	}
+	folder := c.PostForm("folder")
+
+	result := &service.ImportResult{}
+	for _, fileHeader := range form.File["files"] {
+		file, err := fileHeader.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse{
+				Code:    400,
+				Message: "读取上传文件失败",
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		fileResult, err := h.emailMonitorService.ImportEmails(uint(id), fileHeader.Filename, format, folder, file)
+		file.Close()
+		if fileResult != nil {
+			result.Merge(fileResult)
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse{
+				Code:    400,
+				Message: "导入邮件失败",
+				Data:    result,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "导入邮件完成",
+		Data:    result,
+	})
+}
+
 // RegisterRoutes 注册路由
 func (h *EmailMonitorHandler) RegisterRoutes(router *gin.RouterGroup) {
 	monitor := router.Group("/monitor")
@@ -206,5 +369,7 @@ func (h *EmailMonitorHandler) RegisterRoutes(router *gin.RouterGroup) {
 		monitor.POST("/refresh", h.RefreshMailboxes)
 		monitor.GET("/stats", h.GetEmailStats)
 		monitor.GET("/logs", h.GetMonitorLogs)
+		monitor.GET("/checkpoints", h.GetCheckpoints)
+		monitor.DELETE("/checkpoints/:mailbox_id", h.ResetCheckpoint)
 	}
 }
@@ -518,3 +518,62 @@ func (h *MailboxHandler) DiagnoseMailboxConfig(c *gin.Context) {
 		Data:    diagnosis,
 	})
 }
+
+// GetProviders 获取邮箱服务商配置列表
+// @Summary 获取邮箱服务商配置列表
+// @Description 获取内置及配置文件中的邮箱服务商配置，包括推荐的服务器地址、端口和SSL设置
+// @Tags 邮箱管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} APIResponse{data=[]email.ProviderProfile}
+// @Router /api/v1/mailboxes/providers [get]
+func (h *MailboxHandler) GetProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "获取服务商配置成功",
+		Data:    h.mailboxService.GetProviders(),
+	})
+}
+
+// GetMailboxFolders 获取邮箱文件夹列表
+// @Summary 获取邮箱文件夹列表
+// @Description 连接邮箱服务器获取全部文件夹，用于选择需要监控的文件夹
+// @Tags 邮箱管理
+// @Accept json
+// @Produce json
+// @Param id path int true "邮箱ID"
+// @Success 200 {object} APIResponse{data=[]string}
+// @Router /api/v1/mailboxes/{id}/folders [get]
+func (h *MailboxHandler) GetMailboxFolders(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "无效的邮箱ID",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	folders, err := h.mailboxService.GetFolders(uint(id))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "邮箱配置不存在" {
+			statusCode = http.StatusNotFound
+		}
+
+		c.JSON(statusCode, APIResponse{
+			Code:    statusCode,
+			Message: "获取文件夹列表失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "获取文件夹列表成功",
+		Data:    folders,
+	})
+}
@@ -1,17 +1,19 @@
 package api
 
 import (
+	"context"
 	"emailAlert/config"
 	"emailAlert/internal/middleware"
 	"emailAlert/internal/repository"
 	"emailAlert/internal/service"
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRoutes 设置路由
-func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database) {
+// SetupRoutes 设置路由，notificationDispatcherService为已启动后台处理器的通知分发服务
+func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database, notificationDispatcherService service.NotificationDispatcherService) {
 	// 初始化认证服务
 	authService := service.NewAuthService("./config/users.json")
 	authHandler := NewAuthHandler(authService)
@@ -47,31 +49,21 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database
 	alertRepo := repository.NewAlertRepository(db.GetDB())
 	templateRepo := repository.NewTemplateRepository(db.GetDB())
 	channelRepo := repository.NewChannelRepository(db.GetDB())
-	ruleChannelRepo := repository.NewRuleChannelRepository(db.GetDB())
 	notificationLogRepo := repository.NewNotificationLogRepository(db.GetDB())
 	// 规则组和匹配条件仓库
 	ruleGroupRepo := repository.NewRuleGroupRepository(db.GetDB())
 	matchConditionRepo := repository.NewMatchConditionRepository(db.GetDB())
 	ruleGroupChannelRepo := repository.NewRuleGroupChannelRepository(db.GetDB())
+	checkpointRepo := repository.NewMailboxCheckpointRepository(db.GetDB())
 
 	// 初始化基础服务层
 	mailboxService := service.NewMailboxService(mailboxRepo)
 	templateService := service.NewTemplateService(templateRepo)
 	channelService := service.NewChannelService(channelRepo)
-	// 规则组服务的初始化
-	ruleGroupService := service.NewRuleGroupService(ruleGroupRepo, matchConditionRepo, ruleGroupChannelRepo, mailboxRepo)
 	// 增强版规则引擎初始化
 	enhancedRuleEngineService := service.NewEnhancedRuleEngineService(ruleGroupRepo, matchConditionRepo, *alertRepo)
-
-	// 初始化通知分发服务
-	notificationDispatcherService := service.NewNotificationDispatcherService(
-		ruleChannelRepo,
-		ruleGroupChannelRepo,
-		notificationLogRepo,
-		alertRepo,
-		channelService,
-		templateService,
-	)
+	// 规则组服务的初始化
+	ruleGroupService := service.NewRuleGroupService(ruleGroupRepo, matchConditionRepo, ruleGroupChannelRepo, mailboxRepo, enhancedRuleEngineService)
 
 	// 初始化告警服务（传入通知分发服务以支持重试功能）
 	alertService := service.NewAlertService(alertRepo, notificationDispatcherService)
@@ -80,6 +72,40 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database
 	emailMonitorService := service.NewEmailMonitorService(
 		mailboxRepo,
 		alertRepo,
+		checkpointRepo,
+		enhancedRuleEngineService,
+		notificationDispatcherService,
+	)
+	// 邮箱配置变化时实时启停对应的监控协程
+	mailboxService.SetMailboxWatcher(emailMonitorService)
+
+	// 内置SMTP/LMTP接收服务（推送邮箱）
+	if cfg.Receiver.Enabled {
+		smtpReceiverService := service.NewSMTPReceiverService(cfg.Receiver, mailboxRepo, emailMonitorService)
+		if err := smtpReceiverService.Start(); err != nil {
+			log.Printf("启动SMTP/LMTP接收服务失败: %v", err)
+		}
+	}
+
+	// 平台自监控服务，异常时通知管理员渠道
+	selfMonitorService, err := service.NewSelfMonitorService(
+		cfg.SelfMonitor,
+		db.GetDB(),
+		emailMonitorService,
+		notificationDispatcherService,
+		channelService,
+		notificationLogRepo,
+	)
+	if err != nil {
+		log.Printf("初始化平台自监控服务失败: %v", err)
+	} else {
+		selfMonitorService.Start(context.Background())
+	}
+
+	// 历史回溯服务
+	backfillService := service.NewBackfillService(
+		mailboxRepo,
+		ruleGroupRepo,
 		enhancedRuleEngineService,
 		notificationDispatcherService,
 	)
@@ -87,6 +113,7 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database
 	// 初始化处理器
 	mailboxHandler := NewMailboxHandler(mailboxService)
 	emailMonitorHandler := NewEmailMonitorHandler(emailMonitorService)
+	backfillHandler := NewBackfillHandler(backfillService)
 	templateHandler := NewTemplateHandler(templateService)
 	channelHandler := NewChannelHandler(channelService)
 	alertHandler := NewAlertHandler(alertService)
@@ -109,6 +136,7 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database
 			// 特殊功能路由（必须在参数化路由之前）
 			mailboxes.POST("/config-test", mailboxHandler.TestMailboxConfig)         // 测试邮箱配置
 			mailboxes.POST("/diagnose-config", mailboxHandler.DiagnoseMailboxConfig) // 诊断邮箱配置
+			mailboxes.GET("/providers", mailboxHandler.GetProviders)                 // 邮箱服务商配置
 
 			// 参数化路由（放在最后）
 			mailboxes.GET("/:id", mailboxHandler.GetMailbox)
@@ -118,6 +146,17 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database
 			mailboxes.PUT("/:id/status", mailboxHandler.UpdateMailboxStatus)
 			mailboxes.POST("/:id/test", mailboxHandler.TestMailbox)         // 测试现有邮箱连接
 			mailboxes.POST("/:id/diagnose", mailboxHandler.DiagnoseMailbox) // 诊断现有邮箱
+			mailboxes.GET("/:id/folders", mailboxHandler.GetMailboxFolders) // 获取邮箱文件夹列表
+			mailboxes.POST("/:id/backfill", backfillHandler.StartBackfill)  // 历史邮件回溯
+			mailboxes.POST("/:id/import", emailMonitorHandler.ImportEmails) // 导入.eml/mbox邮件文件
+		}
+
+		// 历史回溯任务路由
+		backfillJobs := v1.Group("/backfill-jobs")
+		{
+			backfillJobs.GET("", backfillHandler.GetBackfillJobs)
+			backfillJobs.GET("/:id", backfillHandler.GetBackfillJob)
+			backfillJobs.POST("/:id/cancel", backfillHandler.CancelBackfillJob)
 		}
 
 		// 邮件监控路由
@@ -217,6 +256,7 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database
 			alertService,
 		)
 		systemStatusHandler := NewSystemStatusHandler(systemStatusService)
+		selfMonitorHandler := NewSelfMonitorHandler(selfMonitorService)
 
 		system := v1.Group("/system")
 		{
@@ -224,6 +264,8 @@ func SetupRoutes(router *gin.Engine, cfg *config.Config, db *repository.Database
 			system.GET("/stats", systemStatusHandler.GetSystemStats)
 			system.GET("/health", systemStatusHandler.GetSystemHealth)
 			system.POST("/cleanup", systemStatusHandler.CleanupHistoryData)
+			system.GET("/self-monitor", selfMonitorHandler.GetIssues)
+			system.POST("/proxy/test", systemStatusHandler.TestProxy)
 		}
 	}
 }
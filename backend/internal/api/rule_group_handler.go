@@ -403,16 +403,23 @@ func (h *RuleGroupHandler) TestRuleGroup(c *gin.Context) {
 		return
 	}
 
-	// TODO: 实现规则组测试逻辑
-	// 这里可以调用增强版规则引擎进行测试
+	// 配置错误（如表达式无法编译、条件树无效）作为参数错误返回
+	result, err := h.ruleGroupService.TestRuleGroup(&request.RuleGroupData, &request.TestEmail)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    400,
+			"message": err.Error(),
+			"data":    nil,
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    200,
 		"message": "测试规则组成功",
 		"data": gin.H{
-			"matched":         true, // 临时返回值
-			"rule_group_data": request.RuleGroupData,
-			"test_email":      request.TestEmail,
+			"matched": result.Matched,
+			"result":  result,
 		},
 	})
 }
@@ -454,6 +461,12 @@ func (h *RuleGroupHandler) GetMatchTypeOptions(c *gin.Context) {
 		{"value": "endsWith", "label": "后缀匹配"},
 		{"value": "regex", "label": "正则表达式"},
 		{"value": "notContains", "label": "不包含"},
+		{"value": "gt", "label": "数值大于"},
+		{"value": "gte", "label": "数值大于等于"},
+		{"value": "lt", "label": "数值小于"},
+		{"value": "lte", "label": "数值小于等于"},
+		{"value": "between", "label": "数值介于（最小值,最大值）"},
+		{"value": "expression", "label": "表达式（高级）"},
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -470,8 +483,15 @@ func (h *RuleGroupHandler) GetFieldTypeOptions(c *gin.Context) {
 		{"value": "from", "label": "发件人"},
 		{"value": "to", "label": "收件人"},
 		{"value": "cc", "label": "抄送人"},
+		{"value": "bcc", "label": "密送人"},
+		{"value": "reply_to", "label": "回复地址"},
 		{"value": "body", "label": "邮件正文"},
 		{"value": "attachment_name", "label": "附件名称"},
+		{"value": "attachment_type", "label": "附件类型"},
+		{"value": "attachment_size", "label": "附件大小（如 >10MB）"},
+		{"value": "attachment_content", "label": "附件内容（文本/CSV）"},
+		{"value": "folder", "label": "所在文件夹"},
+		{"value": "header", "label": "邮件头（需指定名称，如 X-Priority）"},
 	}
 
 	c.JSON(http.StatusOK, gin.H{
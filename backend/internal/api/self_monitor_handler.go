@@ -0,0 +1,48 @@
+package api
+
+import (
+	"emailAlert/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SelfMonitorHandler 平台自监控处理器
+type SelfMonitorHandler struct {
+	selfMonitorService *service.SelfMonitorService
+}
+
+// NewSelfMonitorHandler 创建平台自监控处理器
+func NewSelfMonitorHandler(selfMonitorService *service.SelfMonitorService) *SelfMonitorHandler {
+	return &SelfMonitorHandler{
+		selfMonitorService: selfMonitorService,
+	}
+}
+
+// GetIssues 获取当前存在的平台异常
+// @Summary 获取平台自监控异常
+// @Description 获取平台自身当前存在的异常，包括邮箱认证失败、邮箱长时间无法检查、通知渠道持续失败、通知队列积压、数据库不可用
+// @Tags 系统状态
+// @Accept json
+// @Produce json
+// @Success 200 {object} APIResponse
+// @Failure 503 {object} APIResponse
+// @Router /api/v1/system/self-monitor [get]
+func (h *SelfMonitorHandler) GetIssues(c *gin.Context) {
+	if h.selfMonitorService == nil {
+		c.JSON(http.StatusServiceUnavailable, APIResponse{
+			Code:    503,
+			Message: "平台自监控服务未初始化",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "获取平台异常成功",
+		Data: gin.H{
+			"enabled": h.selfMonitorService.Enabled(),
+			"issues":  h.selfMonitorService.GetIssues(),
+		},
+	})
+}
@@ -199,3 +199,45 @@ func (h *SystemStatusHandler) CleanupHistoryData(c *gin.Context) {
 		Data:    result,
 	})
 }
+
+// TestProxy 测试代理连通性
+// @Summary 测试代理连通性
+// @Description 通过指定代理（为空时使用全局代理）连接目标地址，验证SOCKS5/HTTP CONNECT代理配置是否可用
+// @Tags 系统状态
+// @Accept json
+// @Produce json
+// @Param body body object true "测试参数：proxy、target（host:port）"
+// @Success 200 {object} APIResponse
+// @Failure 400 {object} APIResponse
+// @Router /api/v1/system/proxy/test [post]
+func (h *SystemStatusHandler) TestProxy(c *gin.Context) {
+	var req struct {
+		Proxy  string `json:"proxy"`                     // 代理地址，为空时使用全局代理，direct表示直连
+		Target string `json:"target" binding:"required"` // 目标地址，如 imap.example.com:993
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "参数错误",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	result, err := h.systemStatusService.TestProxy(req.Proxy, req.Target)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse{
+			Code:    400,
+			Message: "代理测试失败",
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, APIResponse{
+		Code:    200,
+		Message: "代理测试成功",
+		Data:    result,
+	})
+}
@@ -17,31 +17,90 @@ type BaseModel struct {
 // Mailbox 邮箱配置模型
 type Mailbox struct {
 	BaseModel
-	Name        string `gorm:"size:100;not null" json:"name"`                                       // 邮箱名称
-	Email       string `gorm:"size:255;not null;uniqueIndex:idx_mailbox_email_active" json:"email"` // 邮箱地址
-	Host        string `gorm:"size:255;not null" json:"host"`                                       // IMAP/POP3服务器地址
-	Port        int    `gorm:"not null" json:"port"`                                                // 端口
-	Username    string `gorm:"size:255;not null" json:"username"`                                   // 用户名
-	Password    string `gorm:"size:255;not null" json:"-"`                                          // 密码（不返回给前端）
-	Protocol    string `gorm:"size:10;not null" json:"protocol"`                                    // 协议类型：IMAP/POP3
-	SSL         bool   `gorm:"default:true" json:"ssl"`                                             // 是否启用SSL
-	Status      string `gorm:"size:20;default:'active'" json:"status"`                              // 状态：active/inactive
-	Description string `gorm:"type:text" json:"description"`                                        // 描述
+	Name                  string `gorm:"size:100;not null" json:"name"`                                                 // 邮箱名称
+	Email                 string `gorm:"size:255;not null;uniqueIndex:idx_mailbox_email_active" json:"email"`           // 邮箱地址
+	Host                  string `gorm:"size:255;not null" json:"host"`                                                 // IMAP/POP3服务器地址
+	Port                  int    `gorm:"not null" json:"port"`                                                          // 端口
+	Username              string `gorm:"size:255;not null" json:"username"`                                             // 用户名
+	Password              string `gorm:"size:255;not null" json:"-"`                                                    // 密码（不返回给前端）
+	Protocol              string `gorm:"size:10;not null" json:"protocol"`                                              // 协议类型：IMAP/POP3/SMTP（推送）
+	SSL                   bool   `gorm:"default:true" json:"ssl"`                                                       // 是否启用SSL
+	Folders               string `gorm:"type:text" json:"folders"`                                                      // 监控的文件夹（多个用逗号分隔，为空时监控INBOX）
+	DeleteAfterFetch      bool   `gorm:"default:false" json:"delete_after_fetch"`                                       // POP3：处理后是否删除服务器上的邮件
+	IdleMode              bool   `gorm:"default:false" json:"idle_mode"`                                                // IMAP：是否使用IDLE推送模式
+	CheckInterval         int    `gorm:"default:0" json:"check_interval"`                                               // 检查间隔（秒），0表示使用全局默认值
+	MaxRetries            int    `gorm:"default:0" json:"max_retries"`                                                  // 检查失败最大重试次数，0表示使用全局默认值
+	RetryInterval         int    `gorm:"default:0" json:"retry_interval"`                                               // 重试基础间隔（秒），按重试次数递增，0表示1秒
+	OnlyUnread            *bool  `json:"only_unread"`                                                                   // 是否只处理未读邮件，为空时使用全局配置
+	MarkAsRead            *bool  `json:"mark_as_read"`                                                                  // 处理后是否标记为已读，为空时使用全局配置
+	MatchedActions        string `gorm:"size:500" json:"matched_actions"`                                               // 匹配规则后对邮件执行的动作，如 "seen,move:Processed"
+	UnmatchedActions      string `gorm:"size:500" json:"unmatched_actions"`                                             // 未匹配规则时对邮件执行的动作
+	Schedule              string `gorm:"size:255" json:"schedule"`                                                      // 监控时间段，如 "08:00-20:00"，多个用逗号分隔，为空表示全天
+	AuthType              string `gorm:"size:20;default:'password'" json:"auth_type"`                                   // 认证方式：password/oauth2
+	OAuthClientID         string `gorm:"column:oauth_client_id;size:255" json:"oauth_client_id"`                        // OAuth2客户端ID
+	OAuthClientSecret     string `gorm:"column:oauth_client_secret;size:512" json:"-"`                                  // OAuth2客户端密钥（不返回给前端）
+	OAuthRefreshToken     string `gorm:"column:oauth_refresh_token;type:text" json:"-"`                                 // OAuth2 refresh token（不返回给前端）
+	OAuthTokenURL         string `gorm:"column:oauth_token_url;size:512" json:"oauth_token_url"`                        // OAuth2令牌端点
+	OAuthScope            string `gorm:"column:oauth_scope;size:512" json:"oauth_scope"`                                // OAuth2授权范围，多个用空格分隔
+	TLSMode               string `gorm:"column:tls_mode;size:20" json:"tls_mode"`                                       // TLS模式：none/implicit/starttls，为空时按SSL字段决定
+	TLSCACert             string `gorm:"column:tls_ca_cert;type:text" json:"tls_ca_cert"`                               // 自定义CA证书（PEM内容或文件路径）
+	TLSClientCert         string `gorm:"column:tls_client_cert;type:text" json:"tls_client_cert"`                       // 客户端证书（PEM内容或文件路径）
+	TLSClientKey          string `gorm:"column:tls_client_key;type:text" json:"-"`                                      // 客户端私钥（不返回给前端）
+	TLSInsecureSkipVerify bool   `gorm:"column:tls_insecure_skip_verify;default:false" json:"tls_insecure_skip_verify"` // 跳过服务器证书校验，仅用于测试环境
+	TLSMinVersion         string `gorm:"column:tls_min_version;size:10" json:"tls_min_version"`                         // 最低TLS版本：1.0/1.1/1.2/1.3
+	Proxy                 string `gorm:"size:512" json:"-"`                                                             // 代理地址（socks5://或http://，可含认证信息，不返回给前端），为空时使用全局代理，direct表示直连
+	Status                string `gorm:"size:20;default:'active'" json:"status"`                                        // 状态：active/inactive/suspended（连续认证失败自动暂停）
+	Description           string `gorm:"type:text" json:"description"`                                                  // 描述
 }
 
 // MailboxWithPassword 邮箱配置模型（包含密码，用于编辑）
 type MailboxWithPassword struct {
 	BaseModel
-	Name        string `json:"name"`        // 邮箱名称
-	Email       string `json:"email"`       // 邮箱地址
-	Host        string `json:"host"`        // IMAP/POP3服务器地址
-	Port        int    `json:"port"`        // 端口
-	Username    string `json:"username"`    // 用户名
-	Password    string `json:"password"`    // 密码（明文返回）
-	Protocol    string `json:"protocol"`    // 协议类型：IMAP/POP3
-	SSL         bool   `json:"ssl"`         // 是否启用SSL
-	Status      string `json:"status"`      // 状态：active/inactive
-	Description string `json:"description"` // 描述
+	Name                  string `json:"name"`                     // 邮箱名称
+	Email                 string `json:"email"`                    // 邮箱地址
+	Host                  string `json:"host"`                     // IMAP/POP3服务器地址
+	Port                  int    `json:"port"`                     // 端口
+	Username              string `json:"username"`                 // 用户名
+	Password              string `json:"password"`                 // 密码（明文返回）
+	Protocol              string `json:"protocol"`                 // 协议类型：IMAP/POP3/SMTP（推送）
+	SSL                   bool   `json:"ssl"`                      // 是否启用SSL
+	Folders               string `json:"folders"`                  // 监控的文件夹（多个用逗号分隔）
+	DeleteAfterFetch      bool   `json:"delete_after_fetch"`       // POP3：处理后是否删除服务器上的邮件
+	IdleMode              bool   `json:"idle_mode"`                // IMAP：是否使用IDLE推送模式
+	CheckInterval         int    `json:"check_interval"`           // 检查间隔（秒）
+	MaxRetries            int    `json:"max_retries"`              // 检查失败最大重试次数
+	RetryInterval         int    `json:"retry_interval"`           // 重试基础间隔（秒）
+	OnlyUnread            *bool  `json:"only_unread"`              // 是否只处理未读邮件，为空时使用全局配置
+	MarkAsRead            *bool  `json:"mark_as_read"`             // 处理后是否标记为已读，为空时使用全局配置
+	MatchedActions        string `json:"matched_actions"`          // 匹配规则后执行的动作
+	UnmatchedActions      string `json:"unmatched_actions"`        // 未匹配规则时执行的动作
+	Schedule              string `json:"schedule"`                 // 监控时间段
+	AuthType              string `json:"auth_type"`                // 认证方式：password/oauth2
+	OAuthClientID         string `json:"oauth_client_id"`          // OAuth2客户端ID
+	OAuthClientSecret     string `json:"oauth_client_secret"`      // OAuth2客户端密钥（明文返回）
+	OAuthRefreshToken     string `json:"oauth_refresh_token"`      // OAuth2 refresh token（明文返回）
+	OAuthTokenURL         string `json:"oauth_token_url"`          // OAuth2令牌端点
+	OAuthScope            string `json:"oauth_scope"`              // OAuth2授权范围
+	TLSMode               string `json:"tls_mode"`                 // TLS模式：none/implicit/starttls
+	TLSCACert             string `json:"tls_ca_cert"`              // 自定义CA证书
+	TLSClientCert         string `json:"tls_client_cert"`          // 客户端证书
+	TLSClientKey          string `json:"tls_client_key"`           // 客户端私钥（明文返回）
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"` // 跳过服务器证书校验
+	TLSMinVersion         string `json:"tls_min_version"`          // 最低TLS版本
+	Proxy                 string `json:"proxy"`                    // 代理地址（明文返回）
+	Status                string `json:"status"`                   // 状态：active/inactive
+	Description           string `json:"description"`              // 描述
+}
+
+// MailboxCheckpoint 邮箱检查点模型（记录每个邮箱文件夹最后处理的UID，重启后从此处继续）
+type MailboxCheckpoint struct {
+	BaseModel
+	MailboxID   uint      `gorm:"not null;uniqueIndex:idx_checkpoint_mailbox_folder" json:"mailbox_id"`      // 关联邮箱ID
+	Folder      string    `gorm:"size:255;not null;uniqueIndex:idx_checkpoint_mailbox_folder" json:"folder"` // 文件夹名称
+	UIDValidity uint32    `gorm:"not null;default:0" json:"uid_validity"`                                    // 文件夹UIDVALIDITY，变化时检查点失效
+	LastUID     uint32    `gorm:"not null;default:0" json:"last_uid"`                                        // 最后处理的UID
+	SeenUIDLs   []string  `gorm:"column:seen_uidls;type:text;serializer:json" json:"-"`                      // POP3邮箱已处理的UIDL，重启后据此识别停机期间到达的邮件
+	CheckedAt   time.Time `json:"checked_at"`                                                                // 最后检查时间
 }
 
 // AlertRule 告警规则模型
@@ -61,15 +120,54 @@ type AlertRule struct {
 // RuleGroup 规则组模型 - 新增，支持多规则组合
 type RuleGroup struct {
 	BaseModel
-	Name        string           `gorm:"size:100;not null" json:"name"`            // 规则组名称
-	MailboxID   uint             `gorm:"not null" json:"mailbox_id"`               // 关联邮箱ID
-	Mailbox     Mailbox          `gorm:"foreignKey:MailboxID" json:"mailbox"`      // 关联邮箱
-	Logic       string           `gorm:"size:10;default:'and'" json:"logic"`       // 规则间逻辑：and/or
-	Priority    int              `gorm:"default:1" json:"priority"`                // 优先级 (1-10)
-	Status      string           `gorm:"size:20;default:'active'" json:"status"`   // 状态：active/inactive
-	Description string           `gorm:"type:text" json:"description"`             // 描述
-	Conditions  []MatchCondition `gorm:"foreignKey:RuleGroupID" json:"conditions"` // 关联的匹配条件
-	Channels    []Channel        `gorm:"-" json:"channels"`                        // 关联的通知渠道（通过服务层手动加载）
+	Name          string           `gorm:"size:100;not null" json:"name"`                   // 规则组名称
+	MailboxID     uint             `gorm:"not null" json:"mailbox_id"`                      // 关联邮箱ID
+	Mailbox       Mailbox          `gorm:"foreignKey:MailboxID" json:"mailbox"`             // 关联邮箱
+	Logic         string           `gorm:"size:10;default:'and'" json:"logic"`              // 规则间逻辑：and/or
+	Priority      int              `gorm:"default:1" json:"priority"`                       // 优先级 (1-10)
+	Status        string           `gorm:"size:20;default:'active'" json:"status"`          // 状态：active/inactive
+	Description   string           `gorm:"type:text" json:"description"`                    // 描述
+	Actions       string           `gorm:"size:500" json:"actions"`                         // 匹配后对邮件执行的动作，为空时使用邮箱配置
+	ConditionTree *ConditionNode   `gorm:"type:text;serializer:json" json:"condition_tree"` // 条件树（AND/OR/NOT嵌套），配置后替代Logic
+	Extractions   []ExtractionRule `gorm:"type:text;serializer:json" json:"extractions"`    // 变量提取规则，匹配后提取的变量保存为告警标签
+	Conditions    []MatchCondition `gorm:"foreignKey:RuleGroupID" json:"conditions"`        // 关联的匹配条件
+	Channels      []Channel        `gorm:"-" json:"channels"`                               // 关联的通知渠道（通过服务层手动加载）
+}
+
+// 条件树节点运算符
+const (
+	ConditionOpAnd = "and"
+	ConditionOpOr  = "or"
+	ConditionOpNot = "not"
+)
+
+// ConditionNode 条件树节点，叶子节点引用规则组的一个匹配条件，其余节点为AND/OR/NOT运算
+// 保存时叶子节点可以用condition_index引用同一请求中conditions数组的下标，持久化后统一为condition_id
+type ConditionNode struct {
+	Op             string           `json:"op,omitempty"`              // 运算符：and/or/not，叶子节点为空
+	ConditionID    uint             `json:"condition_id,omitempty"`    // 叶子节点引用的匹配条件ID
+	ConditionIndex *int             `json:"condition_index,omitempty"` // 叶子节点引用的条件下标（仅保存请求中使用）
+	Children       []*ConditionNode `json:"children,omitempty"`        // 子节点，not节点只能有一个子节点
+}
+
+// 变量提取来源
+const (
+	ExtractionSourceRegex  = "regex"  // 正则表达式，命名分组作为变量名
+	ExtractionSourceHeader = "header" // 邮件头的值
+	ExtractionSourceJSON   = "json"   // 按JSON路径读取字段内容中的JSON
+)
+
+// ExtractionRule 变量提取规则，从匹配的邮件中提取结构化数据（如主机名、指标值、工单号）
+type ExtractionRule struct {
+	Name    string `json:"name"`    // 变量名；regex类型的命名分组自带变量名，无命名分组时使用该名称
+	Source  string `json:"source"`  // 提取来源：regex/header/json
+	Field   string `json:"field"`   // regex/json读取的字段，同匹配条件的field_type，默认body
+	Pattern string `json:"pattern"` // regex为正则表达式，header为邮件头名称，json为JSON路径（如 $.labels.host、items[0].id）
+}
+
+// IsLeaf 是否为引用匹配条件的叶子节点
+func (n *ConditionNode) IsLeaf() bool {
+	return n.Op == ""
 }
 
 // MatchCondition 匹配条件模型 - 新增，支持多维度匹配
@@ -77,9 +175,11 @@ type MatchCondition struct {
 	BaseModel
 	RuleGroupID  uint      `gorm:"not null" json:"rule_group_id"`             // 关联规则组ID
 	RuleGroup    RuleGroup `gorm:"foreignKey:RuleGroupID" json:"rule_group"`  // 关联规则组
-	FieldType    string    `gorm:"size:20;not null" json:"field_type"`        // 匹配字段：subject/from/to/cc/body/attachment_name
-	MatchType    string    `gorm:"size:20;not null" json:"match_type"`        // 匹配类型：equals/contains/startsWith/endsWith/regex/notContains
-	Keywords     string    `gorm:"type:text;not null" json:"keywords"`        // 关键词（多个用逗号分隔）
+	FieldType    string    `gorm:"size:20;not null" json:"field_type"`        // 匹配字段：subject/from/to/cc/bcc/reply_to/body/attachment_name/attachment_type/attachment_size/attachment_content/folder/header
+	HeaderName   string    `gorm:"size:255" json:"header_name"`               // 邮件头名称，字段为header时使用，如 X-Priority
+	MatchType    string    `gorm:"size:20;not null" json:"match_type"`        // 匹配类型：equals/contains/startsWith/endsWith/regex/notContains/expression/gt/gte/lt/lte/between
+	Keywords     string    `gorm:"type:text;not null" json:"keywords"`        // 关键词（多个用逗号分隔），数值比较类型为阈值，between为"最小值,最大值"
+	ValuePattern string    `gorm:"size:500" json:"value_pattern"`             // 数值比较类型提取数值的正则，取命名分组value或第一个分组，为空时取字段中的第一个数值
 	KeywordLogic string    `gorm:"size:10;default:'or'" json:"keyword_logic"` // 关键词逻辑：and/or
 	Priority     int       `gorm:"default:1" json:"priority"`                 // 条件优先级
 	Status       string    `gorm:"size:20;default:'active'" json:"status"`    // 状态：active/inactive
@@ -98,6 +198,8 @@ type Channel struct {
 	LastTestAt  *time.Time `json:"last_test_at"`                                    // 最后测试时间
 	TemplateID  *uint      `gorm:"index" json:"template_id"`                        // 关联的模版ID（可选，为空时使用默认模版）
 	Template    *Template  `gorm:"foreignKey:TemplateID" json:"template,omitempty"` // 关联的模版
+	Timezone    string     `gorm:"size:64" json:"timezone"`                         // 模版中时间的展示时区（IANA名称），为空时使用系统时区
+	Proxy       string     `gorm:"size:512" json:"proxy"`                           // 代理地址（socks5://或http://），为空时使用全局代理，direct表示直连
 }
 
 // WeChatConfig 企业微信配置
@@ -191,21 +293,40 @@ type Template struct {
 // Alert 告警记录模型
 type Alert struct {
 	BaseModel
-	MailboxID    uint      `gorm:"not null" json:"mailbox_id"`               // 邮箱ID
-	Mailbox      Mailbox   `gorm:"foreignKey:MailboxID" json:"mailbox"`      // 关联邮箱
-	RuleID       uint      `gorm:"default:0" json:"rule_id"`                 // 规则ID（已废弃，兼容用）
-	Rule         AlertRule `gorm:"foreignKey:RuleID" json:"rule"`            // 关联规则（已废弃，兼容用）
-	RuleGroupID  uint      `gorm:"default:0" json:"rule_group_id"`           // 规则组ID（新架构）
-	RuleGroup    RuleGroup `gorm:"foreignKey:RuleGroupID" json:"rule_group"` // 关联规则组（新架构）
-	Subject      string    `gorm:"size:500" json:"subject"`                  // 邮件主题
-	Sender       string    `gorm:"size:255" json:"sender"`                   // 发件人
-	Content      string    `gorm:"type:longtext" json:"content"`             // 邮件内容
-	MessageID    string    `gorm:"size:255;index" json:"message_id"`         // 邮件MessageID（用于去重）
-	ReceivedAt   time.Time `gorm:"not null" json:"received_at"`              // 邮件接收时间
-	Status       string    `gorm:"size:20;default:'pending'" json:"status"`  // 处理状态：pending/sent/failed
-	SentChannels string    `gorm:"type:text" json:"sent_channels"`           // 已发送的渠道
-	ErrorMsg     string    `gorm:"type:text" json:"error_msg"`               // 错误信息
-	RetryCount   int       `gorm:"default:0" json:"retry_count"`             // 重试次数
+	MailboxID    uint         `gorm:"not null" json:"mailbox_id"`               // 邮箱ID
+	Mailbox      Mailbox      `gorm:"foreignKey:MailboxID" json:"mailbox"`      // 关联邮箱
+	RuleID       uint         `gorm:"default:0" json:"rule_id"`                 // 规则ID（已废弃，兼容用）
+	Rule         AlertRule    `gorm:"foreignKey:RuleID" json:"rule"`            // 关联规则（已废弃，兼容用）
+	RuleGroupID  uint         `gorm:"default:0" json:"rule_group_id"`           // 规则组ID（新架构）
+	RuleGroup    RuleGroup    `gorm:"foreignKey:RuleGroupID" json:"rule_group"` // 关联规则组（新架构）
+	Subject      string       `gorm:"size:500" json:"subject"`                  // 邮件主题
+	Sender       string       `gorm:"size:255" json:"sender"`                   // 发件人
+	Content      string       `gorm:"type:longtext" json:"content"`             // 邮件内容
+	MessageID    string       `gorm:"size:255;index" json:"message_id"`         // 邮件MessageID（用于去重）
+	UIDL         string       `gorm:"size:255;index" json:"uidl,omitempty"`     // POP3邮件UIDL（没有MessageID时用于去重）
+	ReceivedAt   time.Time    `gorm:"not null" json:"received_at"`              // 邮件接收时间
+	Status       string       `gorm:"size:20;default:'pending'" json:"status"`  // 处理状态：pending/sent/failed
+	SentChannels string       `gorm:"type:text" json:"sent_channels"`           // 已发送的渠道
+	ErrorMsg     string       `gorm:"type:text" json:"error_msg"`               // 错误信息
+	RetryCount   int          `gorm:"default:0" json:"retry_count"`             // 重试次数
+	Labels       []AlertLabel `gorm:"foreignKey:AlertID" json:"labels"`         // 规则组提取规则从邮件中提取的变量
+}
+
+// AlertLabel 告警标签，键值对形式保存从邮件中提取的变量，模版中通过 {{.Vars.名称}} 使用
+type AlertLabel struct {
+	ID      uint   `gorm:"primarykey" json:"-"`
+	AlertID uint   `gorm:"not null;index" json:"-"`                             // 告警ID
+	Name    string `gorm:"size:100;not null;index:idx_alert_label" json:"name"` // 变量名
+	Value   string `gorm:"size:255;index:idx_alert_label" json:"value"`         // 变量值
+}
+
+// LabelMap 将告警标签转换为变量名到值的映射
+func (a *Alert) LabelMap() map[string]string {
+	vars := make(map[string]string, len(a.Labels))
+	for _, label := range a.Labels {
+		vars[label.Name] = label.Value
+	}
+	return vars
 }
 
 // User 用户模型（后续扩展）
@@ -216,23 +337,40 @@ type User struct {
 	Password string `gorm:"size:255;not null" json:"-"`
 	Role     string `gorm:"size:20;default:'user'" json:"role"` // user/admin
 	Status   string `gorm:"size:20;default:'active'" json:"status"`
+	Timezone string `gorm:"size:64" json:"timezone"` // 展示时区（IANA名称），为空时使用系统时区
 }
 
 // EmailData 邮件数据结构（用于规则匹配）- 升级版本
 type EmailData struct {
-	UID             int       `json:"uid"`
-	Subject         string    `json:"subject"`
-	Sender          string    `json:"sender"`  // 发件人 (From)
-	To              []string  `json:"to"`      // 收件人列表
-	CC              []string  `json:"cc"`      // 抄送人列表
-	BCC             []string  `json:"bcc"`     // 密送人列表
-	Content         string    `json:"content"` // 邮件正文
-	HTMLContent     string    `json:"html_content"`
-	AttachmentNames []string  `json:"attachment_names"` // 附件名称列表
-	ReceivedAt      time.Time `json:"received_at"`
-	MessageID       string    `json:"message_id"`
-	Size            uint64    `json:"size"`
-	Flags           []string  `json:"flags"`
+	UID             int                 `json:"uid"`
+	Subject         string              `json:"subject"`
+	Sender          string              `json:"sender"`        // 发件人 (From)
+	FromName        string              `json:"from_name"`     // 发件人显示名称
+	SenderHeader    string              `json:"sender_header"` // Sender头（代发邮件时与From不同）
+	To              []string            `json:"to"`            // 收件人列表，格式为 "名称 <地址>"
+	CC              []string            `json:"cc"`            // 抄送人列表
+	BCC             []string            `json:"bcc"`           // 密送人列表
+	ReplyTo         []string            `json:"reply_to"`      // 回复地址列表
+	Content         string              `json:"content"`       // 邮件正文
+	Folder          string              `json:"folder"`        // 邮件所在文件夹
+	HTMLContent     string              `json:"html_content"`
+	AttachmentNames []string            `json:"attachment_names"` // 附件名称列表
+	Attachments     []AttachmentInfo    `json:"attachments"`      // 附件信息
+	Headers         map[string][]string `json:"headers"`          // 全部邮件头，键为规范化的头名称（如 X-Priority）
+	ReceivedAt      time.Time           `json:"received_at"`
+	MessageID       string              `json:"message_id"`
+	UIDL            string              `json:"uidl"` // POP3邮件UIDL
+	Size            uint64              `json:"size"`
+	Flags           []string            `json:"flags"`
+}
+
+// AttachmentInfo 邮件附件信息
+type AttachmentInfo struct {
+	Name        string `json:"name"`                   // 文件名
+	MimeType    string `json:"mime_type"`              // MIME类型
+	Size        int64  `json:"size"`                   // 大小（字节）
+	SHA256      string `json:"sha256"`                 // 内容SHA-256
+	TextContent string `json:"text_content,omitempty"` // 文本/CSV附件的内容（超过大小上限时为空）
 }
 
 // TemplateVariable 模版变量定义
@@ -245,12 +383,13 @@ type TemplateVariable struct {
 
 // TemplateRenderData 模版渲染数据
 type TemplateRenderData struct {
-	Email   *EmailData `json:"email"`   // 邮件数据
-	Alert   *Alert     `json:"alert"`   // 告警数据
-	Rule    *AlertRule `json:"rule"`    // 规则数据
-	Mailbox *Mailbox   `json:"mailbox"` // 邮箱数据
-	System  SystemInfo `json:"system"`  // 系统信息
-	Time    TimeInfo   `json:"time"`    // 时间信息
+	Email   *EmailData        `json:"email"`   // 邮件数据
+	Alert   *Alert            `json:"alert"`   // 告警数据
+	Rule    *AlertRule        `json:"rule"`    // 规则数据
+	Mailbox *Mailbox          `json:"mailbox"` // 邮箱数据
+	System  SystemInfo        `json:"system"`  // 系统信息
+	Time    TimeInfo          `json:"time"`    // 时间信息
+	Vars    map[string]string `json:"vars"`    // 告警标签（规则组提取的变量）
 }
 
 // SystemInfo 系统信息
@@ -268,6 +407,7 @@ type TimeInfo struct {
 	NowUnix   int64     `json:"now_unix"`
 	Today     string    `json:"today"`
 	Yesterday string    `json:"yesterday"`
+	Timezone  string    `json:"timezone"` // 时间的展示时区
 }
 
 // LoginRequest 登录请求
@@ -281,4 +421,5 @@ type LoginResponse struct {
 	SessionID string `json:"session_id"`
 	Username  string `json:"username"`
 	Role      string `json:"role"`
+	Timezone  string `json:"timezone"` // 用户的展示时区，未配置时为系统时区
 }
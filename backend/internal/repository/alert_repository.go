@@ -26,7 +26,7 @@ func (r *AlertRepository) Create(alert *model.Alert) error {
 // GetByID 根据ID获取告警记录
 func (r *AlertRepository) GetByID(id uint) (*model.Alert, error) {
 	var alert model.Alert
-	err := r.db.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").First(&alert, id).Error
+	err := r.db.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").Preload("Labels").First(&alert, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +56,7 @@ func (r *AlertRepository) List(page, pageSize int, status string, mailboxID uint
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err = query.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").
+	err = query.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").Preload("Labels").
 		Order("created_at DESC").
 		Offset(offset).Limit(pageSize).
 		Find(&alerts).Error
@@ -99,6 +99,13 @@ func (r *AlertRepository) ListWithFilters(page, pageSize int, filters map[string
 	if endDate, ok := filters["end_date"]; ok && endDate != "" {
 		query = query.Where("created_at <= ?", endDate.(string)+" 23:59:59")
 	}
+	if labels, ok := filters["labels"].(map[string]string); ok {
+		// 每个标签都必须匹配
+		for name, value := range labels {
+			query = query.Where("id IN (?)", r.db.Model(&model.AlertLabel{}).
+				Select("alert_id").Where("name = ? AND value = ?", name, value))
+		}
+	}
 
 	// 计算总数
 	err := query.Count(&total).Error
@@ -120,7 +127,7 @@ func (r *AlertRepository) ListWithFilters(page, pageSize int, filters map[string
 
 	// 分页查询
 	offset := (page - 1) * pageSize
-	err = query.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").
+	err = query.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").Preload("Labels").
 		Order(orderClause).
 		Offset(offset).Limit(pageSize).
 		Find(&alerts).Error
@@ -191,10 +198,20 @@ func (r *AlertRepository) ExistsByCompositeKey(mailboxID uint, uid int, subject,
 	return count > 0, nil
 }
 
+// ExistsByUIDL 检查指定邮箱中UIDL对应的POP3邮件是否已存在
+func (r *AlertRepository) ExistsByUIDL(mailboxID uint, uidl string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Alert{}).Where("mailbox_id = ? AND uidl = ?", mailboxID, uidl).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // GetByMessageID 根据MessageID获取告警记录
 func (r *AlertRepository) GetByMessageID(messageID string) (*model.Alert, error) {
 	var alert model.Alert
-	err := r.db.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").
+	err := r.db.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").Preload("Labels").
 		Where("message_id = ?", messageID).First(&alert).Error
 	if err != nil {
 		return nil, err
@@ -276,7 +293,7 @@ func (r *AlertRepository) GetAlertsByMailbox(mailboxID uint, limit int) ([]model
 		query = query.Limit(limit)
 	}
 
-	err := query.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").Find(&alerts).Error
+	err := query.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").Preload("Labels").Find(&alerts).Error
 	if err != nil {
 		return nil, err
 	}
@@ -300,7 +317,7 @@ func (r *AlertRepository) GetPendingAlerts(limit int) ([]model.Alert, error) {
 		query = query.Limit(limit)
 	}
 
-	err := query.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").Find(&alerts).Error
+	err := query.Preload("Mailbox").Preload("Rule").Preload("RuleGroup").Preload("Labels").Find(&alerts).Error
 	if err != nil {
 		return nil, err
 	}
@@ -83,6 +83,7 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		&model.Mailbox{},
 		&model.AlertRule{},
 		&model.Alert{},
+		&model.AlertLabel{},
 		&model.Channel{},
 		&model.Template{},
 		&model.RuleChannel{},
@@ -90,6 +91,7 @@ func NewDatabase(cfg *config.Config) (*Database, error) {
 		&model.NotificationLog{},
 		&model.RuleGroup{},      // 新增：规则组模型
 		&model.MatchCondition{}, // 新增：匹配条件模型
+		&model.MailboxCheckpoint{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("数据库迁移失败: %w", err)
@@ -127,6 +129,7 @@ func (d *Database) AutoMigrate() error {
 		&model.Mailbox{},
 		&model.AlertRule{},
 		&model.Alert{},
+		&model.AlertLabel{},
 		&model.Template{},
 		&model.Channel{},
 		&model.RuleChannel{},
@@ -134,6 +137,7 @@ func (d *Database) AutoMigrate() error {
 		&model.NotificationLog{},
 		&model.RuleGroup{},      // 新增：规则组模型
 		&model.MatchCondition{}, // 新增：匹配条件模型
+		&model.MailboxCheckpoint{},
 	)
 }
 
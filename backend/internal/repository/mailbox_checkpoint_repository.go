@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"emailAlert/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MailboxCheckpointRepository 邮箱检查点数据访问层
+type MailboxCheckpointRepository struct {
+	db *gorm.DB
+}
+
+// NewMailboxCheckpointRepository 创建邮箱检查点仓库实例
+func NewMailboxCheckpointRepository(db *gorm.DB) *MailboxCheckpointRepository {
+	return &MailboxCheckpointRepository{db: db}
+}
+
+// Get 获取指定邮箱文件夹的检查点，不存在时返回nil
+func (r *MailboxCheckpointRepository) Get(mailboxID uint, folder string) (*model.MailboxCheckpoint, error) {
+	var checkpoint model.MailboxCheckpoint
+	err := r.db.Where("mailbox_id = ? AND folder = ?", mailboxID, folder).First(&checkpoint).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// Save 保存检查点（按邮箱ID和文件夹覆盖写入）
+func (r *MailboxCheckpointRepository) Save(checkpoint *model.MailboxCheckpoint) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "mailbox_id"}, {Name: "folder"}},
+		DoUpdates: clause.AssignmentColumns([]string{"uid_validity", "last_uid", "seen_uidls", "checked_at", "updated_at"}),
+	}).Create(checkpoint).Error
+}
+
+// List 获取检查点列表，mailboxID为0时返回全部
+func (r *MailboxCheckpointRepository) List(mailboxID uint) ([]model.MailboxCheckpoint, error) {
+	var checkpoints []model.MailboxCheckpoint
+	query := r.db.Model(&model.MailboxCheckpoint{})
+	if mailboxID > 0 {
+		query = query.Where("mailbox_id = ?", mailboxID)
+	}
+	err := query.Order("mailbox_id ASC, folder ASC").Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+// Delete 删除检查点，folder为空时删除该邮箱的全部检查点
+// 使用物理删除，避免软删除记录占用唯一索引
+func (r *MailboxCheckpointRepository) Delete(mailboxID uint, folder string) error {
+	query := r.db.Unscoped().Where("mailbox_id = ?", mailboxID)
+	if folder != "" {
+		query = query.Where("folder = ?", folder)
+	}
+	return query.Delete(&model.MailboxCheckpoint{}).Error
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"emailAlert/internal/model"
 	"errors"
+	"strings"
 
 	"gorm.io/gorm"
 )
@@ -48,6 +49,20 @@ func (r *MailboxRepository) GetByEmail(email string) (*model.Mailbox, error) {
 	return &mailbox, nil
 }
 
+// GetPushMailboxByEmail 根据收件地址获取启用的推送邮箱（地址不区分大小写）
+func (r *MailboxRepository) GetPushMailboxByEmail(email, protocol string) (*model.Mailbox, error) {
+	var mailbox model.Mailbox
+	err := r.db.Where("LOWER(email) = ? AND protocol = ? AND status = ?", strings.ToLower(email), protocol, "active").
+		First(&mailbox).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("邮箱配置不存在")
+		}
+		return nil, err
+	}
+	return &mailbox, nil
+}
+
 // List 获取邮箱配置列表
 func (r *MailboxRepository) List(page, pageSize int, status string) ([]model.Mailbox, int64, error) {
 	var mailboxes []model.Mailbox
@@ -80,6 +95,11 @@ func (r *MailboxRepository) Update(id uint, mailbox *model.Mailbox) error {
 	return r.db.Model(&model.Mailbox{}).Where("id = ?", id).Updates(mailbox).Error
 }
 
+// UpdateFields 按字段更新邮箱配置（可更新零值字段）
+func (r *MailboxRepository) UpdateFields(id uint, fields map[string]interface{}) error {
+	return r.db.Model(&model.Mailbox{}).Where("id = ?", id).Updates(fields).Error
+}
+
 // Delete 删除邮箱配置
 func (r *MailboxRepository) Delete(id uint) error {
 	return r.db.Delete(&model.Mailbox{}, id).Error
@@ -90,6 +110,13 @@ func (r *MailboxRepository) UpdateStatus(id uint, status string) error {
 	return r.db.Model(&model.Mailbox{}).Where("id = ?", id).Update("status", status).Error
 }
 
+// UpdateOAuthRefreshToken 将使用指定OAuth2凭据的邮箱的refresh token替换为轮换后的值
+func (r *MailboxRepository) UpdateOAuthRefreshToken(clientID, previous, refreshToken string) error {
+	return r.db.Model(&model.Mailbox{}).
+		Where("oauth_client_id = ? AND oauth_refresh_token = ?", clientID, previous).
+		Update("oauth_refresh_token", refreshToken).Error
+}
+
 // GetActiveMailboxes 获取所有活跃的邮箱配置
 func (r *MailboxRepository) GetActiveMailboxes() ([]model.Mailbox, error) {
 	var mailboxes []model.Mailbox
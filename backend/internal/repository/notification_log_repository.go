@@ -16,6 +16,7 @@ type NotificationLogRepository interface {
 	GetByChannelID(channelID uint, page, size int) ([]*model.NotificationLog, int64, error)
 	GetNotificationLogsWithDetails(conditions map[string]interface{}, offset, size int, startTime, endTime *time.Time) ([]*model.NotificationLog, int64, error)
 	GetFailedLogs(maxRetryCount int) ([]*model.NotificationLog, error)
+	GetRecentResults(since time.Time) ([]*model.NotificationLog, error)
 	UpdateStatus(id uint, status, errorMsg, responseData string) error
 	UpdateSentAt(id uint, sentAt time.Time) error
 	IncrementRetryCount(id uint) error
@@ -136,6 +137,15 @@ func (r *notificationLogRepository) GetFailedLogs(maxRetryCount int) ([]*model.N
 	return logs, err
 }
 
+// GetRecentResults 获取指定时间之后有发送结果（成功或失败）的通知日志，按更新时间倒序，不含发送内容
+func (r *notificationLogRepository) GetRecentResults(since time.Time) ([]*model.NotificationLog, error) {
+	var logs []*model.NotificationLog
+	err := r.db.Select("id", "channel_id", "alert_id", "status", "error_msg", "created_at", "updated_at").
+		Where("updated_at >= ? AND status IN ?", since, []string{"success", "failed"}).
+		Order("updated_at DESC").Find(&logs).Error
+	return logs, err
+}
+
 // UpdateStatus 更新通知状态
 func (r *notificationLogRepository) UpdateStatus(id uint, status, errorMsg, responseData string) error {
 	updates := map[string]interface{}{
@@ -16,6 +16,7 @@ type RuleGroupRepository interface {
 	GetByMailboxID(mailboxID uint) ([]*model.RuleGroup, error)
 	GetActiveRuleGroups() ([]*model.RuleGroup, error)
 	GetWithConditions(id uint) (*model.RuleGroup, error)
+	UpdateConditionTree(id uint, tree *model.ConditionNode) error
 }
 
 // ruleGroupRepository 规则组仓库实现
@@ -84,6 +85,13 @@ func (r *ruleGroupRepository) Update(ruleGroup *model.RuleGroup) error {
 	return r.db.Save(ruleGroup).Error
 }
 
+// UpdateConditionTree 更新规则组的条件树，tree为nil时清空
+func (r *ruleGroupRepository) UpdateConditionTree(id uint, tree *model.ConditionNode) error {
+	return r.db.Model(&model.RuleGroup{BaseModel: model.BaseModel{ID: id}}).
+		Select("condition_tree").
+		Updates(&model.RuleGroup{ConditionTree: tree}).Error
+}
+
 // Delete 删除规则组（软删除）
 func (r *ruleGroupRepository) Delete(id uint) error {
 	return r.db.Delete(&model.RuleGroup{}, id).Error
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"emailAlert/internal/model"
+	"emailAlert/pkg/timezone"
 )
 
 // AuthService 认证服务接口
@@ -59,6 +60,7 @@ type SimpleUser struct {
 	Username string `json:"username"`
 	Password string `json:"password"`
 	Role     string `json:"role"`
+	Timezone string `json:"timezone,omitempty"` // 展示时区（IANA名称），为空时使用系统时区
 }
 
 // UsersConfig 用户配置文件结构
@@ -92,6 +94,7 @@ func (s *authService) LoadUsers() ([]model.User, error) {
 			Username: su.Username,
 			Password: su.Password,
 			Role:     su.Role,
+			Timezone: su.Timezone,
 		})
 	}
 
@@ -128,6 +131,7 @@ func (s *authService) Login(username, password string) (*model.LoginResponse, er
 		SessionID: sessionID,
 		Username:  user.Username,
 		Role:      user.Role,
+		Timezone:  timezone.Resolve(user.Timezone).String(),
 	}, nil
 }
 
@@ -227,6 +231,10 @@ func (s *authService) generateSessionID() string {
 
 // CreateUser 创建用户
 func (s *authService) CreateUser(user *model.User) error {
+	if err := timezone.Validate(user.Timezone); err != nil {
+		return err
+	}
+
 	// 读取现有用户
 	users, err := s.LoadUsers()
 	if err != nil {
@@ -247,6 +255,10 @@ func (s *authService) CreateUser(user *model.User) error {
 
 // UpdateUser 更新用户信息
 func (s *authService) UpdateUser(username string, user *model.User) error {
+	if err := timezone.Validate(user.Timezone); err != nil {
+		return err
+	}
+
 	users, err := s.LoadUsers()
 	if err != nil {
 		return err
@@ -319,6 +331,7 @@ func (s *authService) saveUsers(users []model.User) error {
 			Username: u.Username,
 			Password: u.Password,
 			Role:     u.Role,
+			Timezone: u.Timezone,
 		})
 	}
 
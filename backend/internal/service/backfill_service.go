@@ -0,0 +1,412 @@
+package service
+
+import (
+	"context"
+	"emailAlert/internal/model"
+	"emailAlert/internal/repository"
+	"emailAlert/pkg/email"
+	"emailAlert/pkg/timezone"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 历史回溯任务状态
+const (
+	BackfillStatusRunning   = "running"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusFailed    = "failed"
+	BackfillStatusCancelled = "cancelled"
+)
+
+const (
+	maxBackfillMatches = 500 // 每个任务保留的匹配明细上限
+	maxBackfillJobs    = 50  // 内存中保留的任务数量上限，超过时清理最早结束的任务
+)
+
+// BackfillRequest 历史回溯请求
+type BackfillRequest struct {
+	Folder       string `json:"folder"`                   // 文件夹，为空时使用INBOX
+	Since        string `json:"since" binding:"required"` // 起始时间，格式 "2006-01-02 15:04:05" 或 "2006-01-02"
+	Until        string `json:"until"`                    // 结束时间，为空表示到当前时间
+	RuleGroupIDs []uint `json:"rule_group_ids"`           // 只运行指定的规则组，为空时运行邮箱的全部激活规则组
+	DryRun       bool   `json:"dry_run"`                  // 试运行：只报告匹配结果，不创建告警
+	Notify       bool   `json:"notify"`                   // 非试运行时是否发送告警通知
+}
+
+// BackfillMatch 历史回溯匹配明细
+type BackfillMatch struct {
+	UID           int       `json:"uid"`
+	Subject       string    `json:"subject"`
+	Sender        string    `json:"sender"`
+	ReceivedAt    time.Time `json:"received_at"`
+	RuleGroupID   uint      `json:"rule_group_id"`
+	RuleGroupName string    `json:"rule_group_name"`
+	AlertID       uint      `json:"alert_id,omitempty"`
+	IsDuplicate   bool      `json:"is_duplicate"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// BackfillJob 历史回溯任务
+type BackfillJob struct {
+	ID            uint            `json:"id"`
+	MailboxID     uint            `json:"mailbox_id"`
+	MailboxName   string          `json:"mailbox_name"`
+	Folder        string          `json:"folder"`
+	Since         time.Time       `json:"since"`
+	Until         time.Time       `json:"until"`
+	RuleGroupIDs  []uint          `json:"rule_group_ids"`
+	DryRun        bool            `json:"dry_run"`
+	Notify        bool            `json:"notify"`
+	Status        string          `json:"status"`
+	Total         int             `json:"total"`          // 候选邮件总数
+	Processed     int             `json:"processed"`      // 已检查的邮件数
+	InRange       int             `json:"in_range"`       // 时间范围内的邮件数
+	Matched       int             `json:"matched"`        // 匹配规则组的邮件数
+	AlertsCreated int             `json:"alerts_created"` // 创建的告警数
+	Duplicates    int             `json:"duplicates"`     // 跳过的重复告警数
+	Errors        int             `json:"errors"`         // 处理错误数
+	Matches       []BackfillMatch `json:"matches"`
+	Error         string          `json:"error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// BackfillService 历史回溯服务，将指定时间范围内的邮件重新交给规则引擎处理
+// 不受监控启动时间的限制，用于新增规则组后对历史邮件补充告警
+type BackfillService struct {
+	mailboxRepo            *repository.MailboxRepository
+	ruleGroupRepo          repository.RuleGroupRepository
+	enhancedRuleEngine     EnhancedRuleEngineService
+	notificationDispatcher NotificationDispatcherService
+
+	jobs   map[uint]*BackfillJob
+	nextID uint
+	mutex  sync.RWMutex
+}
+
+// NewBackfillService 创建历史回溯服务实例
+func NewBackfillService(
+	mailboxRepo *repository.MailboxRepository,
+	ruleGroupRepo repository.RuleGroupRepository,
+	enhancedRuleEngine EnhancedRuleEngineService,
+	notificationDispatcher NotificationDispatcherService,
+) *BackfillService {
+	return &BackfillService{
+		mailboxRepo:            mailboxRepo,
+		ruleGroupRepo:          ruleGroupRepo,
+		enhancedRuleEngine:     enhancedRuleEngine,
+		notificationDispatcher: notificationDispatcher,
+		jobs:                   make(map[uint]*BackfillJob),
+	}
+}
+
+// StartBackfill 创建并在后台运行历史回溯任务
+func (s *BackfillService) StartBackfill(mailboxID uint, req *BackfillRequest) (*BackfillJob, error) {
+	mailbox, err := s.mailboxRepo.GetByID(mailboxID)
+	if err != nil {
+		return nil, fmt.Errorf("获取邮箱配置失败: %v", err)
+	}
+
+	since, err := parseBackfillTime(req.Since)
+	if err != nil {
+		return nil, fmt.Errorf("起始时间格式无效: %v", err)
+	}
+	var until time.Time
+	if strings.TrimSpace(req.Until) != "" {
+		if until, err = parseBackfillTime(req.Until); err != nil {
+			return nil, fmt.Errorf("结束时间格式无效: %v", err)
+		}
+		if !until.After(since) {
+			return nil, errors.New("结束时间必须晚于起始时间")
+		}
+	}
+
+	ruleGroups, err := s.selectRuleGroups(mailboxID, req.RuleGroupIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := strings.TrimSpace(req.Folder)
+	if folder == "" {
+		folder = "INBOX"
+	}
+
+	s.mutex.Lock()
+	for _, job := range s.jobs {
+		if job.MailboxID == mailboxID && job.Folder == folder && job.Status == BackfillStatusRunning {
+			s.mutex.Unlock()
+			return nil, fmt.Errorf("邮箱 %s 的文件夹 %s 已有正在运行的回溯任务 (ID: %d)", mailbox.Name, folder, job.ID)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.nextID++
+	job := &BackfillJob{
+		ID:           s.nextID,
+		MailboxID:    mailboxID,
+		MailboxName:  mailbox.Name,
+		Folder:       folder,
+		Since:        since,
+		Until:        until,
+		RuleGroupIDs: req.RuleGroupIDs,
+		DryRun:       req.DryRun,
+		Notify:       req.Notify && !req.DryRun,
+		Status:       BackfillStatusRunning,
+		CreatedAt:    time.Now(),
+		cancel:       cancel,
+	}
+	s.jobs[job.ID] = job
+	s.pruneJobs()
+	snapshot := job.snapshot()
+	s.mutex.Unlock()
+
+	go s.runBackfill(ctx, job, toMailboxConfig(mailbox), ruleGroups)
+
+	return snapshot, nil
+}
+
+// selectRuleGroups 获取回溯使用的规则组
+func (s *BackfillService) selectRuleGroups(mailboxID uint, ids []uint) ([]*model.RuleGroup, error) {
+	ruleGroups, err := s.ruleGroupRepo.GetByMailboxID(mailboxID)
+	if err != nil {
+		return nil, fmt.Errorf("获取邮箱规则组失败: %v", err)
+	}
+	if len(ids) == 0 {
+		if len(ruleGroups) == 0 {
+			return nil, errors.New("邮箱没有激活的规则组")
+		}
+		return ruleGroups, nil
+	}
+
+	byID := make(map[uint]*model.RuleGroup, len(ruleGroups))
+	for _, ruleGroup := range ruleGroups {
+		byID[ruleGroup.ID] = ruleGroup
+	}
+
+	selected := make([]*model.RuleGroup, 0, len(ids))
+	for _, id := range ids {
+		ruleGroup, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("规则组 %d 不存在、未激活或不属于该邮箱", id)
+		}
+		selected = append(selected, ruleGroup)
+	}
+	return selected, nil
+}
+
+// runBackfill 执行历史回溯任务
+func (s *BackfillService) runBackfill(ctx context.Context, job *BackfillJob, mailboxConfig email.MailboxConfig, ruleGroups []*model.RuleGroup) {
+	log.Printf("历史回溯任务 %d 开始: 邮箱=%s, 文件夹=%s, 试运行=%v", job.ID, job.MailboxName, job.Folder, job.DryRun)
+
+	handler := &backfillHandler{service: s, job: job, ruleGroups: ruleGroups}
+	query := email.RangeQuery{Folder: job.Folder, Since: job.Since, Until: job.Until}
+	err := email.NewClientFromConfig(mailboxConfig).FetchRange(ctx, query, handler)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+	job.FinishedAt = &now
+	job.cancel()
+	switch {
+	case errors.Is(err, context.Canceled):
+		job.Status = BackfillStatusCancelled
+	case err != nil:
+		job.Status = BackfillStatusFailed
+		job.Error = err.Error()
+	default:
+		job.Status = BackfillStatusCompleted
+	}
+
+	log.Printf("历史回溯任务 %d 结束: 状态=%s, 检查=%d/%d, 匹配=%d, 创建告警=%d",
+		job.ID, job.Status, job.Processed, job.Total, job.Matched, job.AlertsCreated)
+}
+
+// backfillHandler 实现email.RangeHandler，将历史邮件交给规则引擎处理
+type backfillHandler struct {
+	service    *BackfillService
+	job        *BackfillJob
+	ruleGroups []*model.RuleGroup
+}
+
+// RangeTotal 记录候选邮件总数
+func (h *backfillHandler) RangeTotal(total int) {
+	h.service.mutex.Lock()
+	h.job.Total = total
+	h.service.mutex.Unlock()
+}
+
+// HandleRangeEmail 处理一封历史邮件
+func (h *backfillHandler) HandleRangeEmail(emailData *email.EmailData) error {
+	if emailData == nil {
+		h.service.mutex.Lock()
+		h.job.Processed++
+		h.service.mutex.Unlock()
+		return nil
+	}
+
+	modelEmailData := toModelEmailData(emailData)
+	var matches []BackfillMatch
+	errorCount := 0
+
+	if h.job.DryRun {
+		matchResults, err := h.service.enhancedRuleEngine.MatchRuleGroups(modelEmailData, h.ruleGroups)
+		if err != nil {
+			errorCount++
+			log.Printf("历史回溯任务 %d: 规则组匹配失败: %v", h.job.ID, err)
+		}
+		for _, result := range matchResults {
+			if result.Matched {
+				matches = append(matches, newBackfillMatch(emailData, result.RuleGroup))
+			}
+		}
+	} else {
+		results, err := h.service.enhancedRuleEngine.ProcessEmailWithRuleGroupList(modelEmailData, h.ruleGroups)
+		if err != nil {
+			errorCount++
+			log.Printf("历史回溯任务 %d: 规则引擎处理失败: %v", h.job.ID, err)
+		}
+		for _, result := range results {
+			match := newBackfillMatch(emailData, result.RuleGroup)
+			match.IsDuplicate = result.IsDuplicate
+			match.Error = result.Error
+			if result.Error != "" {
+				errorCount++
+			}
+			if result.Created {
+				match.AlertID = result.Alert.ID
+				if h.job.Notify {
+					if err := h.service.notificationDispatcher.DispatchAlert(result.Alert); err != nil {
+						log.Printf("历史回溯任务 %d: 分发告警通知失败: %v", h.job.ID, err)
+					}
+				}
+			}
+			matches = append(matches, match)
+		}
+	}
+
+	h.service.mutex.Lock()
+	defer h.service.mutex.Unlock()
+
+	h.job.Processed++
+	h.job.InRange++
+	h.job.Errors += errorCount
+	if len(matches) > 0 {
+		h.job.Matched++
+	}
+	for _, match := range matches {
+		if match.AlertID != 0 {
+			h.job.AlertsCreated++
+		}
+		if match.IsDuplicate {
+			h.job.Duplicates++
+		}
+		if len(h.job.Matches) < maxBackfillMatches {
+			h.job.Matches = append(h.job.Matches, match)
+		}
+	}
+	return nil
+}
+
+// newBackfillMatch 创建匹配明细
+func newBackfillMatch(emailData *email.EmailData, ruleGroup *model.RuleGroup) BackfillMatch {
+	return BackfillMatch{
+		UID:           emailData.UID,
+		Subject:       emailData.Subject,
+		Sender:        emailData.Sender,
+		ReceivedAt:    emailData.ReceivedAt,
+		RuleGroupID:   ruleGroup.ID,
+		RuleGroupName: ruleGroup.Name,
+	}
+}
+
+// GetJob 获取历史回溯任务
+func (s *BackfillService) GetJob(id uint) (*BackfillJob, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, errors.New("回溯任务不存在")
+	}
+	return job.snapshot(), nil
+}
+
+// ListJobs 获取历史回溯任务列表（不含匹配明细），mailboxID为0时返回全部
+func (s *BackfillService) ListJobs(mailboxID uint) []*BackfillJob {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	jobs := make([]*BackfillJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if mailboxID != 0 && job.MailboxID != mailboxID {
+			continue
+		}
+		snapshot := job.snapshot()
+		snapshot.Matches = nil
+		jobs = append(jobs, snapshot)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID > jobs[j].ID })
+	return jobs
+}
+
+// CancelJob 取消正在运行的历史回溯任务
+func (s *BackfillService) CancelJob(id uint) error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return errors.New("回溯任务不存在")
+	}
+	if job.Status != BackfillStatusRunning {
+		return fmt.Errorf("回溯任务已结束，状态: %s", job.Status)
+	}
+	job.cancel()
+	return nil
+}
+
+// pruneJobs 清理最早结束的任务（需持有锁）
+func (s *BackfillService) pruneJobs() {
+	for len(s.jobs) > maxBackfillJobs {
+		var oldest *BackfillJob
+		for _, job := range s.jobs {
+			if job.Status == BackfillStatusRunning {
+				continue
+			}
+			if oldest == nil || job.ID < oldest.ID {
+				oldest = job
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		delete(s.jobs, oldest.ID)
+	}
+}
+
+// snapshot 复制任务状态，避免返回后被后台协程修改（需持有锁）
+func (job *BackfillJob) snapshot() *BackfillJob {
+	copied := *job
+	copied.Matches = append([]BackfillMatch(nil), job.Matches...)
+	copied.cancel = nil
+	return &copied
+}
+
+// parseBackfillTime 按系统时区解析回溯时间，未带时区偏移的时间视为系统时区
+func parseBackfillTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05Z07:00", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, timezone.System()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%s，应为 2006-01-02 15:04:05 或 2006-01-02", value)
+}
@@ -4,6 +4,8 @@ import (
 	"emailAlert/internal/model"
 	"emailAlert/internal/repository"
 	"emailAlert/pkg/notification"
+	"emailAlert/pkg/proxy"
+	"emailAlert/pkg/timezone"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -18,10 +20,11 @@ type ChannelService interface {
 	UpdateChannel(channel *model.Channel) error
 	DeleteChannel(id uint) error
 	TestChannel(id uint) error
-	TestChannelConfig(channelType, config string) error
+	TestChannelConfig(channelType, config, proxyURL string) error
 	UpdateChannelStatus(id uint, status string) error
 	GetChannelTypes() []string
 	SendNotification(channelID uint, title, content string) error
+	SendNotificationToChannel(channel *model.Channel, title, content string) error
 }
 
 // channelService 通知渠道服务实现
@@ -50,6 +53,12 @@ func (s *channelService) CreateChannel(channel *model.Channel) error {
 	if err := s.validateChannelConfig(channel.Type, channel.Config); err != nil {
 		return fmt.Errorf("渠道配置验证失败: %v", err)
 	}
+	if err := timezone.Validate(channel.Timezone); err != nil {
+		return err
+	}
+	if err := proxy.Validate(channel.Proxy); err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
 
 	return s.channelRepo.Create(channel)
 }
@@ -75,6 +84,12 @@ func (s *channelService) UpdateChannel(channel *model.Channel) error {
 	if err := s.validateChannelConfig(channel.Type, channel.Config); err != nil {
 		return fmt.Errorf("渠道配置验证失败: %v", err)
 	}
+	if err := timezone.Validate(channel.Timezone); err != nil {
+		return err
+	}
+	if err := proxy.Validate(channel.Proxy); err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
 
 	return s.channelRepo.Update(channel)
 }
@@ -94,7 +109,7 @@ func (s *channelService) TestChannel(id uint) error {
 	testResult := "测试成功"
 	testTime := time.Now()
 
-	err = s.testChannelByType(channel.Type, channel.Config)
+	err = s.testChannelByType(channel.Type, channel.Config, channel.Proxy)
 	if err != nil {
 		testResult = fmt.Sprintf("测试失败: %v", err)
 	}
@@ -105,9 +120,12 @@ func (s *channelService) TestChannel(id uint) error {
 	return err
 }
 
-// TestChannelConfig 测试渠道配置
-func (s *channelService) TestChannelConfig(channelType, config string) error {
-	return s.testChannelByType(channelType, config)
+// TestChannelConfig 测试渠道配置，proxyURL为空时使用全局代理
+func (s *channelService) TestChannelConfig(channelType, config, proxyURL string) error {
+	if err := proxy.Validate(proxyURL); err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+	return s.testChannelByType(channelType, config, proxyURL)
 }
 
 // UpdateChannelStatus 更新渠道状态
@@ -127,11 +145,16 @@ func (s *channelService) SendNotification(channelID uint, title, content string)
 		return fmt.Errorf("获取渠道信息失败: %v", err)
 	}
 
+	return s.SendNotificationToChannel(channel, title, content)
+}
+
+// SendNotificationToChannel 使用已加载的渠道配置发送通知，不再查询数据库
+func (s *channelService) SendNotificationToChannel(channel *model.Channel, title, content string) error {
 	if channel.Status != "active" {
 		return fmt.Errorf("渠道已停用")
 	}
 
-	return s.sendNotificationByType(channel.Type, channel.Config, title, content)
+	return s.sendNotificationByType(channel.Type, channel.Config, channel.Proxy, title, content)
 }
 
 // validateChannelConfig 验证渠道配置
@@ -216,86 +239,101 @@ func (s *channelService) validateDingTalkConfig(config *model.DingTalkConfig) er
 	return nil
 }
 
-// testChannelByType 根据类型测试渠道
-func (s *channelService) testChannelByType(channelType, config string) error {
+// testChannelByType 根据类型测试渠道，按渠道的代理配置连接
+func (s *channelService) testChannelByType(channelType, config, proxyURL string) error {
 	switch channelType {
 	case "wechat":
-		return s.testWeChatChannel(config)
+		return s.testWeChatChannel(config, proxyURL)
 	case "dingtalk":
-		return s.testDingTalkChannel(config)
+		return s.testDingTalkChannel(config, proxyURL)
 	case "email":
-		return s.testEmailChannel(config)
+		return s.testEmailChannel(config, proxyURL)
 	case "webhook":
-		return s.testWebhookChannel(config)
+		return s.testWebhookChannel(config, proxyURL)
 	default:
 		return fmt.Errorf("不支持的渠道类型: %s", channelType)
 	}
 }
 
 // testWeChatChannel 测试企业微信渠道
-func (s *channelService) testWeChatChannel(config string) error {
+func (s *channelService) testWeChatChannel(config, proxyURL string) error {
 	var wechatConfig model.WeChatConfig
 	if err := json.Unmarshal([]byte(config), &wechatConfig); err != nil {
 		return err
 	}
 
+	notifier, err := s.wechatNotifier.WithProxy(proxyURL)
+	if err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+
 	switch wechatConfig.Type {
 	case "robot":
-		return s.wechatNotifier.TestRobotConnection(wechatConfig.WebhookURL, wechatConfig.Key)
+		return notifier.TestRobotConnection(wechatConfig.WebhookURL, wechatConfig.Key)
 	case "app":
-		return s.wechatNotifier.TestAppConnection(wechatConfig.CorpID, wechatConfig.Secret, wechatConfig.AgentID, wechatConfig.ToUser)
+		return notifier.TestAppConnection(wechatConfig.CorpID, wechatConfig.Secret, wechatConfig.AgentID, wechatConfig.ToUser)
 	default:
 		return fmt.Errorf("不支持的企业微信类型: %s", wechatConfig.Type)
 	}
 }
 
 // testDingTalkChannel 测试钉钉渠道
-func (s *channelService) testDingTalkChannel(config string) error {
+func (s *channelService) testDingTalkChannel(config, proxyURL string) error {
 	var dingConfig model.DingTalkConfig
 	if err := json.Unmarshal([]byte(config), &dingConfig); err != nil {
 		return err
 	}
 
+	notifier, err := s.dingtalkNotifier.WithProxy(proxyURL)
+	if err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+
 	switch dingConfig.Type {
 	case "robot":
-		return s.dingtalkNotifier.TestRobotConnection(dingConfig.WebhookURL, dingConfig.Secret)
+		return notifier.TestRobotConnection(dingConfig.WebhookURL, dingConfig.Secret)
 	case "work":
-		return s.dingtalkNotifier.TestWorkConnection(dingConfig.AppKey, dingConfig.AppSecret, dingConfig.AgentID, dingConfig.UserIDs)
+		return notifier.TestWorkConnection(dingConfig.AppKey, dingConfig.AppSecret, dingConfig.AgentID, dingConfig.UserIDs)
 	default:
 		return fmt.Errorf("不支持的钉钉类型: %s", dingConfig.Type)
 	}
 }
 
-// sendNotificationByType 根据类型发送通知
-func (s *channelService) sendNotificationByType(channelType, config, title, content string) error {
+// sendNotificationByType 根据类型发送通知，按渠道的代理配置连接
+func (s *channelService) sendNotificationByType(channelType, config, proxyURL, title, content string) error {
 	switch channelType {
 	case "wechat":
-		return s.sendWeChatNotification(config, title, content)
+		return s.sendWeChatNotification(config, proxyURL, title, content)
 	case "dingtalk":
-		return s.sendDingTalkNotification(config, title, content)
+		return s.sendDingTalkNotification(config, proxyURL, title, content)
 	case "email":
-		return s.sendEmailNotification(config, title, content)
+		return s.sendEmailNotification(config, proxyURL, title, content)
 	case "webhook":
-		return s.sendWebhookNotification(config, title, content)
+		return s.sendWebhookNotification(config, proxyURL, title, content)
 	default:
 		return fmt.Errorf("不支持的渠道类型: %s", channelType)
 	}
 }
 
 // sendWeChatNotification 发送企业微信通知
-func (s *channelService) sendWeChatNotification(config, title, content string) error {
+func (s *channelService) sendWeChatNotification(config, proxyURL, title, content string) error {
 	var wechatConfig model.WeChatConfig
 	if err := json.Unmarshal([]byte(config), &wechatConfig); err != nil {
 		return err
 	}
 
+	notifier, err := s.wechatNotifier.WithProxy(proxyURL)
+	if err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+
 	message := fmt.Sprintf("**%s**\n\n%s", title, content)
 
 	switch wechatConfig.Type {
 	case "robot":
-		return s.wechatNotifier.SendRobotMessage(wechatConfig.WebhookURL, wechatConfig.Key, message, "markdown")
+		return notifier.SendRobotMessage(wechatConfig.WebhookURL, wechatConfig.Key, message, "markdown")
 	case "app":
-		return s.wechatNotifier.SendAppMessage(wechatConfig.CorpID, wechatConfig.Secret, wechatConfig.AgentID,
+		return notifier.SendAppMessage(wechatConfig.CorpID, wechatConfig.Secret, wechatConfig.AgentID,
 			wechatConfig.ToUser, wechatConfig.ToParty, wechatConfig.ToTag, title, content)
 	default:
 		return fmt.Errorf("不支持的企业微信类型: %s", wechatConfig.Type)
@@ -303,39 +341,49 @@ func (s *channelService) sendWeChatNotification(config, title, content string) e
 }
 
 // sendDingTalkNotification 发送钉钉通知
-func (s *channelService) sendDingTalkNotification(config, title, content string) error {
+func (s *channelService) sendDingTalkNotification(config, proxyURL, title, content string) error {
 	var dingConfig model.DingTalkConfig
 	if err := json.Unmarshal([]byte(config), &dingConfig); err != nil {
 		return err
 	}
 
+	notifier, err := s.dingtalkNotifier.WithProxy(proxyURL)
+	if err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+
 	switch dingConfig.Type {
 	case "robot":
 		// 钉钉群机器人支持markdown格式
 		message := fmt.Sprintf("## %s\n\n%s", title, content)
-		return s.dingtalkNotifier.SendRobotMessage(dingConfig.WebhookURL, dingConfig.Secret, message, title, "markdown")
+		return notifier.SendRobotMessage(dingConfig.WebhookURL, dingConfig.Secret, message, title, "markdown")
 	case "work":
 		// 钉钉工作通知使用OA消息格式
-		return s.dingtalkNotifier.SendWorkMessage(dingConfig.AppKey, dingConfig.AppSecret, dingConfig.AgentID, dingConfig.UserIDs, title, content)
+		return notifier.SendWorkMessage(dingConfig.AppKey, dingConfig.AppSecret, dingConfig.AgentID, dingConfig.UserIDs, title, content)
 	default:
 		return fmt.Errorf("不支持的钉钉类型: %s", dingConfig.Type)
 	}
 }
 
 // testWebhookChannel 测试Webhook渠道
-func (s *channelService) testWebhookChannel(config string) error {
+func (s *channelService) testWebhookChannel(config, proxyURL string) error {
 	var webhookConfig notification.WebhookConfig
 	if err := json.Unmarshal([]byte(config), &webhookConfig); err != nil {
 		return err
 	}
 
+	notifier, err := s.webhookNotifier.WithProxy(proxyURL)
+	if err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+
 	// 先验证配置
-	if err := s.webhookNotifier.ValidateConfig(&webhookConfig); err != nil {
+	if err := notifier.ValidateConfig(&webhookConfig); err != nil {
 		return err
 	}
 
 	// 然后测试连接
-	response, err := s.webhookNotifier.TestConnection(&webhookConfig)
+	response, err := notifier.TestConnection(&webhookConfig)
 	if err != nil {
 		return err
 	}
@@ -348,13 +396,18 @@ func (s *channelService) testWebhookChannel(config string) error {
 }
 
 // sendWebhookNotification 发送Webhook通知
-func (s *channelService) sendWebhookNotification(config, title, content string) error {
+func (s *channelService) sendWebhookNotification(config, proxyURL, title, content string) error {
 	var webhookConfig notification.WebhookConfig
 	if err := json.Unmarshal([]byte(config), &webhookConfig); err != nil {
 		return err
 	}
 
-	response, err := s.webhookNotifier.SendMessage(&webhookConfig, title, content)
+	notifier, err := s.webhookNotifier.WithProxy(proxyURL)
+	if err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+
+	response, err := notifier.SendMessage(&webhookConfig, title, content)
 	if err != nil {
 		return err
 	}
@@ -367,19 +420,24 @@ func (s *channelService) sendWebhookNotification(config, title, content string)
 }
 
 // testEmailChannel 测试邮件渠道
-func (s *channelService) testEmailChannel(config string) error {
+func (s *channelService) testEmailChannel(config, proxyURL string) error {
 	var emailConfig notification.EmailConfig
 	if err := json.Unmarshal([]byte(config), &emailConfig); err != nil {
 		return err
 	}
 
+	notifier, err := s.emailNotifier.WithProxy(proxyURL)
+	if err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+
 	// 先验证配置
-	if err := s.emailNotifier.ValidateConfig(&emailConfig); err != nil {
+	if err := notifier.ValidateConfig(&emailConfig); err != nil {
 		return err
 	}
 
 	// 然后测试连接
-	response, err := s.emailNotifier.TestConnection(&emailConfig)
+	response, err := notifier.TestConnection(&emailConfig)
 	if err != nil {
 		return err
 	}
@@ -392,13 +450,18 @@ func (s *channelService) testEmailChannel(config string) error {
 }
 
 // sendEmailNotification 发送邮件通知
-func (s *channelService) sendEmailNotification(config, title, content string) error {
+func (s *channelService) sendEmailNotification(config, proxyURL, title, content string) error {
 	var emailConfig notification.EmailConfig
 	if err := json.Unmarshal([]byte(config), &emailConfig); err != nil {
 		return err
 	}
 
-	response, err := s.emailNotifier.SendMessage(&emailConfig, title, content)
+	notifier, err := s.emailNotifier.WithProxy(proxyURL)
+	if err != nil {
+		return fmt.Errorf("代理配置无效: %v", err)
+	}
+
+	response, err := notifier.SendMessage(&emailConfig, title, content)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"emailAlert/pkg/email"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxImportErrors 导入结果中保留的错误明细上限
+const maxImportErrors = 100
+
+// ImportResult 邮件文件导入结果
+type ImportResult struct {
+	Total     int      `json:"total"`     // 读取到的邮件数
+	Processed int      `json:"processed"` // 交给规则引擎处理的邮件数
+	Matched   int      `json:"matched"`   // 匹配规则组的邮件数
+	Skipped   int      `json:"skipped"`   // 重复等原因跳过的邮件数
+	Failed    int      `json:"failed"`    // 解析或处理失败的邮件数
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// addError 记录导入错误
+func (r *ImportResult) addError(format string, args ...interface{}) {
+	r.Failed++
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+	}
+}
+
+// Merge 合并多个文件的导入结果
+func (r *ImportResult) Merge(other *ImportResult) {
+	r.Total += other.Total
+	r.Processed += other.Processed
+	r.Matched += other.Matched
+	r.Skipped += other.Skipped
+	r.Failed += other.Failed
+	for _, msg := range other.Errors {
+		if len(r.Errors) >= maxImportErrors {
+			break
+		}
+		r.Errors = append(r.Errors, msg)
+	}
+}
+
+// ImportEmails 导入.eml或mbox文件中的邮件，交给指定邮箱的处理流程（含去重）
+// folder为邮件所属的文件夹（用于folder条件匹配），为空时使用INBOX
+func (s *EmailMonitorService) ImportEmails(mailboxID uint, filename, format, folder string, r io.Reader) (*ImportResult, error) {
+	if _, err := s.mailboxRepo.GetByID(mailboxID); err != nil {
+		return nil, fmt.Errorf("获取邮箱配置失败: %v", err)
+	}
+
+	result := &ImportResult{}
+	parser := email.NewEmailParser()
+	err := email.ReadMessages(filename, format, r, func(name, raw string) error {
+		s.importMessage(parser, mailboxID, folder, name, raw, result)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	s.addLog("info", fmt.Sprintf("导入邮件文件 %s 完成: 共 %d 封, 处理 %d 封, 匹配 %d 封, 跳过 %d 封, 失败 %d 封",
+		filename, result.Total, result.Processed, result.Matched, result.Skipped, result.Failed), mailboxID)
+	return result, nil
+}
+
+// ImportMaildir 导入Maildir目录中的邮件
+func (s *EmailMonitorService) ImportMaildir(mailboxID uint, dir, folder string) (*ImportResult, error) {
+	if _, err := s.mailboxRepo.GetByID(mailboxID); err != nil {
+		return nil, fmt.Errorf("获取邮箱配置失败: %v", err)
+	}
+
+	result := &ImportResult{}
+	parser := email.NewEmailParser()
+	err := email.ReadMaildir(dir, func(name, raw string) error {
+		s.importMessage(parser, mailboxID, folder, name, raw, result)
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	s.addLog("info", fmt.Sprintf("导入Maildir目录 %s 完成: 共 %d 封, 处理 %d 封, 匹配 %d 封, 跳过 %d 封, 失败 %d 封",
+		dir, result.Total, result.Processed, result.Matched, result.Skipped, result.Failed), mailboxID)
+	return result, nil
+}
+
+// ReceiveEmail 处理接收服务推送的原始邮件，与监控拉取的邮件走相同的处理流程
+func (s *EmailMonitorService) ReceiveEmail(mailboxID uint, raw []byte) (*email.HandleResult, error) {
+	emailData, err := email.NewEmailParser().ParseMessage(string(raw))
+	if err != nil {
+		return nil, fmt.Errorf("解析邮件失败: %v", err)
+	}
+
+	emailData.Pushed = true
+	emailData.Folder = "INBOX"
+	emailData.ReceivedAt = time.Now()
+
+	return s.HandleEmail(mailboxID, emailData)
+}
+
+// importMessage 解析单封邮件并交给HandleEmail处理
+func (s *EmailMonitorService) importMessage(parser *email.EmailParser, mailboxID uint, folder, name, raw string, result *ImportResult) {
+	result.Total++
+
+	emailData, err := parser.ParseMessage(raw)
+	if err != nil {
+		result.addError("%s: 解析邮件失败: %v", name, err)
+		return
+	}
+
+	emailData.Imported = true
+	emailData.Folder = strings.TrimSpace(folder)
+	if emailData.Folder == "" {
+		emailData.Folder = "INBOX"
+	}
+	if emailData.ReceivedAt.IsZero() {
+		emailData.ReceivedAt = time.Now()
+	}
+
+	handleResult, err := s.HandleEmail(mailboxID, emailData)
+	if err != nil {
+		result.addError("%s: 处理邮件失败: %v", name, err)
+		return
+	}
+	if handleResult == nil {
+		result.Skipped++
+		return
+	}
+
+	result.Processed++
+	if handleResult.Matched {
+		result.Matched++
+	}
+}
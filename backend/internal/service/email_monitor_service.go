@@ -4,6 +4,7 @@ import (
 	"emailAlert/internal/model"
 	"emailAlert/internal/repository"
 	"emailAlert/pkg/email"
+	"emailAlert/pkg/timezone"
 	"fmt"
 	"log"
 	"sync"
@@ -22,6 +23,7 @@ type LogEntry struct {
 type EmailMonitorService struct {
 	mailboxRepo            *repository.MailboxRepository
 	alertRepo              *repository.AlertRepository
+	checkpointRepo         *repository.MailboxCheckpointRepository
 	enhancedRuleEngine     EnhancedRuleEngineService
 	notificationDispatcher NotificationDispatcherService
 	monitor                *email.Monitor
@@ -34,12 +36,14 @@ type EmailMonitorService struct {
 func NewEmailMonitorService(
 	mailboxRepo *repository.MailboxRepository,
 	alertRepo *repository.AlertRepository,
+	checkpointRepo *repository.MailboxCheckpointRepository,
 	enhancedRuleEngine EnhancedRuleEngineService,
 	notificationDispatcher NotificationDispatcherService,
 ) *EmailMonitorService {
 	service := &EmailMonitorService{
 		mailboxRepo:            mailboxRepo,
 		alertRepo:              alertRepo,
+		checkpointRepo:         checkpointRepo,
 		enhancedRuleEngine:     enhancedRuleEngine,
 		notificationDispatcher: notificationDispatcher,
 		logChannel:             make(chan LogEntry, 100),
@@ -49,6 +53,8 @@ func NewEmailMonitorService(
 	// 创建邮件监控器，传入处理器
 	config := email.DefaultMonitorConfig()
 	service.monitor = email.NewMonitor(config, service)
+	service.monitor.SetCheckpointStore(service)
+	service.monitor.SetSuspender(service)
 
 	// 启动日志分发协程
 	go service.startLogDispatcher()
@@ -113,42 +119,29 @@ func (s *EmailMonitorService) addLog(level, message string, mailboxID ...uint) {
 }
 
 // HandleEmail 实现EmailHandler接口，处理收到的邮件
-func (s *EmailMonitorService) HandleEmail(mailboxID uint, emailData *email.EmailData) error {
+func (s *EmailMonitorService) HandleEmail(mailboxID uint, emailData *email.EmailData) (*email.HandleResult, error) {
 	s.addLog("info", fmt.Sprintf("收到邮件: 主题=%s, 发件人=%s", emailData.Subject, emailData.Sender), mailboxID)
 
-	// 定义北京时区
-	beijingTZ, err := time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		s.addLog("error", fmt.Sprintf("加载北京时区失败: %v", err), mailboxID)
-		beijingTZ = time.FixedZone("CST", 8*3600) // 使用固定时区作为后备
-	}
-
-	// 验证邮件接收时间是否在监控启动时间之后
-	status := s.monitor.GetStatus()
-	if startTimeStr, ok := status["start_time"].(string); ok {
-		// 使用ParseInLocation确保解析时保持北京时区，而不是转换为UTC
-		if startTime, err := time.ParseInLocation("2006-01-02 15:04:05", startTimeStr, beijingTZ); err == nil {
-			// startTime已经是北京时间了，不需要再次转换
-
-			s.addLog("debug", fmt.Sprintf("北京时间比较: 邮件时间=%s, 启动时间=%s",
-				emailData.ReceivedAt.Format("2006-01-02 15:04:05"),
-				startTime.Format("2006-01-02 15:04:05")), mailboxID)
-
-			// 验证邮件接收时间是否在监控启动时间之后
-			if !emailData.ReceivedAt.IsZero() && !emailData.ReceivedAt.After(startTime) {
-				s.addLog("warning", fmt.Sprintf("邮件接收时间(%s)早于或等于监控启动时间(%s)，跳过处理: %s",
-					emailData.ReceivedAt.Format("2006-01-02 15:04:05"),
-					startTime.Format("2006-01-02 15:04:05"),
-					emailData.Subject), mailboxID)
-				return nil
-			} else {
-				s.addLog("info", fmt.Sprintf("邮件时间验证通过: 邮件时间=%s > 监控启动时间=%s",
-					emailData.ReceivedAt.Format("2006-01-02 15:04:05"),
-					startTime.Format("2006-01-02 15:04:05")), mailboxID)
-			}
-		} else {
-			s.addLog("error", fmt.Sprintf("解析监控启动时间失败: %v", err), mailboxID)
+	// 验证邮件接收时间是否在监控启动时间之后（从检查点恢复的邮件不受此限制）
+	if emailData.Resumed {
+		s.addLog("info", fmt.Sprintf("邮件来自检查点恢复，跳过启动时间验证: %s", emailData.Subject), mailboxID)
+	} else if emailData.Imported {
+		s.addLog("info", fmt.Sprintf("邮件来自文件导入，跳过启动时间验证: %s", emailData.Subject), mailboxID)
+	} else if emailData.Pushed {
+		s.addLog("info", fmt.Sprintf("邮件来自SMTP/LMTP推送，跳过启动时间验证: %s", emailData.Subject), mailboxID)
+	} else if startTime := s.monitor.GetStartTime(); !startTime.IsZero() {
+		// 按UTC时刻比较，展示时转换为系统时区
+		loc := timezone.System()
+		if !emailData.ReceivedAt.IsZero() && !emailData.ReceivedAt.After(startTime) {
+			s.addLog("warning", fmt.Sprintf("邮件接收时间(%s)早于或等于监控启动时间(%s)，跳过处理: %s",
+				emailData.ReceivedAt.In(loc).Format("2006-01-02 15:04:05"),
+				startTime.In(loc).Format("2006-01-02 15:04:05"),
+				emailData.Subject), mailboxID)
+			return nil, nil
 		}
+		s.addLog("info", fmt.Sprintf("邮件时间验证通过: 邮件时间=%s > 监控启动时间=%s",
+			emailData.ReceivedAt.In(loc).Format("2006-01-02 15:04:05"),
+			startTime.In(loc).Format("2006-01-02 15:04:05")), mailboxID)
 	} else {
 		s.addLog("warning", "无法获取监控启动时间，跳过时间验证", mailboxID)
 	}
@@ -163,6 +156,12 @@ func (s *EmailMonitorService) HandleEmail(mailboxID uint, emailData *email.Email
 		if duplicateCheckErr != nil {
 			s.addLog("error", fmt.Sprintf("检查邮件MessageID是否存在失败: %v", duplicateCheckErr), mailboxID)
 		}
+	} else if emailData.UIDL != "" {
+		// POP3邮件没有MessageID时使用完整UIDL去重，UIDL在邮箱内唯一且跨会话稳定
+		isDuplicate, duplicateCheckErr = s.alertRepo.ExistsByUIDL(mailboxID, emailData.UIDL)
+		if duplicateCheckErr != nil {
+			s.addLog("error", fmt.Sprintf("检查邮件UIDL是否存在失败: %v", duplicateCheckErr), mailboxID)
+		}
 	} else {
 		// MessageID为空时，使用组合字段进行去重检查
 		s.addLog("warning", fmt.Sprintf("邮件MessageID为空，使用组合字段进行去重检查: %s", emailData.Subject), mailboxID)
@@ -182,37 +181,17 @@ func (s *EmailMonitorService) HandleEmail(mailboxID uint, emailData *email.Email
 
 	if isDuplicate {
 		s.addLog("warning", fmt.Sprintf("邮件已存在，跳过处理: %s", emailData.Subject), mailboxID)
-		return nil
+		return nil, nil
 	}
 
 	// 转换邮件数据格式
-	modelEmailData := &model.EmailData{
-		UID:         emailData.UID,
-		Subject:     emailData.Subject,
-		Sender:      emailData.Sender,
-		Content:     emailData.Content,
-		HTMLContent: emailData.HTMLContent,
-		ReceivedAt:  emailData.ReceivedAt,
-		MessageID:   emailData.MessageID,
-		Size:        emailData.Size,
-		Flags:       emailData.Flags,
-		// 暂时留空，后续可根据需要扩展
-		To: []string{},
-		CC: []string{},
-		AttachmentNames: func() []string {
-			names := make([]string, len(emailData.Attachments))
-			for i, att := range emailData.Attachments {
-				names[i] = att.Name
-			}
-			return names
-		}(),
-	}
+	modelEmailData := toModelEmailData(emailData)
 
 	// 使用增强版规则引擎处理邮件
 	results, err := s.enhancedRuleEngine.ProcessEmailWithRuleGroups(modelEmailData, mailboxID)
 	if err != nil {
 		s.addLog("error", fmt.Sprintf("规则引擎处理失败: %v", err), mailboxID)
-		return fmt.Errorf("规则引擎处理失败: %v", err)
+		return nil, fmt.Errorf("规则引擎处理失败: %v", err)
 	}
 
 	// 统计处理结果
@@ -221,9 +200,12 @@ func (s *EmailMonitorService) HandleEmail(mailboxID uint, emailData *email.Email
 	duplicatesSkipped := 0
 	errors := 0
 
+	handleResult := &email.HandleResult{}
 	for _, result := range results {
 		if result.RuleGroup != nil {
 			totalMatched++
+			handleResult.Matched = true
+			handleResult.Actions = mergeActions(handleResult.Actions, result.RuleGroup.Actions)
 		}
 		if result.Created {
 			alertsCreated++
@@ -248,6 +230,146 @@ func (s *EmailMonitorService) HandleEmail(mailboxID uint, emailData *email.Email
 			totalMatched, alertsCreated, duplicatesSkipped, errors), mailboxID)
 	}
 
+	return handleResult, nil
+}
+
+// toModelEmailData 将监控器解析的邮件转换为规则引擎使用的邮件数据
+func toModelEmailData(emailData *email.EmailData) *model.EmailData {
+	modelEmailData := &model.EmailData{
+		UID:         emailData.UID,
+		Subject:     emailData.Subject,
+		Sender:      emailData.Sender,
+		Content:     emailData.Content,
+		HTMLContent: emailData.HTMLContent,
+		ReceivedAt:  emailData.ReceivedAt,
+		MessageID:   emailData.MessageID,
+		UIDL:        emailData.UIDL,
+		Size:        emailData.Size,
+		Flags:       emailData.Flags,
+		Folder:      emailData.Folder,
+		Headers:     emailData.Headers,
+		FromName:    emailData.FromName,
+		To:          email.FormatAddresses(emailData.To),
+		CC:          email.FormatAddresses(emailData.CC),
+		BCC:         email.FormatAddresses(emailData.BCC),
+		ReplyTo:     email.FormatAddresses(emailData.ReplyTo),
+		AttachmentNames: func() []string {
+			names := make([]string, len(emailData.Attachments))
+			for i, att := range emailData.Attachments {
+				names[i] = att.Name
+			}
+			return names
+		}(),
+		Attachments: func() []model.AttachmentInfo {
+			attachments := make([]model.AttachmentInfo, len(emailData.Attachments))
+			for i, att := range emailData.Attachments {
+				attachments[i] = model.AttachmentInfo{
+					Name:        att.Name,
+					MimeType:    att.MimeType,
+					Size:        att.Size,
+					SHA256:      att.SHA256,
+					TextContent: att.TextContent,
+				}
+			}
+			return attachments
+		}(),
+	}
+
+	if emailData.SenderHeader != nil {
+		modelEmailData.SenderHeader = emailData.SenderHeader.String()
+	}
+
+	return modelEmailData
+}
+
+// mergeActions 合并多个匹配规则组配置的处理后动作，去除重复项
+// 按规则组优先级顺序合并，移动或删除只保留第一个
+func mergeActions(actions []email.MailboxAction, spec string) []email.MailboxAction {
+	groupActions, err := email.ParseActions(spec)
+	if err != nil {
+		log.Printf("解析规则组处理后动作 %q 失败: %v", spec, err)
+		return actions
+	}
+
+	for _, action := range groupActions {
+		duplicate := false
+		for _, existing := range actions {
+			if existing == action || (isTerminalAction(existing) && isTerminalAction(action)) {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// isTerminalAction 判断是否为移动或删除动作（执行后邮件离开当前文件夹）
+func isTerminalAction(action email.MailboxAction) bool {
+	return action.Type == email.ActionMove || action.Type == email.ActionDelete
+}
+
+// HandleActionResult 实现ActionResultHandler接口，将处理后动作结果写入监控日志
+func (s *EmailMonitorService) HandleActionResult(mailboxID uint, result email.ActionResult) {
+	if result.Error != nil {
+		s.addLog("error", fmt.Sprintf("执行处理后动作 %s 失败: 文件夹=%s, 邮件数=%d, 错误=%v",
+			result.Action, result.Folder, len(result.UIDs), result.Error), mailboxID)
+		return
+	}
+	s.addLog("success", fmt.Sprintf("执行处理后动作 %s 成功: 文件夹=%s, 邮件数=%d",
+		result.Action, result.Folder, len(result.UIDs)), mailboxID)
+}
+
+// LoadCheckpoint 实现CheckpointStore接口，读取邮箱文件夹检查点
+func (s *EmailMonitorService) LoadCheckpoint(mailboxID uint, folder string) (*email.Checkpoint, error) {
+	checkpoint, err := s.checkpointRepo.Get(mailboxID, folder)
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+
+	return &email.Checkpoint{
+		UIDValidity: checkpoint.UIDValidity,
+		LastUID:     checkpoint.LastUID,
+		SeenUIDLs:   checkpoint.SeenUIDLs,
+	}, nil
+}
+
+// SaveCheckpoint 实现CheckpointStore接口，保存邮箱文件夹检查点
+func (s *EmailMonitorService) SaveCheckpoint(mailboxID uint, folder string, checkpoint email.Checkpoint) error {
+	return s.checkpointRepo.Save(&model.MailboxCheckpoint{
+		MailboxID:   mailboxID,
+		Folder:      folder,
+		UIDValidity: checkpoint.UIDValidity,
+		LastUID:     checkpoint.LastUID,
+		SeenUIDLs:   checkpoint.SeenUIDLs,
+		CheckedAt:   time.Now(),
+	})
+}
+
+// GetCheckpoints 获取邮箱检查点列表，mailboxID为0时返回全部
+func (s *EmailMonitorService) GetCheckpoints(mailboxID uint) ([]model.MailboxCheckpoint, error) {
+	checkpoints, err := s.checkpointRepo.List(mailboxID)
+	if err != nil {
+		return nil, fmt.Errorf("获取检查点失败: %v", err)
+	}
+	return checkpoints, nil
+}
+
+// ResetCheckpoint 重置邮箱检查点，folder为空时重置该邮箱的全部文件夹
+// 重置后下次检查将以当前最新邮件为基线重新开始
+func (s *EmailMonitorService) ResetCheckpoint(mailboxID uint, folder string) error {
+	if _, err := s.mailboxRepo.GetByID(mailboxID); err != nil {
+		return err
+	}
+
+	if err := s.checkpointRepo.Delete(mailboxID, folder); err != nil {
+		return fmt.Errorf("重置检查点失败: %v", err)
+	}
+
+	s.monitor.ResetCheckpoint(mailboxID, folder)
+	s.addLog("warning", fmt.Sprintf("邮箱检查点已重置: 邮箱ID=%d", mailboxID), mailboxID)
 	return nil
 }
 
@@ -268,18 +390,7 @@ func (s *EmailMonitorService) Start() error {
 	var monitorConfigs []email.MailboxConfig
 	for _, mb := range mailboxes {
 		// 直接使用明文密码
-		config := email.MailboxConfig{
-			ID:       mb.ID,
-			Name:     mb.Name,
-			Email:    mb.Email,
-			Host:     mb.Host,
-			Port:     mb.Port,
-			Username: mb.Username,
-			Password: mb.Password,
-			Protocol: mb.Protocol,
-			SSL:      mb.SSL,
-			Status:   mb.Status,
-		}
+		config := toMailboxConfig(&mb)
 		monitorConfigs = append(monitorConfigs, config)
 		s.addLog("info", fmt.Sprintf("准备监控邮箱: %s (%s)", mb.Name, mb.Email), mb.ID)
 	}
@@ -316,6 +427,19 @@ func (s *EmailMonitorService) GetStatus() map[string]interface{} {
 	return s.monitor.GetStatus()
 }
 
+// SuspendMailbox 邮箱连续认证失败被自动暂停时，持久化暂停状态，需手动重新启用
+func (s *EmailMonitorService) SuspendMailbox(mailboxID uint, reason string) {
+	if err := s.mailboxRepo.UpdateStatus(mailboxID, email.MailboxStatusSuspended); err != nil {
+		s.addLog("error", fmt.Sprintf("保存邮箱暂停状态失败: %v", err), mailboxID)
+	}
+	s.addLog("error", fmt.Sprintf("邮箱已自动暂停监控，请检查账号密码或授权码后重新启用: %s", reason), mailboxID)
+}
+
+// GetMailboxHealth 获取各邮箱的连接健康状态
+func (s *EmailMonitorService) GetMailboxHealth() []email.MailboxHealth {
+	return s.monitor.GetHealth()
+}
+
 // RefreshMailboxes 刷新邮箱配置（当邮箱配置发生变化时调用）
 func (s *EmailMonitorService) RefreshMailboxes() error {
 	s.addLog("info", "正在刷新邮箱配置...")
@@ -338,18 +462,7 @@ func (s *EmailMonitorService) RefreshMailboxes() error {
 	var monitorConfigs []email.MailboxConfig
 	for _, mb := range mailboxes {
 		// 直接使用明文密码
-		config := email.MailboxConfig{
-			ID:       mb.ID,
-			Name:     mb.Name,
-			Email:    mb.Email,
-			Host:     mb.Host,
-			Port:     mb.Port,
-			Username: mb.Username,
-			Password: mb.Password,
-			Protocol: mb.Protocol,
-			SSL:      mb.SSL,
-			Status:   mb.Status,
-		}
+		config := toMailboxConfig(&mb)
 		monitorConfigs = append(monitorConfigs, config)
 	}
 
@@ -360,27 +473,23 @@ func (s *EmailMonitorService) RefreshMailboxes() error {
 	return nil
 }
 
-// AddMailbox 添加邮箱到监控
+// AddMailbox 添加邮箱到监控，已在监控中的邮箱以新配置重启监控协程
 func (s *EmailMonitorService) AddMailbox(mailbox model.Mailbox) error {
 	if !s.monitor.IsRunning() {
 		return nil // 如果监控未运行，不需要添加
 	}
 
-	// 直接使用明文密码
-	config := email.MailboxConfig{
-		ID:       mailbox.ID,
-		Name:     mailbox.Name,
-		Email:    mailbox.Email,
-		Host:     mailbox.Host,
-		Port:     mailbox.Port,
-		Username: mailbox.Username,
-		Password: mailbox.Password,
-		Protocol: mailbox.Protocol,
-		SSL:      mailbox.SSL,
-		Status:   mailbox.Status,
+	// 停用的邮箱不再监控
+	if mailbox.Status != "active" {
+		s.RemoveMailbox(mailbox.ID)
+		return nil
 	}
 
+	// 直接使用明文密码
+	config := toMailboxConfig(&mailbox)
+
 	s.monitor.AddMailbox(config)
+	s.addLog("info", fmt.Sprintf("邮箱 %s (%s) 已加入监控", mailbox.Name, mailbox.Email), mailbox.ID)
 	return nil
 }
 
@@ -399,6 +508,8 @@ func (s *EmailMonitorService) UpdateMonitorConfig(config *email.MonitorConfig) e
 
 	// 创建新的监控器
 	s.monitor = email.NewMonitor(config, s)
+	s.monitor.SetCheckpointStore(s)
+	s.monitor.SetSuspender(s)
 
 	if wasRunning {
 		return s.Start()
@@ -5,18 +5,23 @@ import (
 	"emailAlert/internal/repository"
 	"fmt"
 	"log"
-	"regexp"
+	"net/textproto"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 // EnhancedRuleEngineService 增强版规则执行引擎服务接口
 type EnhancedRuleEngineService interface {
 	ProcessEmailWithRuleGroups(emailData *model.EmailData, mailboxID uint) ([]*EnhancedAlertResult, error)
+	ProcessEmailWithRuleGroupList(emailData *model.EmailData, ruleGroups []*model.RuleGroup) ([]*EnhancedAlertResult, error)
 	MatchRuleGroups(emailData *model.EmailData, ruleGroups []*model.RuleGroup) ([]*RuleGroupMatchResult, error)
+	MatchRuleGroupWithConditions(emailData *model.EmailData, ruleGroup *model.RuleGroup, conditions []*model.MatchCondition) *RuleGroupMatchResult
 	MatchConditions(emailData *model.EmailData, conditions []*model.MatchCondition) ([]*ConditionMatchResult, error)
 	MatchSingleCondition(emailData *model.EmailData, condition *model.MatchCondition) (bool, string, error)
 	ExtractEmailFields(emailData *model.EmailData) map[string]string
 	GetEnhancedRuleEngineStats() (map[string]interface{}, error)
+	InvalidateRuleCache(ruleGroupID uint)
 }
 
 // enhancedRuleEngineService 增强版规则执行引擎服务实现
@@ -24,6 +29,7 @@ type enhancedRuleEngineService struct {
 	ruleGroupRepo repository.RuleGroupRepository
 	conditionRepo repository.MatchConditionRepository
 	alertRepo     repository.AlertRepository
+	cache         *ruleCache
 }
 
 // EnhancedAlertResult 增强版告警处理结果
@@ -45,6 +51,18 @@ type RuleGroupMatchResult struct {
 	Logic            string                  `json:"logic"`
 	Reason           string                  `json:"reason"`
 	ConditionResults []*ConditionMatchResult `json:"condition_results"`
+	Tree             *ConditionNodeResult    `json:"tree,omitempty"` // 条件树各节点的匹配结果，规则组配置了条件树时返回
+	Vars             map[string]string       `json:"vars,omitempty"` // 匹配成功后按提取规则提取的变量
+}
+
+// ConditionNodeResult 条件树节点匹配结果
+type ConditionNodeResult struct {
+	Op          string                 `json:"op,omitempty"`           // 运算符：and/or/not，叶子节点为空
+	ConditionID uint                   `json:"condition_id,omitempty"` // 叶子节点引用的条件ID
+	Matched     bool                   `json:"matched"`
+	Skipped     bool                   `json:"skipped"` // 引用的条件已停用或不存在，不参与计算
+	Reason      string                 `json:"reason"`
+	Children    []*ConditionNodeResult `json:"children,omitempty"`
 }
 
 // ConditionMatchResult 条件匹配结果
@@ -66,13 +84,12 @@ func NewEnhancedRuleEngineService(
 		ruleGroupRepo: ruleGroupRepo,
 		conditionRepo: conditionRepo,
 		alertRepo:     alertRepo,
+		cache:         newRuleCache(),
 	}
 }
 
 // ProcessEmailWithRuleGroups 使用规则组处理邮件
 func (s *enhancedRuleEngineService) ProcessEmailWithRuleGroups(emailData *model.EmailData, mailboxID uint) ([]*EnhancedAlertResult, error) {
-	var results []*EnhancedAlertResult
-
 	// 1. 获取该邮箱的所有激活规则组
 	ruleGroups, err := s.ruleGroupRepo.GetByMailboxID(mailboxID)
 	if err != nil {
@@ -81,9 +98,16 @@ func (s *enhancedRuleEngineService) ProcessEmailWithRuleGroups(emailData *model.
 
 	if len(ruleGroups) == 0 {
 		log.Printf("邮箱 %d 没有配置规则组", mailboxID)
-		return results, nil
+		return nil, nil
 	}
 
+	return s.ProcessEmailWithRuleGroupList(emailData, ruleGroups)
+}
+
+// ProcessEmailWithRuleGroupList 使用指定的规则组处理邮件（如历史回溯时只运行新增的规则组）
+func (s *enhancedRuleEngineService) ProcessEmailWithRuleGroupList(emailData *model.EmailData, ruleGroups []*model.RuleGroup) ([]*EnhancedAlertResult, error) {
+	var results []*EnhancedAlertResult
+
 	// 2. 执行规则组匹配
 	matchResults, err := s.MatchRuleGroups(emailData, ruleGroups)
 	if err != nil {
@@ -102,7 +126,7 @@ func (s *enhancedRuleEngineService) ProcessEmailWithRuleGroups(emailData *model.
 		}
 
 		// 4. 检查是否重复告警（基于MessageID和规则组ID）
-		isDuplicate, err := s.CheckDuplicateByRuleGroup(emailData, matchResult.RuleGroup.ID)
+		isDuplicate, err := s.CheckDuplicateByRuleGroup(emailData, matchResult.RuleGroup)
 		if err != nil {
 			result.Error = fmt.Sprintf("检查重复告警失败: %v", err)
 			results = append(results, result)
@@ -117,7 +141,7 @@ func (s *enhancedRuleEngineService) ProcessEmailWithRuleGroups(emailData *model.
 		}
 
 		// 5. 创建告警记录
-		alert, err := s.CreateAlertFromRuleGroup(emailData, matchResult.RuleGroup)
+		alert, err := s.CreateAlertFromRuleGroup(emailData, matchResult.RuleGroup, matchResult.Vars)
 		if err != nil {
 			result.Error = fmt.Sprintf("创建告警失败: %v", err)
 			results = append(results, result)
@@ -139,86 +163,230 @@ func (s *enhancedRuleEngineService) ProcessEmailWithRuleGroups(emailData *model.
 func (s *enhancedRuleEngineService) MatchRuleGroups(emailData *model.EmailData, ruleGroups []*model.RuleGroup) ([]*RuleGroupMatchResult, error) {
 	var results []*RuleGroupMatchResult
 
+	// 每封邮件只提取一次字段内容，所有规则组共用
+	fields := newEmailFields(s.ExtractEmailFields(emailData))
+
 	for _, ruleGroup := range ruleGroups {
 		if ruleGroup.Status != "active" {
 			continue
 		}
 
-		result := &RuleGroupMatchResult{
-			RuleGroup: ruleGroup,
-			Logic:     ruleGroup.Logic,
-		}
-
-		// 获取规则组的所有激活条件
-		conditions, err := s.conditionRepo.GetByRuleGroupID(ruleGroup.ID)
+		// 获取规则组编译后的激活条件和变量提取规则
+		compiled, err := s.compiledRuleGroup(ruleGroup)
 		if err != nil {
-			result.Matched = false
-			result.Reason = fmt.Sprintf("获取规则组条件失败: %v", err)
-			results = append(results, result)
+			results = append(results, &RuleGroupMatchResult{
+				RuleGroup: ruleGroup,
+				Logic:     ruleGroup.Logic,
+				Reason:    fmt.Sprintf("获取规则组条件失败: %v", err),
+			})
 			continue
 		}
 
-		if len(conditions) == 0 {
-			result.Matched = false
-			result.Reason = "规则组没有配置匹配条件"
-			results = append(results, result)
-			continue
+		results = append(results, s.matchRuleGroup(emailData, fields, ruleGroup, compiled))
+	}
+
+	return results, nil
+}
+
+// MatchRuleGroupWithConditions 使用指定的条件匹配规则组，不从数据库加载条件（如测试未保存的规则组）
+func (s *enhancedRuleEngineService) MatchRuleGroupWithConditions(emailData *model.EmailData, ruleGroup *model.RuleGroup, conditions []*model.MatchCondition) *RuleGroupMatchResult {
+	return s.matchRuleGroup(emailData, newEmailFields(s.ExtractEmailFields(emailData)), ruleGroup, newCompiledRuleGroup(ruleGroup, conditions))
+}
+
+// InvalidateRuleCache 失效规则组的编译缓存，ruleGroupID为0时清空全部，规则组或条件变更后调用
+func (s *enhancedRuleEngineService) InvalidateRuleCache(ruleGroupID uint) {
+	s.cache.invalidate(ruleGroupID)
+}
+
+// compiledRuleGroup 获取规则组编译后的激活条件和变量提取规则，缓存未命中时从数据库加载条件并编译
+func (s *enhancedRuleEngineService) compiledRuleGroup(ruleGroup *model.RuleGroup) (*compiledRuleGroup, error) {
+	cached, generation, ok := s.cache.get(ruleGroup.ID)
+	if ok {
+		return cached, nil
+	}
+
+	conditions, err := s.conditionRepo.GetByRuleGroupID(ruleGroup.ID)
+	if err != nil {
+		return nil, err
+	}
+	compiled := newCompiledRuleGroup(ruleGroup, conditions)
+	s.cache.put(ruleGroup.ID, compiled, generation)
+	return compiled, nil
+}
+
+// matchRuleGroup 使用编译后的条件匹配规则组
+func (s *enhancedRuleEngineService) matchRuleGroup(emailData *model.EmailData, fields *emailFields, ruleGroup *model.RuleGroup, compiled *compiledRuleGroup) *RuleGroupMatchResult {
+	conditions := compiled.conditions
+	result := &RuleGroupMatchResult{
+		RuleGroup: ruleGroup,
+		Logic:     ruleGroup.Logic,
+	}
+
+	if len(conditions) == 0 {
+		result.Matched = false
+		result.Reason = "规则组没有配置匹配条件"
+		return result
+	}
+
+	// 表达式条件可以引用提取的变量，此时在匹配前提取；否则只在匹配成功后提取
+	var vars map[string]string
+	if len(compiled.extractions) > 0 && hasExpressionCondition(conditions) {
+		vars = extractVariables(emailData, fields.values, compiled.extractions)
+	}
+
+	// 执行条件匹配
+	conditionResults, err := s.matchConditions(emailData, fields, vars, conditions)
+	if err != nil {
+		result.Matched = false
+		result.Reason = fmt.Sprintf("条件匹配失败: %v", err)
+		return result
+	}
+
+	s.evaluateRuleGroupLogic(result, ruleGroup, conditionResults)
+
+	if result.Matched && len(compiled.extractions) > 0 {
+		if vars == nil {
+			vars = extractVariables(emailData, fields.values, compiled.extractions)
 		}
+		result.Vars = vars
+	}
 
-		// 执行条件匹配
-		conditionResults, err := s.MatchConditions(emailData, conditions)
-		if err != nil {
-			result.Matched = false
-			result.Reason = fmt.Sprintf("条件匹配失败: %v", err)
-			results = append(results, result)
-			continue
+	return result
+}
+
+// evaluateRuleGroupLogic 根据条件匹配结果和规则组逻辑（或条件树）计算规则组是否匹配
+func (s *enhancedRuleEngineService) evaluateRuleGroupLogic(result *RuleGroupMatchResult, ruleGroup *model.RuleGroup, conditionResults []*ConditionMatchResult) {
+
+	result.ConditionResults = conditionResults
+	result.TotalCount = len(conditionResults)
+
+	// 统计匹配成功的条件数量
+	matchedCount := 0
+	for _, condResult := range conditionResults {
+		if condResult.Matched {
+			matchedCount++
+		}
+	}
+	result.MatchedCount = matchedCount
+
+	// 配置了条件树时按条件树计算，忽略规则组的扁平逻辑
+	if ruleGroup.ConditionTree != nil {
+		result.Logic = "tree"
+		result.Tree = evaluateConditionTree(ruleGroup.ConditionTree, conditionResults)
+		result.Matched = result.Tree.Matched
+		if result.Tree.Skipped {
+			result.Reason = "条件树中没有启用的条件"
+		} else {
+			result.Reason = "条件树" + result.Tree.Reason
+		}
+		return
+	}
+
+	// 根据规则组逻辑判断是否匹配
+	if ruleGroup.Logic == "and" {
+		result.Matched = matchedCount == result.TotalCount
+		if result.Matched {
+			result.Reason = fmt.Sprintf("所有 %d 个条件都匹配成功 (AND逻辑)", result.TotalCount)
+		} else {
+			result.Reason = fmt.Sprintf("只有 %d/%d 个条件匹配成功，AND逻辑要求全部匹配", matchedCount, result.TotalCount)
+		}
+	} else { // or逻辑
+		result.Matched = matchedCount > 0
+		if result.Matched {
+			result.Reason = fmt.Sprintf("%d/%d 个条件匹配成功 (OR逻辑)", matchedCount, result.TotalCount)
+		} else {
+			result.Reason = "没有任何条件匹配成功"
+		}
+	}
+}
+
+// hasExpressionCondition 是否包含表达式条件
+func hasExpressionCondition(conditions []*compiledCondition) bool {
+	for _, compiled := range conditions {
+		if compiled.condition.MatchType == "expression" {
+			return true
 		}
+	}
+	return false
+}
 
-		result.ConditionResults = conditionResults
-		result.TotalCount = len(conditionResults)
+// evaluateConditionTree 按条件树计算匹配结果，记录每个节点的匹配原因
+// 已停用或不存在的条件视为跳过：AND/OR忽略被跳过的子节点，全部子节点被跳过时该节点也被跳过
+func evaluateConditionTree(node *model.ConditionNode, conditionResults []*ConditionMatchResult) *ConditionNodeResult {
+	result := &ConditionNodeResult{Op: node.Op, ConditionID: node.ConditionID}
 
-		// 统计匹配成功的条件数量
-		matchedCount := 0
+	if node.IsLeaf() {
 		for _, condResult := range conditionResults {
-			if condResult.Matched {
-				matchedCount++
+			if condResult.Condition.ID == node.ConditionID {
+				result.Matched = condResult.Matched
+				result.Reason = fmt.Sprintf("条件 %d (%s %s): %s", node.ConditionID,
+					condResult.Condition.FieldType, condResult.Condition.MatchType, condResult.Reason)
+				return result
 			}
 		}
-		result.MatchedCount = matchedCount
+		result.Skipped = true
+		result.Reason = fmt.Sprintf("条件 %d 已停用或不存在，跳过", node.ConditionID)
+		return result
+	}
 
-		// 根据规则组逻辑判断是否匹配
-		if ruleGroup.Logic == "and" {
-			result.Matched = matchedCount == result.TotalCount
-			if result.Matched {
-				result.Reason = fmt.Sprintf("所有 %d 个条件都匹配成功 (AND逻辑)", result.TotalCount)
-			} else {
-				result.Reason = fmt.Sprintf("只有 %d/%d 个条件匹配成功，AND逻辑要求全部匹配", matchedCount, result.TotalCount)
-			}
-		} else { // or逻辑
-			result.Matched = matchedCount > 0
-			if result.Matched {
-				result.Reason = fmt.Sprintf("%d/%d 个条件匹配成功 (OR逻辑)", matchedCount, result.TotalCount)
-			} else {
-				result.Reason = "没有任何条件匹配成功"
-			}
+	matchedCount, activeCount := 0, 0
+	for _, child := range node.Children {
+		childResult := evaluateConditionTree(child, conditionResults)
+		result.Children = append(result.Children, childResult)
+		if childResult.Skipped {
+			continue
 		}
+		activeCount++
+		if childResult.Matched {
+			matchedCount++
+		}
+	}
 
-		results = append(results, result)
+	if activeCount == 0 {
+		result.Skipped = true
+		result.Reason = fmt.Sprintf("%s节点没有启用的子条件，跳过", strings.ToUpper(node.Op))
+		return result
 	}
 
-	return results, nil
+	switch node.Op {
+	case model.ConditionOpNot:
+		result.Matched = matchedCount == 0
+		if result.Matched {
+			result.Reason = "子条件不匹配，NOT取反后匹配成功"
+		} else {
+			result.Reason = "子条件匹配，NOT取反后不匹配"
+		}
+	case model.ConditionOpOr:
+		result.Matched = matchedCount > 0
+		if result.Matched {
+			result.Reason = fmt.Sprintf("%d/%d 个子条件匹配成功 (OR逻辑)", matchedCount, activeCount)
+		} else {
+			result.Reason = fmt.Sprintf("%d 个子条件都没有匹配 (OR逻辑)", activeCount)
+		}
+	default:
+		result.Matched = matchedCount == activeCount
+		if result.Matched {
+			result.Reason = fmt.Sprintf("所有 %d 个子条件都匹配成功 (AND逻辑)", activeCount)
+		} else {
+			result.Reason = fmt.Sprintf("只有 %d/%d 个子条件匹配成功，AND逻辑要求全部匹配", matchedCount, activeCount)
+		}
+	}
+	return result
 }
 
 // MatchConditions 执行条件匹配
 func (s *enhancedRuleEngineService) MatchConditions(emailData *model.EmailData, conditions []*model.MatchCondition) ([]*ConditionMatchResult, error) {
+	return s.matchConditions(emailData, newEmailFields(s.ExtractEmailFields(emailData)), nil, compileConditions(conditions))
+}
+
+// matchConditions 使用编译后的条件执行匹配，vars为表达式条件可以引用的提取变量
+func (s *enhancedRuleEngineService) matchConditions(emailData *model.EmailData, fields *emailFields, vars map[string]string, conditions []*compiledCondition) ([]*ConditionMatchResult, error) {
 	var results []*ConditionMatchResult
 
-	for _, condition := range conditions {
-		if condition.Status != "active" {
-			continue
-		}
+	for _, compiled := range conditions {
+		condition := compiled.condition
 
-		matched, reason, err := s.MatchSingleCondition(emailData, condition)
+		matched, reason, err := s.matchCompiledCondition(emailData, fields, vars, compiled)
 		if err != nil {
 			return nil, fmt.Errorf("匹配条件 %d 失败: %v", condition.ID, err)
 		}
@@ -230,8 +398,7 @@ func (s *enhancedRuleEngineService) MatchConditions(emailData *model.EmailData,
 		}
 
 		// 提取字段内容用于调试
-		emailFields := s.ExtractEmailFields(emailData)
-		if fieldContent, exists := emailFields[condition.FieldType]; exists {
+		if fieldContent, exists := conditionFieldContent(emailData, fields.values, condition); exists {
 			result.FieldContent = fieldContent
 		}
 
@@ -243,46 +410,63 @@ func (s *enhancedRuleEngineService) MatchConditions(emailData *model.EmailData,
 
 // MatchSingleCondition 匹配单个条件
 func (s *enhancedRuleEngineService) MatchSingleCondition(emailData *model.EmailData, condition *model.MatchCondition) (bool, string, error) {
-	// 提取邮件字段内容
-	emailFields := s.ExtractEmailFields(emailData)
+	return s.matchCompiledCondition(emailData, newEmailFields(s.ExtractEmailFields(emailData)), nil, compileCondition(condition))
+}
+
+// matchCompiledCondition 匹配单个编译后的条件
+func (s *enhancedRuleEngineService) matchCompiledCondition(emailData *model.EmailData, fields *emailFields, vars map[string]string, compiled *compiledCondition) (bool, string, error) {
+	condition := compiled.condition
+
+	// 表达式条件的关键词为完整的表达式，不按字段和逗号拆分
+	if condition.MatchType == "expression" {
+		if compiled.err != nil {
+			return false, compiled.err.Error(), compiled.err
+		}
+		matched, err := EvaluateExpression(condition.Keywords, emailData, fields.values, vars)
+		if err != nil {
+			return false, err.Error(), err
+		}
+		if matched {
+			return true, "表达式结果为true", nil
+		}
+		return false, "表达式结果为false", nil
+	}
+
+	// 附件大小按数值比较，关键词为大小表达式
+	if condition.FieldType == "attachment_size" {
+		return s.matchAttachmentSize(emailData, condition)
+	}
 
 	// 获取要匹配的字段内容
-	fieldContent, exists := emailFields[condition.FieldType]
+	fieldContent, exists := conditionFieldContent(emailData, fields.values, condition)
 	if !exists {
 		return false, fmt.Sprintf("不支持的字段类型: %s", condition.FieldType), nil
 	}
 
-	// 解析关键词列表
-	keywords := strings.Split(condition.Keywords, ",")
-	var trimmedKeywords []string
-	for _, keyword := range keywords {
-		if trimmed := strings.TrimSpace(keyword); trimmed != "" {
-			trimmedKeywords = append(trimmedKeywords, trimmed)
-		}
+	if len(compiled.keywords) == 0 {
+		return false, "没有有效的关键词", nil
+	}
+	if compiled.err != nil {
+		return false, compiled.err.Error(), compiled.err
 	}
 
-	if len(trimmedKeywords) == 0 {
-		return false, "没有有效的关键词", nil
+	// 数值比较类型从字段内容中提取数值与阈值比较，不按关键词逻辑组合
+	if compiled.numeric != nil {
+		matched, reason := compiled.numeric.match(fieldContent)
+		return matched, reason, nil
 	}
 
 	// 执行关键词匹配
-	var matchedKeywords []string
-	for _, keyword := range trimmedKeywords {
-		matched, err := s.MatchKeywordWithType(fieldContent, keyword, condition.MatchType)
-		if err != nil {
-			return false, fmt.Sprintf("匹配关键词 '%s' 失败: %v", keyword, err), err
-		}
-		if matched {
-			matchedKeywords = append(matchedKeywords, keyword)
-		}
-	}
+	matchedKeywords := compiled.matchKeywords(fieldContent, func() string {
+		return fields.lowerOf(fieldKey(condition), fieldContent)
+	})
 
 	// 根据关键词逻辑判断最终结果
 	keywordMatched := false
 	var reason string
 
 	if condition.KeywordLogic == "and" {
-		keywordMatched = len(matchedKeywords) == len(trimmedKeywords)
+		keywordMatched = len(matchedKeywords) == len(compiled.keywords)
 		if keywordMatched {
 			reason = fmt.Sprintf("所有关键词都匹配成功: [%s]", strings.Join(matchedKeywords, ", "))
 		} else {
@@ -300,39 +484,116 @@ func (s *enhancedRuleEngineService) MatchSingleCondition(emailData *model.EmailD
 	return keywordMatched, reason, nil
 }
 
-// MatchKeywordWithType 根据匹配类型执行关键词匹配
-func (s *enhancedRuleEngineService) MatchKeywordWithType(content, keyword, matchType string) (bool, error) {
-	switch matchType {
-	case "equals":
-		// 完全匹配
-		return content == keyword, nil
-
-	case "contains":
-		// 包含匹配（不区分大小写）
-		return strings.Contains(strings.ToLower(content), strings.ToLower(keyword)), nil
+// conditionFieldContent 获取条件要匹配的字段内容，fields为ExtractEmailFields的结果
+// header字段取指定邮件头的值（多个值以逗号连接），邮件不含该头时内容为空
+func conditionFieldContent(emailData *model.EmailData, fields map[string]string, condition *model.MatchCondition) (string, bool) {
+	if condition.FieldType == "header" {
+		if condition.HeaderName == "" {
+			return "", false
+		}
+		values := emailData.Headers[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(condition.HeaderName))]
+		return strings.Join(values, ", "), true
+	}
 
-	case "startsWith":
-		// 前缀匹配（不区分大小写）
-		return strings.HasPrefix(strings.ToLower(content), strings.ToLower(keyword)), nil
+	fieldContent, exists := fields[condition.FieldType]
+	return fieldContent, exists
+}
 
-	case "endsWith":
-		// 后缀匹配（不区分大小写）
-		return strings.HasSuffix(strings.ToLower(content), strings.ToLower(keyword)), nil
+// matchAttachmentSize 按附件大小匹配，关键词为大小表达式，如 ">10MB"、"<=500KB"
+// AND逻辑要求同一个附件满足全部表达式，OR逻辑只要任一附件满足任一表达式
+func (s *enhancedRuleEngineService) matchAttachmentSize(emailData *model.EmailData, condition *model.MatchCondition) (bool, string, error) {
+	var expressions []string
+	for _, keyword := range strings.Split(condition.Keywords, ",") {
+		if trimmed := strings.TrimSpace(keyword); trimmed != "" {
+			expressions = append(expressions, trimmed)
+		}
+	}
+	if len(expressions) == 0 {
+		return false, "没有有效的关键词", nil
+	}
 
-	case "regex":
-		// 正则表达式匹配
-		regex, err := regexp.Compile(keyword)
+	type sizeExpression struct {
+		op    string
+		bytes int64
+	}
+	parsed := make([]sizeExpression, 0, len(expressions))
+	for _, expr := range expressions {
+		op, size, err := parseSizeExpression(expr)
 		if err != nil {
-			return false, fmt.Errorf("正则表达式编译失败: %v", err)
+			return false, fmt.Sprintf("大小表达式 '%s' 无效: %v", expr, err), err
 		}
-		return regex.MatchString(content), nil
+		parsed = append(parsed, sizeExpression{op: op, bytes: size})
+	}
+
+	if len(emailData.Attachments) == 0 {
+		return false, "邮件没有附件", nil
+	}
 
-	case "notContains":
-		// 不包含匹配（不区分大小写）
-		return !strings.Contains(strings.ToLower(content), strings.ToLower(keyword)), nil
+	for _, att := range emailData.Attachments {
+		matchedCount := 0
+		for _, expr := range parsed {
+			if compareSize(att.Size, expr.op, expr.bytes) {
+				matchedCount++
+			}
+		}
+
+		if condition.KeywordLogic == "and" && matchedCount == len(parsed) {
+			return true, fmt.Sprintf("附件 %s (%d字节) 满足所有大小条件: [%s]", att.Name, att.Size, strings.Join(expressions, ", ")), nil
+		}
+		if condition.KeywordLogic != "and" && matchedCount > 0 {
+			return true, fmt.Sprintf("附件 %s (%d字节) 满足大小条件", att.Name, att.Size), nil
+		}
+	}
+
+	return false, fmt.Sprintf("没有附件满足大小条件: [%s]", strings.Join(expressions, ", ")), nil
+}
+
+// parseSizeExpression 解析大小表达式，支持 > >= < <= = 和 B/KB/MB/GB 单位，省略运算符时为 >=
+func parseSizeExpression(expr string) (string, int64, error) {
+	op := ">="
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(expr, candidate) {
+			op = candidate
+			expr = strings.TrimSpace(strings.TrimPrefix(expr, candidate))
+			break
+		}
+	}
 
+	upper := strings.ToUpper(expr)
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix     string
+		multiplier int64
+	}{
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(upper, unit.suffix) {
+			multiplier = unit.multiplier
+			upper = strings.TrimSpace(strings.TrimSuffix(upper, unit.suffix))
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(upper, 64)
+	if err != nil || value < 0 {
+		return "", 0, fmt.Errorf("无法解析大小: %s", expr)
+	}
+	return op, int64(value * float64(multiplier)), nil
+}
+
+// compareSize 按运算符比较大小
+func compareSize(size int64, op string, threshold int64) bool {
+	switch op {
+	case ">":
+		return size > threshold
+	case ">=":
+		return size >= threshold
+	case "<":
+		return size < threshold
+	case "<=":
+		return size <= threshold
 	default:
-		return false, fmt.Errorf("不支持的匹配类型: %s", matchType)
+		return size == threshold
 	}
 }
 
@@ -342,6 +603,7 @@ func (s *enhancedRuleEngineService) ExtractEmailFields(emailData *model.EmailDat
 		"subject": emailData.Subject,
 		"from":    emailData.Sender,
 		"body":    emailData.Content,
+		"folder":  emailData.Folder,
 	}
 
 	// 处理多值字段
@@ -353,21 +615,47 @@ func (s *enhancedRuleEngineService) ExtractEmailFields(emailData *model.EmailDat
 		fields["cc"] = strings.Join(emailData.CC, ", ")
 	}
 
+	if len(emailData.BCC) > 0 {
+		fields["bcc"] = strings.Join(emailData.BCC, ", ")
+	}
+
+	if len(emailData.ReplyTo) > 0 {
+		fields["reply_to"] = strings.Join(emailData.ReplyTo, ", ")
+	}
+
 	if len(emailData.AttachmentNames) > 0 {
 		fields["attachment_name"] = strings.Join(emailData.AttachmentNames, ", ")
 	}
 
+	if len(emailData.Attachments) > 0 {
+		var types, sizes, contents []string
+		for _, att := range emailData.Attachments {
+			types = append(types, att.MimeType)
+			sizes = append(sizes, strconv.FormatInt(att.Size, 10))
+			if att.TextContent != "" {
+				contents = append(contents, att.TextContent)
+			}
+		}
+		fields["attachment_type"] = strings.Join(types, ", ")
+		fields["attachment_size"] = strings.Join(sizes, ", ")
+		fields["attachment_content"] = strings.Join(contents, "\n")
+	}
+
 	return fields
 }
 
 // CheckDuplicateByRuleGroup 检查规则组重复告警
-func (s *enhancedRuleEngineService) CheckDuplicateByRuleGroup(emailData *model.EmailData, ruleGroupID uint) (bool, error) {
+func (s *enhancedRuleEngineService) CheckDuplicateByRuleGroup(emailData *model.EmailData, ruleGroup *model.RuleGroup) (bool, error) {
 	// 基于MessageID检查是否已存在告警（可以扩展为基于规则组的更精确检查）
+	// 没有MessageID的POP3邮件使用完整UIDL检查
+	if emailData.MessageID == "" && emailData.UIDL != "" {
+		return s.alertRepo.ExistsByUIDL(ruleGroup.MailboxID, emailData.UIDL)
+	}
 	return s.alertRepo.ExistsByMessageID(emailData.MessageID)
 }
 
-// CreateAlertFromRuleGroup 从规则组创建告警
-func (s *enhancedRuleEngineService) CreateAlertFromRuleGroup(emailData *model.EmailData, ruleGroup *model.RuleGroup) (*model.Alert, error) {
+// CreateAlertFromRuleGroup 从规则组创建告警，vars为规则组提取的变量，保存为告警标签
+func (s *enhancedRuleEngineService) CreateAlertFromRuleGroup(emailData *model.EmailData, ruleGroup *model.RuleGroup, vars map[string]string) (*model.Alert, error) {
 	alert := &model.Alert{
 		MailboxID:    ruleGroup.MailboxID,
 		RuleID:       0,            // 旧架构字段，保持为0
@@ -376,6 +664,7 @@ func (s *enhancedRuleEngineService) CreateAlertFromRuleGroup(emailData *model.Em
 		Sender:       emailData.Sender,
 		Content:      emailData.Content,
 		MessageID:    emailData.MessageID,
+		UIDL:         emailData.UIDL,
 		ReceivedAt:   emailData.ReceivedAt,
 		Status:       "pending",
 		SentChannels: "",
@@ -383,6 +672,16 @@ func (s *enhancedRuleEngineService) CreateAlertFromRuleGroup(emailData *model.Em
 		RetryCount:   0,
 	}
 
+	// 按变量名排序，保证标签顺序稳定
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		alert.Labels = append(alert.Labels, model.AlertLabel{Name: name, Value: vars[name]})
+	}
+
 	err := s.alertRepo.Create(alert)
 	if err != nil {
 		return nil, err
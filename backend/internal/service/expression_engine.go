@@ -0,0 +1,180 @@
+package service
+
+import (
+	"emailAlert/internal/model"
+	"emailAlert/pkg/timezone"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/vm"
+)
+
+// maxExpressionNodes 表达式语法树的最大节点数，避免过于复杂的表达式拖慢匹配
+const maxExpressionNodes = 1000
+
+// maxCachedExpressions 编译结果缓存的最大条数，超过后清空重建
+const maxCachedExpressions = 1024
+
+var (
+	expressionMutex sync.RWMutex
+	expressionCache = make(map[string]*vm.Program)
+)
+
+// expressionEnv 表达式的求值环境
+// 表达式只能读取邮件内容，不能访问文件、网络或修改任何数据
+type expressionEnv struct {
+	Email  expressionEmail     `expr:"email"`  // 邮件内容
+	Fields map[string]string   `expr:"fields"` // 规则引擎提取的字段内容，与条件的field_type一一对应
+	Vars   map[string]string   `expr:"vars"`   // 规则组提取规则提取的变量
+	Header func(string) string `expr:"header"` // 按名称获取邮件头（不区分大小写），多个值以逗号连接
+}
+
+// expressionEmail 表达式中可访问的邮件字段，对应model.EmailData
+type expressionEmail struct {
+	UID             int                    `expr:"uid"`
+	Subject         string                 `expr:"subject"`
+	From            string                 `expr:"from"`
+	FromName        string                 `expr:"from_name"`
+	Sender          string                 `expr:"sender"`
+	To              []string               `expr:"to"`
+	CC              []string               `expr:"cc"`
+	BCC             []string               `expr:"bcc"`
+	ReplyTo         []string               `expr:"reply_to"`
+	Body            string                 `expr:"body"`
+	HTMLBody        string                 `expr:"html_body"`
+	Folder          string                 `expr:"folder"`
+	AttachmentNames []string               `expr:"attachment_names"`
+	Attachments     []expressionAttachment `expr:"attachments"`
+	Headers         map[string][]string    `expr:"headers"`
+	ReceivedAt      time.Time              `expr:"received_at"`
+	MessageID       string                 `expr:"message_id"`
+	Size            uint64                 `expr:"size"`
+	Flags           []string               `expr:"flags"`
+}
+
+// expressionAttachment 表达式中可访问的附件字段
+type expressionAttachment struct {
+	Name        string `expr:"name"`
+	MimeType    string `expr:"mime_type"`
+	Size        int64  `expr:"size"`
+	SHA256      string `expr:"sha256"`
+	TextContent string `expr:"text_content"`
+}
+
+// methodNamePatcher 允许以小写形式调用方法，如 email.received_at.hour() 对应 time.Time.Hour()
+type methodNamePatcher struct{}
+
+// Visit 将方法调用的名称首字母转为大写
+func (methodNamePatcher) Visit(node *ast.Node) {
+	member, ok := (*node).(*ast.MemberNode)
+	if !ok || !member.Method {
+		return
+	}
+	if name, ok := member.Property.(*ast.StringNode); ok && name.Value != "" {
+		runes := []rune(name.Value)
+		runes[0] = unicode.ToUpper(runes[0])
+		name.Value = string(runes)
+	}
+}
+
+// CompileExpression 编译表达式并缓存，同一表达式只编译一次
+func CompileExpression(expression string) (*vm.Program, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return nil, errors.New("表达式不能为空")
+	}
+
+	expressionMutex.RLock()
+	program, ok := expressionCache[expression]
+	expressionMutex.RUnlock()
+	if ok {
+		return program, nil
+	}
+
+	program, err := expr.Compile(expression,
+		expr.Env(expressionEnv{}),
+		expr.AsBool(),
+		expr.MaxNodes(maxExpressionNodes),
+		expr.Patch(methodNamePatcher{}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("表达式编译失败: %v", err)
+	}
+
+	expressionMutex.Lock()
+	if len(expressionCache) >= maxCachedExpressions {
+		expressionCache = make(map[string]*vm.Program)
+	}
+	expressionCache[expression] = program
+	expressionMutex.Unlock()
+
+	return program, nil
+}
+
+// newExpressionEnv 根据邮件构建表达式的求值环境，时间按系统时区展示
+func newExpressionEnv(emailData *model.EmailData, fields, vars map[string]string) expressionEnv {
+	attachments := make([]expressionAttachment, 0, len(emailData.Attachments))
+	for _, att := range emailData.Attachments {
+		attachments = append(attachments, expressionAttachment{
+			Name:        att.Name,
+			MimeType:    att.MimeType,
+			Size:        att.Size,
+			SHA256:      att.SHA256,
+			TextContent: att.TextContent,
+		})
+	}
+
+	return expressionEnv{
+		Email: expressionEmail{
+			UID:             emailData.UID,
+			Subject:         emailData.Subject,
+			From:            emailData.Sender,
+			FromName:        emailData.FromName,
+			Sender:          emailData.SenderHeader,
+			To:              emailData.To,
+			CC:              emailData.CC,
+			BCC:             emailData.BCC,
+			ReplyTo:         emailData.ReplyTo,
+			Body:            emailData.Content,
+			HTMLBody:        emailData.HTMLContent,
+			Folder:          emailData.Folder,
+			AttachmentNames: emailData.AttachmentNames,
+			Attachments:     attachments,
+			Headers:         emailData.Headers,
+			ReceivedAt:      emailData.ReceivedAt.In(timezone.System()),
+			MessageID:       emailData.MessageID,
+			Size:            emailData.Size,
+			Flags:           emailData.Flags,
+		},
+		Fields: fields,
+		Vars:   vars,
+		Header: func(name string) string {
+			return strings.Join(emailData.Headers[textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))], ", ")
+		},
+	}
+}
+
+// EvaluateExpression 对邮件执行表达式，返回表达式的布尔结果
+func EvaluateExpression(expression string, emailData *model.EmailData, fields, vars map[string]string) (bool, error) {
+	program, err := CompileExpression(expression)
+	if err != nil {
+		return false, err
+	}
+
+	output, err := expr.Run(program, newExpressionEnv(emailData, fields, vars))
+	if err != nil {
+		return false, fmt.Errorf("表达式执行失败: %v", err)
+	}
+	matched, ok := output.(bool)
+	if !ok {
+		return false, fmt.Errorf("表达式结果不是布尔值: %v", output)
+	}
+	return matched, nil
+}
@@ -4,13 +4,25 @@ import (
 	"emailAlert/internal/model"
 	"emailAlert/internal/repository"
 	"emailAlert/pkg/email"
+	"emailAlert/pkg/oauth"
+	"emailAlert/pkg/proxy"
 	"errors"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 )
 
+// MailboxWatcher 邮箱配置变化的监听者（邮件监控服务），用于运行中增删监控协程
+type MailboxWatcher interface {
+	AddMailbox(mailbox model.Mailbox) error
+	RemoveMailbox(mailboxID uint)
+}
+
 // MailboxService 邮箱配置服务层
 type MailboxService struct {
 	mailboxRepo *repository.MailboxRepository
+	watcher     MailboxWatcher
 }
 
 // NewMailboxService 创建邮箱配置服务实例
@@ -18,17 +30,96 @@ func NewMailboxService(mailboxRepo *repository.MailboxRepository) *MailboxServic
 	return &MailboxService{mailboxRepo: mailboxRepo}
 }
 
+// SetMailboxWatcher 设置邮箱配置变化的监听者
+func (s *MailboxService) SetMailboxWatcher(watcher MailboxWatcher) {
+	s.watcher = watcher
+}
+
+// notifyWatcher 通知监听者邮箱配置已变化，启动、重启或停止该邮箱的监控协程
+func (s *MailboxService) notifyWatcher(id uint) {
+	if s.watcher == nil {
+		return
+	}
+
+	mailbox, err := s.mailboxRepo.GetByID(id)
+	if err != nil {
+		// 配置已删除
+		s.watcher.RemoveMailbox(id)
+		return
+	}
+	if err := s.watcher.AddMailbox(*mailbox); err != nil {
+		log.Printf("邮箱 %s: 更新监控失败: %v", mailbox.Name, err)
+	}
+}
+
 // CreateMailboxRequest 创建邮箱配置请求结构
 type CreateMailboxRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Email       string `json:"email" binding:"required,email"`
-	Host        string `json:"host" binding:"required"`
-	Port        int    `json:"port" binding:"required,min=1,max=65535"`
-	Username    string `json:"username" binding:"required"`
-	Password    string `json:"password" binding:"required"`
-	Protocol    string `json:"protocol" binding:"required,oneof=IMAP POP3"`
+	Host        string `json:"host"`                                             // IMAP/POP3必填，SMTP推送邮箱无需填写
+	Port        int    `json:"port" binding:"omitempty,min=1,max=65535"`         // IMAP/POP3必填
+	Username    string `json:"username"`                                         // IMAP/POP3必填
+	Password    string `json:"password"`                                         // 认证方式为password时必填
+	Protocol    string `json:"protocol" binding:"required,oneof=IMAP POP3 SMTP"` // SMTP表示由内置接收服务推送
 	SSL         bool   `json:"ssl"`
 	Description string `json:"description"`
+
+	Folders          string `json:"folders"`            // 监控的文件夹（多个用逗号分隔，为空时监控INBOX）
+	DeleteAfterFetch bool   `json:"delete_after_fetch"` // POP3：处理后是否删除服务器上的邮件
+	IdleMode         bool   `json:"idle_mode"`          // IMAP：是否使用IDLE推送模式
+
+	// 监控策略，零值表示使用全局默认值
+	CheckInterval int    `json:"check_interval" binding:"omitempty,min=5,max=86400"` // 检查间隔（秒）
+	MaxRetries    int    `json:"max_retries" binding:"omitempty,min=0,max=10"`       // 检查失败最大重试次数
+	RetryInterval int    `json:"retry_interval" binding:"omitempty,min=0,max=600"`   // 重试基础间隔（秒）
+	OnlyUnread    *bool  `json:"only_unread"`                                        // 是否只处理未读邮件，为空时使用全局配置
+	MarkAsRead    *bool  `json:"mark_as_read"`                                       // 处理后是否标记为已读，为空时使用全局配置
+	Schedule      string `json:"schedule"`                                           // 监控时间段，如 "08:00-20:00"
+
+	// 处理后动作，多个用逗号分隔：seen、flag:<关键字>、move:<文件夹>、delete
+	MatchedActions   string `json:"matched_actions"`   // 匹配规则后执行的动作
+	UnmatchedActions string `json:"unmatched_actions"` // 未匹配规则时执行的动作
+
+	// OAuth2认证（Microsoft 365、Google Workspace等已停用基本认证的邮箱）
+	AuthType          string `json:"auth_type" binding:"omitempty,oneof=password oauth2"` // 认证方式，默认password
+	OAuthClientID     string `json:"oauth_client_id"`                                     // OAuth2客户端ID
+	OAuthClientSecret string `json:"oauth_client_secret"`                                 // OAuth2客户端密钥
+	OAuthRefreshToken string `json:"oauth_refresh_token"`                                 // OAuth2 refresh token
+	OAuthTokenURL     string `json:"oauth_token_url"`                                     // OAuth2令牌端点
+	OAuthScope        string `json:"oauth_scope"`                                         // OAuth2授权范围，多个用空格分隔
+
+	// TLS配置（STARTTLS、私有CA、客户端证书等），为空时按SSL字段使用默认设置
+	TLSMode               string `json:"tls_mode" binding:"omitempty,oneof=none implicit starttls"` // TLS模式
+	TLSCACert             string `json:"tls_ca_cert"`                                               // 自定义CA证书（PEM内容或文件路径）
+	TLSClientCert         string `json:"tls_client_cert"`                                           // 客户端证书（PEM内容或文件路径）
+	TLSClientKey          string `json:"tls_client_key"`                                            // 客户端私钥（PEM内容或文件路径）
+	TLSInsecureSkipVerify bool   `json:"tls_insecure_skip_verify"`                                  // 跳过服务器证书校验，仅用于测试环境
+	TLSMinVersion         string `json:"tls_min_version" binding:"omitempty,oneof=1.0 1.1 1.2 1.3"` // 最低TLS版本
+
+	Proxy string `json:"proxy"` // 代理地址（socks5://或http://），为空时使用全局代理，direct表示直连
+}
+
+// oauthConfig 提取请求中的OAuth2凭据
+func (req *CreateMailboxRequest) oauthConfig() oauth.Config {
+	return oauth.Config{
+		ClientID:     strings.TrimSpace(req.OAuthClientID),
+		ClientSecret: req.OAuthClientSecret,
+		RefreshToken: strings.TrimSpace(req.OAuthRefreshToken),
+		TokenURL:     strings.TrimSpace(req.OAuthTokenURL),
+		Scope:        strings.TrimSpace(req.OAuthScope),
+	}
+}
+
+// tlsOptions 提取请求中的TLS配置
+func (req *CreateMailboxRequest) tlsOptions() email.TLSOptions {
+	return email.TLSOptions{
+		Mode:               strings.TrimSpace(req.TLSMode),
+		CACert:             strings.TrimSpace(req.TLSCACert),
+		ClientCert:         strings.TrimSpace(req.TLSClientCert),
+		ClientKey:          strings.TrimSpace(req.TLSClientKey),
+		InsecureSkipVerify: req.TLSInsecureSkipVerify,
+		MinVersion:         strings.TrimSpace(req.TLSMinVersion),
+	}
 }
 
 // UpdateMailboxRequest 更新邮箱配置请求结构
@@ -39,10 +130,39 @@ type UpdateMailboxRequest struct {
 	Port        int    `json:"port" binding:"omitempty,min=1,max=65535"`
 	Username    string `json:"username"`
 	Password    string `json:"password"` // 为空时不更新密码
-	Protocol    string `json:"protocol" binding:"omitempty,oneof=IMAP POP3"`
-	SSL         bool   `json:"ssl"`
+	Protocol    string `json:"protocol" binding:"omitempty,oneof=IMAP POP3 SMTP"`
 	Status      string `json:"status" binding:"omitempty,oneof=active inactive"`
 	Description string `json:"description"`
+
+	// 以下字段为空时不更新
+	SSL              *bool   `json:"ssl"`                                                // 是否使用SSL
+	Folders          *string `json:"folders"`                                            // 监控的文件夹（多个用逗号分隔，空字符串表示监控INBOX）
+	DeleteAfterFetch *bool   `json:"delete_after_fetch"`                                 // POP3：处理后是否删除服务器上的邮件
+	IdleMode         *bool   `json:"idle_mode"`                                          // IMAP：是否使用IDLE推送模式
+	CheckInterval    *int    `json:"check_interval" binding:"omitempty,min=0,max=86400"` // 检查间隔（秒），0表示使用全局默认值
+	MaxRetries       *int    `json:"max_retries" binding:"omitempty,min=0,max=10"`       // 检查失败最大重试次数
+	RetryInterval    *int    `json:"retry_interval" binding:"omitempty,min=0,max=600"`   // 重试基础间隔（秒）
+	OnlyUnread       *bool   `json:"only_unread"`                                        // 是否只处理未读邮件
+	MarkAsRead       *bool   `json:"mark_as_read"`                                       // 处理后是否标记为已读
+	Schedule         *string `json:"schedule"`                                           // 监控时间段，空字符串表示全天
+	MatchedActions   *string `json:"matched_actions"`                                    // 匹配规则后执行的动作，空字符串表示不执行
+	UnmatchedActions *string `json:"unmatched_actions"`                                  // 未匹配规则时执行的动作，空字符串表示不执行
+
+	AuthType          *string `json:"auth_type" binding:"omitempty,oneof=password oauth2"` // 认证方式
+	OAuthClientID     *string `json:"oauth_client_id"`                                     // OAuth2客户端ID
+	OAuthClientSecret string  `json:"oauth_client_secret"`                                 // OAuth2客户端密钥，为空时不更新
+	OAuthRefreshToken string  `json:"oauth_refresh_token"`                                 // OAuth2 refresh token，为空时不更新
+	OAuthTokenURL     *string `json:"oauth_token_url"`                                     // OAuth2令牌端点
+	OAuthScope        *string `json:"oauth_scope"`                                         // OAuth2授权范围
+
+	TLSMode               *string `json:"tls_mode" binding:"omitempty,oneof=none implicit starttls"` // TLS模式，空字符串表示按SSL字段决定
+	TLSCACert             *string `json:"tls_ca_cert"`                                               // 自定义CA证书，空字符串表示使用系统CA
+	TLSClientCert         *string `json:"tls_client_cert"`                                           // 客户端证书，空字符串表示不使用客户端证书
+	TLSClientKey          string  `json:"tls_client_key"`                                            // 客户端私钥，为空时不更新
+	TLSInsecureSkipVerify *bool   `json:"tls_insecure_skip_verify"`                                  // 跳过服务器证书校验
+	TLSMinVersion         *string `json:"tls_min_version" binding:"omitempty,oneof=1.0 1.1 1.2 1.3"` // 最低TLS版本
+
+	Proxy *string `json:"proxy"` // 代理地址，空字符串表示使用全局代理，direct表示直连
 }
 
 // MailboxListResponse 邮箱列表响应结构
@@ -64,6 +184,18 @@ func (s *MailboxService) Create(req *CreateMailboxRequest) (*model.Mailbox, erro
 		return nil, errors.New("邮箱地址已存在")
 	}
 
+	if err := email.ValidateSchedule(req.Schedule); err != nil {
+		return nil, fmt.Errorf("监控时间段配置无效: %v", err)
+	}
+	if err := validateMailboxActions(req.MatchedActions, req.UnmatchedActions); err != nil {
+		return nil, err
+	}
+
+	authType := req.AuthType
+	if authType == "" {
+		authType = oauth.AuthTypePassword
+	}
+
 	// 直接使用明文密码
 
 	// 创建邮箱配置模型
@@ -78,6 +210,47 @@ func (s *MailboxService) Create(req *CreateMailboxRequest) (*model.Mailbox, erro
 		SSL:         req.SSL,
 		Status:      "active",
 		Description: req.Description,
+
+		Folders:          normalizeFolders(req.Folders),
+		DeleteAfterFetch: req.DeleteAfterFetch,
+		IdleMode:         req.IdleMode,
+		CheckInterval:    req.CheckInterval,
+		MaxRetries:       req.MaxRetries,
+		RetryInterval:    req.RetryInterval,
+		OnlyUnread:       req.OnlyUnread,
+		MarkAsRead:       req.MarkAsRead,
+		Schedule:         strings.TrimSpace(req.Schedule),
+		MatchedActions:   strings.TrimSpace(req.MatchedActions),
+		UnmatchedActions: strings.TrimSpace(req.UnmatchedActions),
+
+		AuthType:          authType,
+		OAuthClientID:     strings.TrimSpace(req.OAuthClientID),
+		OAuthClientSecret: req.OAuthClientSecret,
+		OAuthRefreshToken: strings.TrimSpace(req.OAuthRefreshToken),
+		OAuthTokenURL:     strings.TrimSpace(req.OAuthTokenURL),
+		OAuthScope:        strings.TrimSpace(req.OAuthScope),
+
+		TLSMode:               strings.TrimSpace(req.TLSMode),
+		TLSCACert:             strings.TrimSpace(req.TLSCACert),
+		TLSClientCert:         strings.TrimSpace(req.TLSClientCert),
+		TLSClientKey:          strings.TrimSpace(req.TLSClientKey),
+		TLSInsecureSkipVerify: req.TLSInsecureSkipVerify,
+		TLSMinVersion:         strings.TrimSpace(req.TLSMinVersion),
+
+		Proxy: strings.TrimSpace(req.Proxy),
+	}
+	applyProviderDefaults(mailbox)
+	if err := validateMailboxServer(mailbox); err != nil {
+		return nil, err
+	}
+	if err := validateMailboxAuth(mailbox); err != nil {
+		return nil, err
+	}
+	if err := validateMailboxTLS(mailbox); err != nil {
+		return nil, err
+	}
+	if err := proxy.Validate(mailbox.Proxy); err != nil {
+		return nil, fmt.Errorf("代理配置无效: %v", err)
 	}
 
 	// 保存到数据库
@@ -85,6 +258,7 @@ func (s *MailboxService) Create(req *CreateMailboxRequest) (*model.Mailbox, erro
 	if err != nil {
 		return nil, fmt.Errorf("创建邮箱配置失败: %v", err)
 	}
+	s.notifyWatcher(mailbox.ID)
 
 	// 返回时不包含密码
 	mailbox.Password = ""
@@ -123,6 +297,34 @@ func (s *MailboxService) GetByIDWithPassword(id uint) (*model.MailboxWithPasswor
 		SSL:         mailbox.SSL,
 		Status:      mailbox.Status,
 		Description: mailbox.Description,
+
+		Folders:          mailbox.Folders,
+		DeleteAfterFetch: mailbox.DeleteAfterFetch,
+		IdleMode:         mailbox.IdleMode,
+		CheckInterval:    mailbox.CheckInterval,
+		MaxRetries:       mailbox.MaxRetries,
+		RetryInterval:    mailbox.RetryInterval,
+		OnlyUnread:       mailbox.OnlyUnread,
+		MarkAsRead:       mailbox.MarkAsRead,
+		Schedule:         mailbox.Schedule,
+		MatchedActions:   mailbox.MatchedActions,
+		UnmatchedActions: mailbox.UnmatchedActions,
+
+		AuthType:          mailbox.AuthType,
+		OAuthClientID:     mailbox.OAuthClientID,
+		OAuthClientSecret: mailbox.OAuthClientSecret,
+		OAuthRefreshToken: mailbox.OAuthRefreshToken,
+		OAuthTokenURL:     mailbox.OAuthTokenURL,
+		OAuthScope:        mailbox.OAuthScope,
+
+		TLSMode:               mailbox.TLSMode,
+		TLSCACert:             mailbox.TLSCACert,
+		TLSClientCert:         mailbox.TLSClientCert,
+		TLSClientKey:          mailbox.TLSClientKey,
+		TLSInsecureSkipVerify: mailbox.TLSInsecureSkipVerify,
+		TLSMinVersion:         mailbox.TLSMinVersion,
+
+		Proxy: mailbox.Proxy,
 	}
 
 	return result, nil
@@ -175,6 +377,90 @@ func (s *MailboxService) Update(id uint, req *UpdateMailboxRequest) (*model.Mail
 		}
 	}
 
+	if req.Schedule != nil {
+		if err := email.ValidateSchedule(*req.Schedule); err != nil {
+			return nil, fmt.Errorf("监控时间段配置无效: %v", err)
+		}
+	}
+	if req.MatchedActions != nil {
+		if err := validateMailboxActions(*req.MatchedActions, ""); err != nil {
+			return nil, err
+		}
+	}
+	if req.UnmatchedActions != nil {
+		if err := validateMailboxActions("", *req.UnmatchedActions); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验更新后的服务器和认证配置
+	merged := *existingMailbox
+	if req.Protocol != "" {
+		merged.Protocol = req.Protocol
+	}
+	if req.Host != "" {
+		merged.Host = req.Host
+	}
+	if req.Port > 0 {
+		merged.Port = req.Port
+	}
+	if req.Username != "" {
+		merged.Username = req.Username
+	}
+	if req.Password != "" {
+		merged.Password = req.Password
+	}
+	if req.SSL != nil {
+		merged.SSL = *req.SSL
+	}
+	if req.AuthType != nil {
+		merged.AuthType = *req.AuthType
+	}
+	if req.OAuthClientID != nil {
+		merged.OAuthClientID = strings.TrimSpace(*req.OAuthClientID)
+	}
+	if req.OAuthClientSecret != "" {
+		merged.OAuthClientSecret = req.OAuthClientSecret
+	}
+	if req.OAuthRefreshToken != "" {
+		merged.OAuthRefreshToken = strings.TrimSpace(req.OAuthRefreshToken)
+	}
+	if req.OAuthTokenURL != nil {
+		merged.OAuthTokenURL = strings.TrimSpace(*req.OAuthTokenURL)
+	}
+	if req.TLSMode != nil {
+		merged.TLSMode = strings.TrimSpace(*req.TLSMode)
+	}
+	if req.TLSCACert != nil {
+		merged.TLSCACert = strings.TrimSpace(*req.TLSCACert)
+	}
+	if req.TLSClientCert != nil {
+		merged.TLSClientCert = strings.TrimSpace(*req.TLSClientCert)
+		if merged.TLSClientCert == "" {
+			merged.TLSClientKey = ""
+		}
+	}
+	if req.TLSClientKey != "" {
+		merged.TLSClientKey = strings.TrimSpace(req.TLSClientKey)
+	}
+	if req.TLSMinVersion != nil {
+		merged.TLSMinVersion = strings.TrimSpace(*req.TLSMinVersion)
+	}
+	if err := validateMailboxServer(&merged); err != nil {
+		return nil, err
+	}
+	if err := validateMailboxAuth(&merged); err != nil {
+		return nil, err
+	}
+	if err := validateMailboxTLS(&merged); err != nil {
+		return nil, err
+	}
+	if req.Proxy != nil {
+		if err := proxy.Validate(*req.Proxy); err != nil {
+			return nil, fmt.Errorf("代理配置无效: %v", err)
+		}
+	}
+
 	// 准备更新数据
 	updateData := &model.Mailbox{}
 
@@ -200,7 +486,6 @@ func (s *MailboxService) Update(id uint, req *UpdateMailboxRequest) (*model.Mail
 	if req.Protocol != "" {
 		updateData.Protocol = req.Protocol
 	}
-	updateData.SSL = req.SSL
 	if req.Status != "" {
 		updateData.Status = req.Status
 	}
@@ -212,6 +497,89 @@ func (s *MailboxService) Update(id uint, req *UpdateMailboxRequest) (*model.Mail
 		return nil, fmt.Errorf("更新邮箱配置失败: %v", err)
 	}
 
+	// 零值字段Updates会忽略，需要单独更新
+	fields := map[string]interface{}{}
+	if req.SSL != nil {
+		fields["ssl"] = *req.SSL
+	}
+	if req.Folders != nil {
+		fields["folders"] = normalizeFolders(*req.Folders)
+	}
+	if req.DeleteAfterFetch != nil {
+		fields["delete_after_fetch"] = *req.DeleteAfterFetch
+	}
+	if req.IdleMode != nil {
+		fields["idle_mode"] = *req.IdleMode
+	}
+	if req.CheckInterval != nil {
+		fields["check_interval"] = *req.CheckInterval
+	}
+	if req.MaxRetries != nil {
+		fields["max_retries"] = *req.MaxRetries
+	}
+	if req.RetryInterval != nil {
+		fields["retry_interval"] = *req.RetryInterval
+	}
+	if req.OnlyUnread != nil {
+		fields["only_unread"] = *req.OnlyUnread
+	}
+	if req.MarkAsRead != nil {
+		fields["mark_as_read"] = *req.MarkAsRead
+	}
+	if req.Schedule != nil {
+		fields["schedule"] = strings.TrimSpace(*req.Schedule)
+	}
+	if req.MatchedActions != nil {
+		fields["matched_actions"] = strings.TrimSpace(*req.MatchedActions)
+	}
+	if req.UnmatchedActions != nil {
+		fields["unmatched_actions"] = strings.TrimSpace(*req.UnmatchedActions)
+	}
+	if req.AuthType != nil {
+		fields["auth_type"] = *req.AuthType
+	}
+	if req.OAuthClientID != nil {
+		fields["oauth_client_id"] = strings.TrimSpace(*req.OAuthClientID)
+	}
+	if req.OAuthClientSecret != "" {
+		fields["oauth_client_secret"] = req.OAuthClientSecret
+	}
+	if req.OAuthRefreshToken != "" {
+		fields["oauth_refresh_token"] = strings.TrimSpace(req.OAuthRefreshToken)
+	}
+	if req.OAuthTokenURL != nil {
+		fields["oauth_token_url"] = strings.TrimSpace(*req.OAuthTokenURL)
+	}
+	if req.OAuthScope != nil {
+		fields["oauth_scope"] = strings.TrimSpace(*req.OAuthScope)
+	}
+	if req.TLSMode != nil {
+		fields["tls_mode"] = merged.TLSMode
+	}
+	if req.TLSCACert != nil {
+		fields["tls_ca_cert"] = merged.TLSCACert
+	}
+	if req.TLSClientCert != nil || req.TLSClientKey != "" {
+		// 清除客户端证书时同时清除私钥
+		fields["tls_client_cert"] = merged.TLSClientCert
+		fields["tls_client_key"] = merged.TLSClientKey
+	}
+	if req.TLSInsecureSkipVerify != nil {
+		fields["tls_insecure_skip_verify"] = *req.TLSInsecureSkipVerify
+	}
+	if req.TLSMinVersion != nil {
+		fields["tls_min_version"] = merged.TLSMinVersion
+	}
+	if req.Proxy != nil {
+		fields["proxy"] = strings.TrimSpace(*req.Proxy)
+	}
+	if len(fields) > 0 {
+		if err := s.mailboxRepo.UpdateFields(id, fields); err != nil {
+			return nil, fmt.Errorf("更新邮箱配置失败: %v", err)
+		}
+	}
+	s.notifyWatcher(id)
+
 	// 返回更新后的配置
 	return s.GetByID(id)
 }
@@ -226,7 +594,11 @@ func (s *MailboxService) Delete(id uint) error {
 
 	// TODO: 检查是否有关联的告警规则，如果有则不允许删除
 
-	return s.mailboxRepo.Delete(id)
+	if err := s.mailboxRepo.Delete(id); err != nil {
+		return err
+	}
+	s.notifyWatcher(id)
+	return nil
 }
 
 // TestConnection 测试邮箱连接
@@ -239,7 +611,7 @@ func (s *MailboxService) TestConnection(id uint) (*email.ConnectionInfo, error)
 
 	// 直接使用明文密码
 	// 创建邮件客户端
-	client := email.NewClient(mailbox.Host, mailbox.Port, mailbox.Username, mailbox.Password, mailbox.SSL)
+	client := email.NewClientFromConfig(toMailboxConfig(mailbox))
 
 	// 获取连接信息
 	return client.GetConnectionInfo(), nil
@@ -248,12 +620,34 @@ func (s *MailboxService) TestConnection(id uint) (*email.ConnectionInfo, error)
 // TestConnectionWithConfig 使用配置参数测试连接
 func (s *MailboxService) TestConnectionWithConfig(req *CreateMailboxRequest) (*email.ConnectionInfo, error) {
 	// 创建邮件客户端
-	client := email.NewClient(req.Host, req.Port, req.Username, req.Password, req.SSL)
+	client := email.NewClientFromConfig(email.MailboxConfig{
+		Host:     req.Host,
+		Port:     req.Port,
+		Username: req.Username,
+		Password: req.Password,
+		Protocol: req.Protocol,
+		SSL:      req.SSL,
+		AuthType: req.AuthType,
+		OAuth:    req.oauthConfig(),
+		TLS:      req.tlsOptions(),
+		Proxy:    strings.TrimSpace(req.Proxy),
+	})
 
 	// 获取连接信息
 	return client.GetConnectionInfo(), nil
 }
 
+// GetFolders 获取邮箱服务器上的文件夹列表（用于选择监控文件夹）
+func (s *MailboxService) GetFolders(id uint) ([]string, error) {
+	mailbox, err := s.mailboxRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	client := email.NewClientFromConfig(toMailboxConfig(mailbox))
+	return client.GetFolders()
+}
+
 // UpdateStatus 更新邮箱状态
 func (s *MailboxService) UpdateStatus(id uint, status string) error {
 	// 验证状态值
@@ -267,7 +661,11 @@ func (s *MailboxService) UpdateStatus(id uint, status string) error {
 		return err
 	}
 
-	return s.mailboxRepo.UpdateStatus(id, status)
+	if err := s.mailboxRepo.UpdateStatus(id, status); err != nil {
+		return err
+	}
+	s.notifyWatcher(id)
+	return nil
 }
 
 // GetActiveMailboxes 获取所有活跃的邮箱配置
@@ -294,18 +692,7 @@ func (s *MailboxService) DiagnoseMailbox(id uint) (*email.EmailDiagnosis, error)
 	}
 
 	// 转换为邮箱配置结构
-	mailboxConfig := email.MailboxConfig{
-		ID:       mailbox.ID,
-		Name:     mailbox.Name,
-		Email:    mailbox.Email,
-		Host:     mailbox.Host,
-		Port:     mailbox.Port,
-		Username: mailbox.Username,
-		Password: mailbox.Password,
-		Protocol: mailbox.Protocol,
-		SSL:      mailbox.SSL,
-		Status:   mailbox.Status,
-	}
+	mailboxConfig := toMailboxConfig(mailbox)
 
 	// 执行诊断
 	return email.DiagnoseMailbox(mailboxConfig), nil
@@ -324,8 +711,194 @@ func (s *MailboxService) DiagnoseMailboxWithConfig(req *CreateMailboxRequest) *e
 		Protocol: req.Protocol,
 		SSL:      req.SSL,
 		Status:   "active",
+
+		Folders:          splitFolders(req.Folders),
+		DeleteAfterFetch: req.DeleteAfterFetch,
+		IdleMode:         req.IdleMode,
+		AuthType:         req.AuthType,
+		OAuth:            req.oauthConfig(),
+		TLS:              req.tlsOptions(),
+		Proxy:            strings.TrimSpace(req.Proxy),
 	}
 
 	// 执行诊断
 	return email.DiagnoseMailbox(mailboxConfig)
 }
+
+// toMailboxConfig 将邮箱配置模型转换为监控使用的配置结构
+func toMailboxConfig(mailbox *model.Mailbox) email.MailboxConfig {
+	return email.MailboxConfig{
+		ID:       mailbox.ID,
+		Name:     mailbox.Name,
+		Email:    mailbox.Email,
+		Host:     mailbox.Host,
+		Port:     mailbox.Port,
+		Username: mailbox.Username,
+		Password: mailbox.Password,
+		Protocol: mailbox.Protocol,
+		SSL:      mailbox.SSL,
+		Status:   mailbox.Status,
+
+		Folders:          splitFolders(mailbox.Folders),
+		DeleteAfterFetch: mailbox.DeleteAfterFetch,
+		IdleMode:         mailbox.IdleMode,
+		CheckInterval:    time.Duration(mailbox.CheckInterval) * time.Second,
+		MaxRetries:       mailbox.MaxRetries,
+		RetryInterval:    time.Duration(mailbox.RetryInterval) * time.Second,
+		OnlyUnread:       mailbox.OnlyUnread,
+		MarkAsRead:       mailbox.MarkAsRead,
+		Schedule:         mailbox.Schedule,
+		MatchedActions:   parseMailboxActions(mailbox.MatchedActions),
+		UnmatchedActions: parseMailboxActions(mailbox.UnmatchedActions),
+
+		AuthType: mailbox.AuthType,
+		OAuth:    toOAuthConfig(mailbox),
+		TLS:      toTLSOptions(mailbox),
+		Proxy:    mailbox.Proxy,
+	}
+}
+
+// toOAuthConfig 提取邮箱的OAuth2凭据
+func toOAuthConfig(mailbox *model.Mailbox) oauth.Config {
+	return oauth.Config{
+		ClientID:     mailbox.OAuthClientID,
+		ClientSecret: mailbox.OAuthClientSecret,
+		RefreshToken: mailbox.OAuthRefreshToken,
+		TokenURL:     mailbox.OAuthTokenURL,
+		Scope:        mailbox.OAuthScope,
+	}
+}
+
+// toTLSOptions 提取邮箱的TLS配置
+func toTLSOptions(mailbox *model.Mailbox) email.TLSOptions {
+	return email.TLSOptions{
+		Mode:               mailbox.TLSMode,
+		CACert:             mailbox.TLSCACert,
+		ClientCert:         mailbox.TLSClientCert,
+		ClientKey:          mailbox.TLSClientKey,
+		InsecureSkipVerify: mailbox.TLSInsecureSkipVerify,
+		MinVersion:         mailbox.TLSMinVersion,
+	}
+}
+
+// GetProviders 获取邮箱服务商配置列表，用于前端自动填写服务器设置
+func (s *MailboxService) GetProviders() []email.ProviderProfile {
+	return email.ListProviders()
+}
+
+// applyProviderDefaults 未填写服务器地址或端口时，按服务商配置补全推荐设置
+// 未填写服务器地址时按邮箱地址的域名匹配服务商
+func applyProviderDefaults(mailbox *model.Mailbox) {
+	if email.IsPushProtocol(mailbox.Protocol) || (mailbox.Host != "" && mailbox.Port > 0) {
+		return
+	}
+
+	host := mailbox.Host
+	if host == "" {
+		if at := strings.LastIndex(mailbox.Email, "@"); at >= 0 {
+			host = mailbox.Email[at+1:]
+		}
+	}
+	provider := email.LookupProvider(host)
+	if provider == nil {
+		return
+	}
+
+	defaultHost, defaultPort := provider.IMAPHost, provider.IMAPPort
+	if strings.EqualFold(mailbox.Protocol, email.ProtocolPOP3) {
+		defaultHost, defaultPort = provider.POP3Host, provider.POP3Port
+	}
+	if mailbox.Host == "" && defaultHost != "" {
+		mailbox.Host = defaultHost
+		mailbox.SSL = provider.SSL
+	}
+	if mailbox.Port <= 0 {
+		mailbox.Port = defaultPort
+	}
+	if mailbox.Username == "" {
+		mailbox.Username = mailbox.Email
+	}
+}
+
+// validateMailboxServer 校验拉取邮箱的服务器配置，推送邮箱由接收服务投递，无需服务器信息
+func validateMailboxServer(mailbox *model.Mailbox) error {
+	if email.IsPushProtocol(mailbox.Protocol) {
+		return nil
+	}
+	if strings.TrimSpace(mailbox.Host) == "" {
+		return errors.New("服务器地址不能为空")
+	}
+	if mailbox.Port <= 0 {
+		return errors.New("端口不能为空")
+	}
+	if strings.TrimSpace(mailbox.Username) == "" {
+		return errors.New("用户名不能为空")
+	}
+	return nil
+}
+
+// validateMailboxAuth 校验邮箱认证配置
+func validateMailboxAuth(mailbox *model.Mailbox) error {
+	if email.IsPushProtocol(mailbox.Protocol) {
+		return nil
+	}
+	if mailbox.AuthType != oauth.AuthTypeOAuth2 {
+		if mailbox.Password == "" {
+			return errors.New("密码不能为空")
+		}
+		return nil
+	}
+
+	if err := toOAuthConfig(mailbox).Validate(); err != nil {
+		return fmt.Errorf("OAuth2配置无效: %v", err)
+	}
+	return nil
+}
+
+// validateMailboxTLS 校验邮箱TLS配置
+func validateMailboxTLS(mailbox *model.Mailbox) error {
+	if email.IsPushProtocol(mailbox.Protocol) {
+		return nil
+	}
+	if err := email.ValidateTLSOptions(toTLSOptions(mailbox)); err != nil {
+		return fmt.Errorf("TLS配置无效: %v", err)
+	}
+	return nil
+}
+
+// validateMailboxActions 校验匹配/未匹配时的处理后动作配置
+func validateMailboxActions(matched, unmatched string) error {
+	if err := email.ValidateActions(matched); err != nil {
+		return fmt.Errorf("匹配后动作配置无效: %v", err)
+	}
+	if err := email.ValidateActions(unmatched); err != nil {
+		return fmt.Errorf("未匹配动作配置无效: %v", err)
+	}
+	return nil
+}
+
+// parseMailboxActions 解析已保存的动作配置，配置无效时忽略并记录日志
+func parseMailboxActions(spec string) []email.MailboxAction {
+	actions, err := email.ParseActions(spec)
+	if err != nil {
+		log.Printf("解析处理后动作配置 %q 失败: %v", spec, err)
+		return nil
+	}
+	return actions
+}
+
+// splitFolders 解析逗号分隔的文件夹列表
+func splitFolders(folders string) []string {
+	var result []string
+	for _, folder := range strings.Split(folders, ",") {
+		if folder = strings.TrimSpace(folder); folder != "" {
+			result = append(result, folder)
+		}
+	}
+	return result
+}
+
+// normalizeFolders 规范化文件夹列表（去除空白和空项）
+func normalizeFolders(folders string) string {
+	return strings.Join(splitFolders(folders), ",")
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"emailAlert/internal/model"
+	"emailAlert/internal/repository"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestUpdateMailboxKeepsSSL 部分更新不包含ssl时保留原有设置，包含时可以关闭
+func TestUpdateMailboxKeepsSSL(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Mailbox{}); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	mailboxRepo := repository.NewMailboxRepository(db)
+	mailbox := &model.Mailbox{
+		Name:     "ops",
+		Email:    "ops@example.com",
+		Host:     "imap.example.com",
+		Port:     993,
+		Username: "ops@example.com",
+		Password: "secret",
+		Protocol: "IMAP",
+		SSL:      true,
+		Status:   "active",
+	}
+	if err := mailboxRepo.Create(mailbox); err != nil {
+		t.Fatalf("创建邮箱失败: %v", err)
+	}
+	service := NewMailboxService(mailboxRepo)
+
+	folders := "INBOX,Alerts"
+	updated, err := service.Update(mailbox.ID, &UpdateMailboxRequest{Folders: &folders})
+	if err != nil {
+		t.Fatalf("更新邮箱失败: %v", err)
+	}
+	if !updated.SSL || updated.Folders != folders {
+		t.Fatalf("只更新文件夹时应保留SSL，实际 ssl=%v folders=%q", updated.SSL, updated.Folders)
+	}
+
+	ssl := false
+	updated, err = service.Update(mailbox.ID, &UpdateMailboxRequest{SSL: &ssl})
+	if err != nil {
+		t.Fatalf("更新邮箱失败: %v", err)
+	}
+	if updated.SSL {
+		t.Fatal("请求中ssl为false时应关闭SSL")
+	}
+}
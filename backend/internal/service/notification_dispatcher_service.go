@@ -4,6 +4,7 @@ import (
 	"context"
 	"emailAlert/internal/model"
 	"emailAlert/internal/repository"
+	"emailAlert/pkg/timezone"
 	"fmt"
 	"log"
 	"strings"
@@ -14,10 +15,12 @@ import (
 // NotificationDispatcherService 通知分发服务接口
 type NotificationDispatcherService interface {
 	DispatchAlert(alert *model.Alert) error
+	DispatchAdminNotification(channel *model.Channel, title, content string) error
 	ProcessPendingAlerts() error
 	RetryFailedNotifications() error
 	StartBackgroundProcessor(ctx context.Context) error
 	GetDispatchStats() (map[string]interface{}, error)
+	GetQueueStatus() (length, capacity int)
 }
 
 // notificationDispatcherService 通知分发服务实现
@@ -96,6 +99,52 @@ func (s *notificationDispatcherService) DispatchAlert(alert *model.Alert) error
 	}
 }
 
+// adminNotificationSender 平台告警在模版中显示的发件人
+const adminNotificationSender = "邮件告警平台"
+
+// DispatchAdminNotification 向管理员渠道发送平台告警
+// 与告警通知一样使用渠道模版渲染并记录通知日志，发送失败的由重试工作器重试；
+// channel为调用方缓存的渠道配置，数据库不可用时跳过通知日志直接发送
+func (s *notificationDispatcherService) DispatchAdminNotification(channel *model.Channel, title, content string) error {
+	alert := &model.Alert{
+		Subject:    title,
+		Sender:     adminNotificationSender,
+		Content:    content,
+		ReceivedAt: time.Now(),
+	}
+
+	notificationLog := &model.NotificationLog{
+		ChannelID: channel.ID,
+		Status:    "pending",
+	}
+	if err := s.notificationLogRepo.Create(notificationLog); err != nil {
+		log.Printf("创建平台告警通知日志失败，将直接发送: %v", err)
+		notificationLog = nil
+	}
+
+	body, subject, err := s.generateNotificationContent(alert, channel)
+	if err != nil {
+		body, subject = s.generateSimpleContent(alert, channel.Type, timezone.Resolve(channel.Timezone)), title
+	}
+	if notificationLog != nil {
+		if err := s.notificationLogRepo.UpdateContent(notificationLog.ID, body); err != nil {
+			log.Printf("更新通知日志内容失败: %v", err)
+		}
+	}
+
+	sendErr := s.channelService.SendNotificationToChannel(channel, subject, body)
+	if notificationLog != nil {
+		status, errorMsg, responseData := "success", "", "发送成功"
+		if sendErr != nil {
+			status, errorMsg, responseData = "failed", sendErr.Error(), ""
+		}
+		if err := s.notificationLogRepo.UpdateStatus(notificationLog.ID, status, errorMsg, responseData); err != nil {
+			log.Printf("更新通知日志状态失败: %v", err)
+		}
+	}
+	return sendErr
+}
+
 // processAlert 处理单个告警
 func (s *notificationDispatcherService) processAlert(alert *model.Alert) error {
 	var channels []*model.Channel
@@ -207,8 +256,9 @@ func (s *notificationDispatcherService) sendNotificationToChannel(alert *model.A
 
 // generateNotificationContent 生成通知内容
 func (s *notificationDispatcherService) generateNotificationContent(alert *model.Alert, channel *model.Channel) (string, string, error) {
-	// 获取渲染数据
-	renderData := s.buildRenderData(alert)
+	// 获取渲染数据，时间按渠道配置的时区展示
+	loc := timezone.Resolve(channel.Timezone)
+	renderData := s.buildRenderData(alert, loc)
 
 	var template *model.Template
 	var err error
@@ -226,7 +276,7 @@ func (s *notificationDispatcherService) generateNotificationContent(alert *model
 		template, err = s.templateService.GetDefaultByType(channel.Type)
 		if err != nil {
 			log.Printf("获取默认模版失败，使用简单格式: %v", err)
-			return s.generateSimpleContent(alert, channel.Type), alert.Subject, nil
+			return s.generateSimpleContent(alert, channel.Type, loc), alert.Subject, nil
 		}
 	}
 
@@ -234,7 +284,7 @@ func (s *notificationDispatcherService) generateNotificationContent(alert *model
 	result, err := s.templateService.Render(template.ID, renderData)
 	if err != nil {
 		log.Printf("渲染模版失败，使用简单格式: %v", err)
-		return s.generateSimpleContent(alert, channel.Type), alert.Subject, nil
+		return s.generateSimpleContent(alert, channel.Type, loc), alert.Subject, nil
 	}
 
 	// 处理消息长度限制
@@ -248,8 +298,11 @@ func (s *notificationDispatcherService) generateNotificationContent(alert *model
 }
 
 // buildRenderData 构建模版渲染数据
-func (s *notificationDispatcherService) buildRenderData(alert *model.Alert) *model.TemplateRenderData {
-	now := time.Now()
+func (s *notificationDispatcherService) buildRenderData(alert *model.Alert, loc *time.Location) *model.TemplateRenderData {
+	// 告警时间统一转换为展示时区，避免修改原始告警
+	localAlert := *alert
+	localAlert.ReceivedAt = alert.ReceivedAt.In(loc)
+	alert = &localAlert
 
 	// 构建邮件数据
 	emailData := &model.EmailData{
@@ -270,13 +323,8 @@ func (s *notificationDispatcherService) buildRenderData(alert *model.Alert) *mod
 			ServerName:  "localhost",
 			Environment: "production",
 		},
-		Time: model.TimeInfo{
-			Now:       now,
-			NowFormat: now.Format("2006-01-02 15:04:05"),
-			NowUnix:   now.Unix(),
-			Today:     now.Format("2006-01-02"),
-			Yesterday: now.AddDate(0, 0, -1).Format("2006-01-02"),
-		},
+		Time: newTimeInfo(time.Now(), loc),
+		Vars: alert.LabelMap(),
 	}
 
 	// 如果有关联规则，添加规则信息
@@ -293,20 +341,21 @@ func (s *notificationDispatcherService) buildRenderData(alert *model.Alert) *mod
 }
 
 // generateSimpleContent 生成简单格式的通知内容
-func (s *notificationDispatcherService) generateSimpleContent(alert *model.Alert, channelType string) string {
+func (s *notificationDispatcherService) generateSimpleContent(alert *model.Alert, channelType string, loc *time.Location) string {
+	receivedAt := alert.ReceivedAt.In(loc).Format("2006-01-02 15:04:05")
 	switch channelType {
 	case "dingtalk":
 		return fmt.Sprintf("## 邮件告警通知\n\n**主题：** %s\n**发件人：** %s\n**时间：** %s\n\n**内容：**\n%s",
-			alert.Subject, alert.Sender, alert.ReceivedAt.Format("2006-01-02 15:04:05"), alert.Content)
+			alert.Subject, alert.Sender, receivedAt, alert.Content)
 	case "wechat":
 		return fmt.Sprintf("邮件告警通知\n主题：%s\n发件人：%s\n时间：%s\n\n内容：\n%s",
-			alert.Subject, alert.Sender, alert.ReceivedAt.Format("2006-01-02 15:04:05"), alert.Content)
+			alert.Subject, alert.Sender, receivedAt, alert.Content)
 	case "email":
 		return fmt.Sprintf("<h2>邮件告警通知</h2><p><strong>主题：</strong>%s</p><p><strong>发件人：</strong>%s</p><p><strong>时间：</strong>%s</p><p><strong>内容：</strong></p><pre>%s</pre>",
-			alert.Subject, alert.Sender, alert.ReceivedAt.Format("2006-01-02 15:04:05"), alert.Content)
+			alert.Subject, alert.Sender, receivedAt, alert.Content)
 	default:
 		return fmt.Sprintf("邮件告警通知\n主题：%s\n发件人：%s\n时间：%s\n内容：%s",
-			alert.Subject, alert.Sender, alert.ReceivedAt.Format("2006-01-02 15:04:05"), alert.Content)
+			alert.Subject, alert.Sender, receivedAt, alert.Content)
 	}
 }
 
@@ -543,9 +592,15 @@ func (s *notificationDispatcherService) GetDispatchStats() (map[string]interface
 
 	// 添加系统状态
 	stats["queue_size"] = len(s.alertQueue)
+	stats["queue_capacity"] = cap(s.alertQueue)
 	stats["retry_queue_size"] = len(s.retryQueue)
 	stats["max_workers"] = s.maxWorkers
 	stats["max_retry_count"] = s.maxRetryCount
 
 	return stats, nil
 }
+
+// GetQueueStatus 获取告警分发队列的当前长度和容量
+func (s *notificationDispatcherService) GetQueueStatus() (length, capacity int) {
+	return len(s.alertQueue), cap(s.alertQueue)
+}
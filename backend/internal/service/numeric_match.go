@@ -0,0 +1,171 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultNumberPattern 未配置value_pattern时从字段内容中取第一个数值（可带千分位、百分号和单位）
+var defaultNumberPattern = regexp.MustCompile(`[+-]?\d+(?:,\d{3})*(?:\.\d+)?(?:\s*(?:%|[KkMmGgTt]i?[Bb]\b|[Bb]\b|[KkMmGgTt]\b))?`)
+
+// numericValuePattern 数值及单位的格式
+var numericValuePattern = regexp.MustCompile(`^([+-]?\d+(?:\.\d+)?)\s*([A-Za-z%]*)$`)
+
+// numericUnits 数值单位的倍数，不区分大小写
+// 百分比按百分数比较（93%即93），带B的为二进制字节单位，k/m/g/t为十进制数量单位
+var numericUnits = map[string]float64{
+	"":    1,
+	"%":   1,
+	"k":   1e3,
+	"m":   1e6,
+	"g":   1e9,
+	"t":   1e12,
+	"b":   1,
+	"kb":  1 << 10,
+	"mb":  1 << 20,
+	"gb":  1 << 30,
+	"tb":  1 << 40,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+}
+
+// numericMatchTypeNames 数值比较类型的说明，用于匹配原因
+var numericMatchTypeNames = map[string]string{
+	"gt":  "大于",
+	"gte": "大于等于",
+	"lt":  "小于",
+	"lte": "小于等于",
+}
+
+// isNumericMatchType 是否为数值比较类型
+func isNumericMatchType(matchType string) bool {
+	switch matchType {
+	case "gt", "gte", "lt", "lte", "between":
+		return true
+	}
+	return false
+}
+
+// numericMatcher 预编译的数值比较条件
+type numericMatcher struct {
+	op         string
+	thresholds []float64      // gt/gte/lt/lte为一个阈值，between为最小值和最大值
+	pattern    *regexp.Regexp // 提取数值的正则
+	group      int            // 数值所在的分组下标
+}
+
+// compileNumericMatcher 解析阈值和提取数值的正则，keywords为去除空白后的关键词
+func compileNumericMatcher(op, valuePattern string, keywords []string) (*numericMatcher, error) {
+	matcher := &numericMatcher{op: op, pattern: defaultNumberPattern}
+
+	if op == "between" {
+		if len(keywords) != 2 {
+			return nil, fmt.Errorf("between类型需要两个阈值（最小值,最大值），实际为 %d 个", len(keywords))
+		}
+	} else if len(keywords) != 1 {
+		return nil, fmt.Errorf("%s类型只能有一个阈值，实际为 %d 个", op, len(keywords))
+	}
+	for _, keyword := range keywords {
+		threshold, err := parseNumericValue(keyword)
+		if err != nil {
+			return nil, fmt.Errorf("阈值 '%s' 无效: %v", keyword, err)
+		}
+		matcher.thresholds = append(matcher.thresholds, threshold)
+	}
+	if op == "between" && matcher.thresholds[0] > matcher.thresholds[1] {
+		return nil, fmt.Errorf("between类型的最小值 %s 大于最大值 %s", keywords[0], keywords[1])
+	}
+
+	if valuePattern != "" {
+		regex, err := regexp.Compile(valuePattern)
+		if err != nil {
+			return nil, fmt.Errorf("提取数值的正则表达式编译失败: %v", err)
+		}
+		matcher.pattern = regex
+		if index := regex.SubexpIndex("value"); index > 0 {
+			matcher.group = index
+		} else if regex.NumSubexp() > 0 {
+			matcher.group = 1
+		}
+	}
+
+	return matcher, nil
+}
+
+// match 从字段内容中提取数值并与阈值比较，返回是否匹配和原因
+func (m *numericMatcher) match(content string) (bool, string) {
+	match := m.pattern.FindStringSubmatch(content)
+	if match == nil {
+		if m.pattern == defaultNumberPattern {
+			return false, "字段内容中没有找到数值"
+		}
+		return false, "提取数值的正则没有匹配字段内容"
+	}
+	raw := strings.TrimSpace(match[m.group])
+	if raw == "" {
+		return false, "提取数值的正则分组为空"
+	}
+
+	value, err := parseNumericValue(raw)
+	if err != nil {
+		return false, fmt.Sprintf("无法解析提取的数值 '%s': %v", raw, err)
+	}
+
+	source := formatNumber(value)
+	if source != raw {
+		source = fmt.Sprintf("%s（提取自 '%s'）", source, raw)
+	}
+
+	if m.op == "between" {
+		low, high := m.thresholds[0], m.thresholds[1]
+		if value >= low && value <= high {
+			return true, fmt.Sprintf("数值 %s 在 [%s, %s] 范围内", source, formatNumber(low), formatNumber(high))
+		}
+		return false, fmt.Sprintf("数值 %s 不在 [%s, %s] 范围内", source, formatNumber(low), formatNumber(high))
+	}
+
+	threshold := m.thresholds[0]
+	var matched bool
+	switch m.op {
+	case "gt":
+		matched = value > threshold
+	case "gte":
+		matched = value >= threshold
+	case "lt":
+		matched = value < threshold
+	case "lte":
+		matched = value <= threshold
+	}
+	if matched {
+		return true, fmt.Sprintf("数值 %s %s阈值 %s", source, numericMatchTypeNames[m.op], formatNumber(threshold))
+	}
+	return false, fmt.Sprintf("数值 %s 不%s阈值 %s", source, numericMatchTypeNames[m.op], formatNumber(threshold))
+}
+
+// parseNumericValue 解析带单位的数值，如 "93%"、"12,000"、"1.5GB"、"12k"
+func parseNumericValue(value string) (float64, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), ",", "")
+	parts := numericValuePattern.FindStringSubmatch(value)
+	if parts == nil {
+		return 0, fmt.Errorf("不是有效的数值: %s", value)
+	}
+
+	multiplier, ok := numericUnits[strings.ToLower(parts[2])]
+	if !ok {
+		return 0, fmt.Errorf("不支持的单位: %s", parts[2])
+	}
+	number, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("不是有效的数值: %s", value)
+	}
+	return number * multiplier, nil
+}
+
+// formatNumber 格式化数值，去掉多余的小数位
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
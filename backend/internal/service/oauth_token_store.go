@@ -0,0 +1,53 @@
+package service
+
+import (
+	"emailAlert/internal/repository"
+	"emailAlert/pkg/oauth"
+	"encoding/json"
+	"fmt"
+)
+
+// oauthTokenStore 将令牌端点轮换后的refresh token写回使用该凭据的邮箱和邮件渠道
+type oauthTokenStore struct {
+	mailboxRepo *repository.MailboxRepository
+	channelRepo repository.ChannelRepository
+}
+
+// NewOAuthTokenStore 创建refresh token持久化存储
+func NewOAuthTokenStore(mailboxRepo *repository.MailboxRepository, channelRepo repository.ChannelRepository) oauth.RefreshTokenStore {
+	return &oauthTokenStore{mailboxRepo: mailboxRepo, channelRepo: channelRepo}
+}
+
+// SaveRefreshToken 更新client_id和旧refresh token都相同的邮箱及邮件渠道
+func (s *oauthTokenStore) SaveRefreshToken(config oauth.Config, refreshToken string) error {
+	if err := s.mailboxRepo.UpdateOAuthRefreshToken(config.ClientID, config.RefreshToken, refreshToken); err != nil {
+		return fmt.Errorf("更新邮箱refresh token失败: %v", err)
+	}
+
+	channels, err := s.channelRepo.GetByType("email")
+	if err != nil {
+		return fmt.Errorf("获取邮件渠道失败: %v", err)
+	}
+	for _, channel := range channels {
+		// 按通用JSON修改，保留渠道配置中的其他字段
+		var channelConfig map[string]interface{}
+		if err := json.Unmarshal([]byte(channel.Config), &channelConfig); err != nil {
+			continue
+		}
+		credentials, ok := channelConfig["oauth"].(map[string]interface{})
+		if !ok || credentials["client_id"] != config.ClientID || credentials["refresh_token"] != config.RefreshToken {
+			continue
+		}
+
+		credentials["refresh_token"] = refreshToken
+		data, err := json.Marshal(channelConfig)
+		if err != nil {
+			return fmt.Errorf("序列化渠道 %s 配置失败: %v", channel.Name, err)
+		}
+		channel.Config = string(data)
+		if err := s.channelRepo.Update(channel); err != nil {
+			return fmt.Errorf("更新渠道 %s 的refresh token失败: %v", channel.Name, err)
+		}
+	}
+	return nil
+}
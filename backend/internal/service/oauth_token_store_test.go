@@ -0,0 +1,81 @@
+package service
+
+import (
+	"emailAlert/internal/model"
+	"emailAlert/internal/repository"
+	"emailAlert/pkg/oauth"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestOAuthTokenStorePersistsRotation 轮换后的refresh token只写回使用同一凭据的邮箱和邮件渠道
+func TestOAuthTokenStorePersistsRotation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Mailbox{}, &model.Channel{}); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	mailboxRepo := repository.NewMailboxRepository(db)
+	channelRepo := repository.NewChannelRepository(db)
+	mailboxes := []*model.Mailbox{
+		{Name: "ops", Email: "ops@example.com", OAuthClientID: "client", OAuthRefreshToken: "rt-1"},
+		{Name: "other", Email: "other@example.com", OAuthClientID: "client", OAuthRefreshToken: "rt-other"},
+	}
+	for _, mailbox := range mailboxes {
+		mailbox.Host, mailbox.Port, mailbox.Username = "imap.example.com", 993, mailbox.Email
+		mailbox.Protocol, mailbox.Status = "IMAP", "active"
+		if err := mailboxRepo.Create(mailbox); err != nil {
+			t.Fatalf("创建邮箱失败: %v", err)
+		}
+	}
+	channel := &model.Channel{
+		Name:   "mail",
+		Type:   "email",
+		Config: `{"smtp_host":"smtp.example.com","oauth":{"client_id":"client","refresh_token":"rt-1"}}`,
+		Status: "active",
+	}
+	if err := channelRepo.Create(channel); err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+
+	store := NewOAuthTokenStore(mailboxRepo, channelRepo)
+	if err := store.SaveRefreshToken(oauth.Config{ClientID: "client", RefreshToken: "rt-1"}, "rt-2"); err != nil {
+		t.Fatalf("保存refresh token失败: %v", err)
+	}
+
+	for _, want := range []struct {
+		id    uint
+		token string
+	}{{mailboxes[0].ID, "rt-2"}, {mailboxes[1].ID, "rt-other"}} {
+		mailbox, err := mailboxRepo.GetByID(want.id)
+		if err != nil {
+			t.Fatalf("获取邮箱失败: %v", err)
+		}
+		if mailbox.OAuthRefreshToken != want.token {
+			t.Fatalf("邮箱 %s 的refresh token为 %s，期望 %s", mailbox.Name, mailbox.OAuthRefreshToken, want.token)
+		}
+	}
+
+	saved, err := channelRepo.GetByID(channel.ID)
+	if err != nil {
+		t.Fatalf("获取渠道失败: %v", err)
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(saved.Config), &config); err != nil {
+		t.Fatalf("解析渠道配置失败: %v", err)
+	}
+	credentials := config["oauth"].(map[string]interface{})
+	if credentials["refresh_token"] != "rt-2" || config["smtp_host"] != "smtp.example.com" {
+		t.Fatalf("渠道配置未正确更新: %s", saved.Config)
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"emailAlert/internal/model"
+	"emailAlert/pkg/ahocorasick"
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// compiledCondition 预编译的匹配条件，关键词只解析一次
+type compiledCondition struct {
+	condition     *model.MatchCondition
+	keywords      []string             // 去除空白后的关键词
+	lowerKeywords []string             // 小写关键词，用于不区分大小写的匹配
+	regexes       []*regexp.Regexp     // regex类型的预编译正则，与keywords一一对应
+	matcher       *ahocorasick.Matcher // contains/notContains类型的多关键词自动机
+	numeric       *numericMatcher      // 数值比较类型的阈值和提取数值的正则
+	err           error                // 编译错误（如正则无效），匹配时返回
+}
+
+// compiledRuleGroup 规则组编译后的激活条件和变量提取规则
+type compiledRuleGroup struct {
+	conditions  []*compiledCondition
+	extractions []*compiledExtraction
+}
+
+// newCompiledRuleGroup 编译规则组的条件和变量提取规则
+func newCompiledRuleGroup(ruleGroup *model.RuleGroup, conditions []*model.MatchCondition) *compiledRuleGroup {
+	return &compiledRuleGroup{
+		conditions:  compileConditions(conditions),
+		extractions: compileExtractionRules(ruleGroup.Extractions),
+	}
+}
+
+// ruleCache 规则组的编译缓存，规则组或条件变更时由规则组服务失效
+type ruleCache struct {
+	mutex      sync.RWMutex
+	sets       map[uint]*compiledRuleGroup
+	generation uint64 // 每次失效递增，避免加载期间发生的变更被旧数据覆盖
+}
+
+// newRuleCache 创建规则缓存
+func newRuleCache() *ruleCache {
+	return &ruleCache{sets: make(map[uint]*compiledRuleGroup)}
+}
+
+// get 获取规则组的编译结果，同时返回当前版本号供put使用
+func (c *ruleCache) get(ruleGroupID uint) (*compiledRuleGroup, uint64, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	compiled, ok := c.sets[ruleGroupID]
+	return compiled, c.generation, ok
+}
+
+// put 保存规则组的编译结果，get之后缓存被失效过时放弃保存
+func (c *ruleCache) put(ruleGroupID uint, compiled *compiledRuleGroup, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.generation != generation {
+		return
+	}
+	c.sets[ruleGroupID] = compiled
+}
+
+// invalidate 失效规则组的编译结果，ruleGroupID为0时清空全部
+func (c *ruleCache) invalidate(ruleGroupID uint) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	if ruleGroupID == 0 {
+		c.sets = make(map[uint]*compiledRuleGroup)
+		return
+	}
+	delete(c.sets, ruleGroupID)
+}
+
+// compileConditions 编译一组匹配条件，跳过未启用的条件
+func compileConditions(conditions []*model.MatchCondition) []*compiledCondition {
+	compiled := make([]*compiledCondition, 0, len(conditions))
+	for _, condition := range conditions {
+		if condition.Status != "active" {
+			continue
+		}
+		compiled = append(compiled, compileCondition(condition))
+	}
+	return compiled
+}
+
+// compileCondition 解析关键词并按匹配类型预编译
+func compileCondition(condition *model.MatchCondition) *compiledCondition {
+	compiled := &compiledCondition{condition: condition}
+
+	switch {
+	case condition.MatchType == "expression":
+		// 表达式由CompileExpression单独缓存，这里只提前发现编译错误
+		_, compiled.err = CompileExpression(condition.Keywords)
+		return compiled
+	case condition.FieldType == "attachment_size":
+		// 附件大小按数值比较，不需要预编译关键词
+		return compiled
+	}
+
+	for _, keyword := range strings.Split(condition.Keywords, ",") {
+		if trimmed := strings.TrimSpace(keyword); trimmed != "" {
+			compiled.keywords = append(compiled.keywords, trimmed)
+			compiled.lowerKeywords = append(compiled.lowerKeywords, strings.ToLower(trimmed))
+		}
+	}
+
+	switch condition.MatchType {
+	case "regex":
+		for _, keyword := range compiled.keywords {
+			regex, err := regexp.Compile(keyword)
+			if err != nil {
+				compiled.err = fmt.Errorf("匹配关键词 '%s' 失败: 正则表达式编译失败: %v", keyword, err)
+				break
+			}
+			compiled.regexes = append(compiled.regexes, regex)
+		}
+	case "contains", "notContains":
+		compiled.matcher = ahocorasick.New(compiled.lowerKeywords)
+	case "equals", "startsWith", "endsWith":
+	case "gt", "gte", "lt", "lte", "between":
+		compiled.numeric, compiled.err = compileNumericMatcher(condition.MatchType, strings.TrimSpace(condition.ValuePattern), compiled.keywords)
+	default:
+		compiled.err = fmt.Errorf("不支持的匹配类型: %s", condition.MatchType)
+	}
+
+	return compiled
+}
+
+// emailFields 单封邮件提取出的字段内容，小写形式按需转换并缓存，供所有条件共用
+type emailFields struct {
+	values map[string]string
+	lower  map[string]string
+}
+
+// newEmailFields 创建字段内容，values为ExtractEmailFields的结果
+func newEmailFields(values map[string]string) *emailFields {
+	return &emailFields{values: values, lower: make(map[string]string)}
+}
+
+// lowerOf 获取字段内容的小写形式，key标识字段（邮件头使用规范化名称）
+func (f *emailFields) lowerOf(key, content string) string {
+	if lower, ok := f.lower[key]; ok {
+		return lower
+	}
+	lower := strings.ToLower(content)
+	f.lower[key] = lower
+	return lower
+}
+
+// fieldKey 条件字段在emailFields中的缓存键
+func fieldKey(condition *model.MatchCondition) string {
+	if condition.FieldType == "header" {
+		return "header:" + textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(condition.HeaderName))
+	}
+	return condition.FieldType
+}
+
+// matchKeywords 返回匹配成功的关键词，lower返回字段内容的小写形式
+func (c *compiledCondition) matchKeywords(content string, lower func() string) []string {
+	var matched []string
+
+	switch c.condition.MatchType {
+	case "contains", "notContains":
+		found := c.matcher.Match(lower())
+		wantFound := c.condition.MatchType == "contains"
+		for i, keyword := range c.keywords {
+			if found[i] == wantFound {
+				matched = append(matched, keyword)
+			}
+		}
+	case "regex":
+		for i, regex := range c.regexes {
+			if regex.MatchString(content) {
+				matched = append(matched, c.keywords[i])
+			}
+		}
+	case "equals":
+		for _, keyword := range c.keywords {
+			if content == keyword {
+				matched = append(matched, keyword)
+			}
+		}
+	case "startsWith":
+		lowerContent := lower()
+		for i, keyword := range c.lowerKeywords {
+			if strings.HasPrefix(lowerContent, keyword) {
+				matched = append(matched, c.keywords[i])
+			}
+		}
+	case "endsWith":
+		lowerContent := lower()
+		for i, keyword := range c.lowerKeywords {
+			if strings.HasSuffix(lowerContent, keyword) {
+				matched = append(matched, c.keywords[i])
+			}
+		}
+	}
+
+	return matched
+}
@@ -0,0 +1,320 @@
+package service
+
+import (
+	"emailAlert/internal/model"
+	"emailAlert/internal/repository"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// benchWords 生成测试规则和邮件使用的词表
+var benchWords = []string{
+	"disk", "cpu", "memory", "latency", "timeout", "error", "critical", "warning", "backup", "failed",
+	"database", "replica", "network", "packet", "loss", "certificate", "expired", "quota", "exceeded", "queue",
+	"deploy", "rollback", "cluster", "node", "pod", "restart", "oom", "killed", "threshold", "alarm",
+}
+
+// BenchmarkMatchRuleGroups 测试大量规则组下单封邮件的匹配耗时
+// cached为命中编译缓存的情况，uncached每封邮件前清空缓存，相当于每次从数据库加载并编译条件
+func BenchmarkMatchRuleGroups(b *testing.B) {
+	for _, groups := range []int{100, 2000} {
+		b.Run(fmt.Sprintf("groups=%d", groups), func(b *testing.B) {
+			engine, ruleGroups := setupBenchRuleEngine(b, groups, 3, 5)
+			emails := generateBenchEmails(rand.New(rand.NewSource(2)), 100)
+
+			b.Run("cached", func(b *testing.B) {
+				engine.MatchRuleGroups(emails[0], ruleGroups)
+				runMatchBenchmark(b, engine, emails, ruleGroups, false)
+			})
+			b.Run("uncached", func(b *testing.B) {
+				runMatchBenchmark(b, engine, emails, ruleGroups, true)
+			})
+		})
+	}
+}
+
+// runMatchBenchmark 每次迭代匹配一封邮件，并报告每秒匹配的规则组数
+func runMatchBenchmark(b *testing.B, engine EnhancedRuleEngineService, emails []*model.EmailData, ruleGroups []*model.RuleGroup, invalidate bool) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if invalidate {
+			engine.InvalidateRuleCache(0)
+		}
+		if _, err := engine.MatchRuleGroups(emails[i%len(emails)], ruleGroups); err != nil {
+			b.Fatalf("匹配失败: %v", err)
+		}
+	}
+	b.ReportMetric(float64(b.N*len(ruleGroups))/b.Elapsed().Seconds(), "groups/s")
+}
+
+// setupBenchRuleEngine 使用内存数据库生成规则组和条件，返回规则引擎和规则组列表
+func setupBenchRuleEngine(b *testing.B, groups, conditionsPerGroup, keywordsPerCondition int) (EnhancedRuleEngineService, []*model.RuleGroup) {
+	b.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:bench%d?mode=memory&cache=shared", groups)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		b.Fatalf("创建内存数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.RuleGroup{}, &model.MatchCondition{}, &model.Alert{}, &model.AlertLabel{}); err != nil {
+		b.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	ruleGroupRepo := repository.NewRuleGroupRepository(db)
+	conditionRepo := repository.NewMatchConditionRepository(db)
+	ruleGroups, err := generateBenchRuleGroups(ruleGroupRepo, conditionRepo, rand.New(rand.NewSource(1)), groups, conditionsPerGroup, keywordsPerCondition)
+	if err != nil {
+		b.Fatalf("生成规则失败: %v", err)
+	}
+
+	engine := NewEnhancedRuleEngineService(ruleGroupRepo, conditionRepo, *repository.NewAlertRepository(db))
+	return engine, ruleGroups
+}
+
+// generateBenchRuleGroups 生成规则组和条件并写入数据库，匹配类型覆盖contains/regex/startsWith/header
+func generateBenchRuleGroups(ruleGroupRepo repository.RuleGroupRepository, conditionRepo repository.MatchConditionRepository, rng *rand.Rand, groups, conditionsPerGroup, keywordsPerCondition int) ([]*model.RuleGroup, error) {
+	ruleGroups := make([]*model.RuleGroup, 0, groups)
+	for i := 0; i < groups; i++ {
+		ruleGroup := &model.RuleGroup{
+			Name:      fmt.Sprintf("bench-%d", i),
+			MailboxID: 1,
+			Logic:     []string{"and", "or"}[rng.Intn(2)],
+			Status:    "active",
+		}
+		if err := ruleGroupRepo.Create(ruleGroup); err != nil {
+			return nil, err
+		}
+
+		conditions := make([]*model.MatchCondition, 0, conditionsPerGroup)
+		for j := 0; j < conditionsPerGroup; j++ {
+			condition := &model.MatchCondition{
+				RuleGroupID:  ruleGroup.ID,
+				FieldType:    []string{"subject", "body", "from"}[rng.Intn(3)],
+				KeywordLogic: "or",
+				Status:       "active",
+			}
+			switch rng.Intn(4) {
+			case 0:
+				condition.MatchType = "regex"
+				condition.Keywords = fmt.Sprintf(`(?i)%s.*\d+%%`, benchWords[rng.Intn(len(benchWords))])
+			case 1:
+				condition.MatchType = "startsWith"
+				condition.Keywords = benchKeywords(rng, keywordsPerCondition)
+			case 2:
+				condition.FieldType = "header"
+				condition.HeaderName = "X-Severity"
+				condition.MatchType = "equals"
+				condition.Keywords = "high"
+			default:
+				condition.MatchType = "contains"
+				condition.Keywords = benchKeywords(rng, keywordsPerCondition)
+			}
+			conditions = append(conditions, condition)
+		}
+		if err := conditionRepo.BatchCreate(conditions); err != nil {
+			return nil, err
+		}
+		ruleGroups = append(ruleGroups, ruleGroup)
+	}
+	return ruleGroups, nil
+}
+
+// benchKeywords 随机选取关键词，以逗号连接
+func benchKeywords(rng *rand.Rand, count int) string {
+	keywords := make([]string, count)
+	for i := range keywords {
+		keywords[i] = benchWords[rng.Intn(len(benchWords))] + benchWords[rng.Intn(len(benchWords))]
+	}
+	return strings.Join(keywords, ",")
+}
+
+// generateBenchEmails 生成随机邮件，正文约2KB
+func generateBenchEmails(rng *rand.Rand, count int) []*model.EmailData {
+	emails := make([]*model.EmailData, count)
+	for i := range emails {
+		var body strings.Builder
+		for body.Len() < 2048 {
+			body.WriteString(benchWords[rng.Intn(len(benchWords))])
+			body.WriteString(" ")
+			if rng.Intn(10) == 0 {
+				fmt.Fprintf(&body, "%d%% ", rng.Intn(100))
+			}
+		}
+		severity := []string{"low", "medium", "high"}[rng.Intn(3)]
+		emails[i] = &model.EmailData{
+			Subject:    fmt.Sprintf("%s %s %d%%", benchWords[rng.Intn(len(benchWords))], benchWords[rng.Intn(len(benchWords))], rng.Intn(100)),
+			Sender:     fmt.Sprintf("monitor-%d@example.com", rng.Intn(20)),
+			To:         []string{"ops@example.com"},
+			Content:    body.String(),
+			Folder:     "INBOX",
+			Headers:    map[string][]string{"X-Severity": {severity}},
+			ReceivedAt: time.Now(),
+			MessageID:  fmt.Sprintf("<bench-%d@example.com>", i),
+		}
+	}
+	return emails
+}
+
+// setupRuleCacheTest 创建规则组服务和规则引擎，返回已创建的邮箱ID
+func setupRuleCacheTest(t *testing.T) (RuleGroupService, EnhancedRuleEngineService, uint) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Mailbox{}, &model.RuleGroup{}, &model.MatchCondition{}, &model.RuleGroupChannel{}, &model.Alert{}, &model.AlertLabel{}); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	mailboxRepo := repository.NewMailboxRepository(db)
+	mailbox := &model.Mailbox{Name: "ops", Email: "ops@example.com", Host: "imap.example.com", Port: 993, Username: "ops@example.com", Protocol: "IMAP", Status: "active"}
+	if err := mailboxRepo.Create(mailbox); err != nil {
+		t.Fatalf("创建邮箱失败: %v", err)
+	}
+
+	ruleGroupRepo := repository.NewRuleGroupRepository(db)
+	conditionRepo := repository.NewMatchConditionRepository(db)
+	engine := NewEnhancedRuleEngineService(ruleGroupRepo, conditionRepo, *repository.NewAlertRepository(db))
+	service := NewRuleGroupService(ruleGroupRepo, conditionRepo, repository.NewRuleGroupChannelRepository(db), mailboxRepo, engine)
+	return service, engine, mailbox.ID
+}
+
+// matchOne 匹配单个规则组
+func matchOne(t *testing.T, engine EnhancedRuleEngineService, emailData *model.EmailData, ruleGroup *model.RuleGroup) *RuleGroupMatchResult {
+	t.Helper()
+	results, err := engine.MatchRuleGroups(emailData, []*model.RuleGroup{ruleGroup})
+	if err != nil {
+		t.Fatalf("匹配失败: %v", err)
+	}
+	return results[0]
+}
+
+// TestRuleCacheInvalidation 规则组、条件变更或删除规则组后，匹配使用最新的条件和变量提取规则
+func TestRuleCacheInvalidation(t *testing.T) {
+	service, engine, mailboxID := setupRuleCacheTest(t)
+	emailData := &model.EmailData{Subject: "disk usage 95% host=web1", Sender: "monitor@example.com", Folder: "INBOX"}
+
+	ruleGroup := &model.RuleGroup{
+		Name:        "disk",
+		MailboxID:   mailboxID,
+		Logic:       "and",
+		Status:      "active",
+		Extractions: []model.ExtractionRule{{Source: model.ExtractionSourceRegex, Field: "subject", Pattern: `host=(?P<host>\w+)`}},
+	}
+	condition := func(keywords string) []*model.MatchCondition {
+		return []*model.MatchCondition{{FieldType: "subject", MatchType: "contains", Keywords: keywords, KeywordLogic: "or", Status: "active"}}
+	}
+	if err := service.ProcessRuleGroupWithConditions(&RuleGroupData{RuleGroup: ruleGroup, Conditions: condition("disk")}); err != nil {
+		t.Fatalf("创建规则组失败: %v", err)
+	}
+	if result := matchOne(t, engine, emailData, ruleGroup); !result.Matched || result.Vars["host"] != "web1" {
+		t.Fatalf("初次匹配结果 matched=%v vars=%v，期望匹配并提取 host=web1", result.Matched, result.Vars)
+	}
+
+	// 修改变量提取规则
+	ruleGroup.Extractions = []model.ExtractionRule{{Source: model.ExtractionSourceRegex, Field: "subject", Pattern: `host=(?P<server>\w+)`}}
+	if err := service.UpdateRuleGroup(ruleGroup); err != nil {
+		t.Fatalf("更新规则组失败: %v", err)
+	}
+	if result := matchOne(t, engine, emailData, ruleGroup); result.Vars["server"] != "web1" || result.Vars["host"] != "" {
+		t.Fatalf("更新规则组后提取的变量为 %v，期望 server=web1", result.Vars)
+	}
+
+	// 修改条件
+	if err := service.ProcessRuleGroupWithConditions(&RuleGroupData{RuleGroup: ruleGroup, Conditions: condition("cpu")}); err != nil {
+		t.Fatalf("更新条件失败: %v", err)
+	}
+	if result := matchOne(t, engine, emailData, ruleGroup); result.Matched {
+		t.Fatal("条件改为cpu后不应匹配")
+	}
+	if err := service.ProcessRuleGroupWithConditions(&RuleGroupData{RuleGroup: ruleGroup, Conditions: condition("usage")}); err != nil {
+		t.Fatalf("更新条件失败: %v", err)
+	}
+	if result := matchOne(t, engine, emailData, ruleGroup); !result.Matched {
+		t.Fatalf("条件改为usage后应匹配: %s", result.Reason)
+	}
+
+	// 删除规则组后条件随之删除
+	if err := service.DeleteRuleGroup(ruleGroup.ID); err != nil {
+		t.Fatalf("删除规则组失败: %v", err)
+	}
+	if result := matchOne(t, engine, emailData, ruleGroup); result.Matched {
+		t.Fatal("删除规则组后不应使用缓存的条件匹配")
+	}
+}
+
+// TestRuleCachePutAfterInvalidate 加载期间缓存被失效时放弃保存，避免旧的编译结果覆盖变更
+func TestRuleCachePutAfterInvalidate(t *testing.T) {
+	stale := &compiledRuleGroup{}
+	fresh := &compiledRuleGroup{}
+
+	for _, invalidated := range []uint{1, 2, 0} {
+		cache := newRuleCache()
+		if _, _, ok := cache.get(1); ok {
+			t.Fatal("空缓存不应命中")
+		}
+		_, generation, _ := cache.get(1)
+		cache.invalidate(invalidated)
+		cache.put(1, stale, generation)
+		if _, _, ok := cache.get(1); ok {
+			t.Fatalf("失效规则组 %d 后不应保存失效前加载的编译结果", invalidated)
+		}
+
+		_, generation, _ = cache.get(1)
+		cache.put(1, fresh, generation)
+		if compiled, _, ok := cache.get(1); !ok || compiled != fresh {
+			t.Fatalf("失效规则组 %d 后重新加载的编译结果应被缓存", invalidated)
+		}
+	}
+}
+
+// TestContainsMatcherEquivalence contains/notContains的多关键词自动机与逐个strings.Contains的结果一致
+func TestContainsMatcherEquivalence(t *testing.T) {
+	words := []string{"he", "she", "his", "hers", "Disk", "disk usage", "usage", "磁盘", "磁盘使用率", "率", "ERROR", "error:", "a", "aa", "aaa"}
+	contents := []string{
+		"", "ushers", "She said HIS disk usage was high", "磁盘使用率超过95%", "aaaa", "error: ERROR", "no match here",
+	}
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 200; i++ {
+		var content strings.Builder
+		for j := rng.Intn(8); j >= 0; j-- {
+			content.WriteString(words[rng.Intn(len(words))])
+			content.WriteString([]string{"", " ", "-", "x"}[rng.Intn(4)])
+		}
+		contents = append(contents, content.String())
+	}
+
+	for i := 0; i < 200; i++ {
+		keywords := make([]string, 1+rng.Intn(5))
+		for j := range keywords {
+			keywords[j] = words[rng.Intn(len(words))]
+		}
+		for _, matchType := range []string{"contains", "notContains"} {
+			compiled := compileCondition(&model.MatchCondition{FieldType: "subject", MatchType: matchType, Keywords: strings.Join(keywords, ","), Status: "active"})
+			for _, content := range contents {
+				lower := strings.ToLower(content)
+				var want []string
+				for _, keyword := range keywords {
+					if strings.Contains(lower, strings.ToLower(keyword)) == (matchType == "contains") {
+						want = append(want, keyword)
+					}
+				}
+				got := compiled.matchKeywords(content, func() string { return lower })
+				if strings.Join(got, ",") != strings.Join(want, ",") {
+					t.Fatalf("%s %q 匹配 %q: 自动机结果 %q，strings.Contains结果 %q", matchType, keywords, content, got, want)
+				}
+			}
+		}
+	}
+}
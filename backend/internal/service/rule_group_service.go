@@ -3,8 +3,10 @@ package service
 import (
 	"emailAlert/internal/model"
 	"emailAlert/internal/repository"
+	"emailAlert/pkg/email"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // RuleGroupService 规则组服务接口
@@ -20,6 +22,7 @@ type RuleGroupService interface {
 	ValidateRuleGroup(ruleGroup *model.RuleGroup) error
 	GetRuleGroupWithConditions(id uint) (*model.RuleGroup, error)
 	ProcessRuleGroupWithConditions(ruleGroupData *RuleGroupData) error
+	TestRuleGroup(ruleGroupData *RuleGroupData, emailData *model.EmailData) (*RuleGroupMatchResult, error)
 }
 
 // ruleGroupService 规则组服务实现
@@ -28,6 +31,7 @@ type ruleGroupService struct {
 	conditionRepo        repository.MatchConditionRepository
 	ruleGroupChannelRepo repository.RuleGroupChannelRepository
 	mailboxRepo          *repository.MailboxRepository
+	ruleEngine           EnhancedRuleEngineService
 }
 
 // RuleGroupData 规则组数据结构（包含条件和通知渠道）
@@ -43,12 +47,14 @@ func NewRuleGroupService(
 	conditionRepo repository.MatchConditionRepository,
 	ruleGroupChannelRepo repository.RuleGroupChannelRepository,
 	mailboxRepo *repository.MailboxRepository,
+	ruleEngine EnhancedRuleEngineService,
 ) RuleGroupService {
 	return &ruleGroupService{
 		ruleGroupRepo:        ruleGroupRepo,
 		conditionRepo:        conditionRepo,
 		ruleGroupChannelRepo: ruleGroupChannelRepo,
 		mailboxRepo:          mailboxRepo,
+		ruleEngine:           ruleEngine,
 	}
 }
 
@@ -65,6 +71,9 @@ func (s *ruleGroupService) CreateRuleGroup(ruleGroup *model.RuleGroup) error {
 		return errors.New("关联的邮箱不存在")
 	}
 
+	// 条件树引用匹配条件，只能随条件一起通过with-conditions接口配置
+	ruleGroup.ConditionTree = nil
+
 	return s.ruleGroupRepo.Create(ruleGroup)
 }
 
@@ -104,9 +113,12 @@ func (s *ruleGroupService) UpdateRuleGroup(ruleGroup *model.RuleGroup) error {
 		return errors.New("关联的邮箱不存在")
 	}
 
-	// 保留创建时间
+	// 保留创建时间和条件树（条件树随条件一起通过with-conditions接口维护）
 	ruleGroup.CreatedAt = existingRuleGroup.CreatedAt
+	ruleGroup.ConditionTree = existingRuleGroup.ConditionTree
 
+	// 变量提取规则随规则组一起缓存
+	defer s.ruleEngine.InvalidateRuleCache(ruleGroup.ID)
 	return s.ruleGroupRepo.Update(ruleGroup)
 }
 
@@ -145,6 +157,7 @@ func (s *ruleGroupService) DeleteRuleGroup(id uint) error {
 	}
 
 	// 删除规则组相关的所有条件
+	defer s.ruleEngine.InvalidateRuleCache(id)
 	if err := s.conditionRepo.DeleteByRuleGroupID(id); err != nil {
 		return fmt.Errorf("删除规则组条件失败: %v", err)
 	}
@@ -196,6 +209,17 @@ func (s *ruleGroupService) ValidateRuleGroup(ruleGroup *model.RuleGroup) error {
 		ruleGroup.Priority = 1 // 设置默认优先级
 	}
 
+	// 验证处理后动作
+	ruleGroup.Actions = strings.TrimSpace(ruleGroup.Actions)
+	if err := email.ValidateActions(ruleGroup.Actions); err != nil {
+		return fmt.Errorf("处理后动作配置无效: %v", err)
+	}
+
+	// 验证变量提取规则
+	if err := ValidateExtractionRules(ruleGroup.Extractions); err != nil {
+		return fmt.Errorf("变量提取规则无效: %v", err)
+	}
+
 	return nil
 }
 
@@ -231,6 +255,20 @@ func (s *ruleGroupService) ProcessRuleGroupWithConditions(ruleGroupData *RuleGro
 		return err
 	}
 
+	// 验证匹配条件和条件树，条件树待条件创建后再转换为条件ID保存
+	if err := validateConditionsAndTree(ruleGroupData); err != nil {
+		return err
+	}
+	tree := ruleGroupData.RuleGroup.ConditionTree
+	ruleGroupData.RuleGroup.ConditionTree = nil
+
+	// 条件会被重建，无论成功与否都失效规则引擎的编译缓存（新建时ID在创建后才确定）
+	defer func() {
+		if ruleGroupData.RuleGroup.ID != 0 {
+			s.ruleEngine.InvalidateRuleCache(ruleGroupData.RuleGroup.ID)
+		}
+	}()
+
 	// 如果是新建规则组
 	if ruleGroupData.RuleGroup.ID == 0 {
 		// 创建规则组
@@ -250,6 +288,11 @@ func (s *ruleGroupService) ProcessRuleGroupWithConditions(ruleGroupData *RuleGro
 			}
 		}
 
+		// 保存条件树
+		if err := s.saveConditionTree(ruleGroupData, tree); err != nil {
+			return err
+		}
+
 		// 创建通知渠道关联
 		if len(ruleGroupData.ChannelIDs) > 0 {
 			if err := s.ruleGroupChannelRepo.BatchCreate(ruleGroupData.RuleGroup.ID, ruleGroupData.ChannelIDs, 1); err != nil {
@@ -280,6 +323,11 @@ func (s *ruleGroupService) ProcessRuleGroupWithConditions(ruleGroupData *RuleGro
 			}
 		}
 
+		// 保存条件树
+		if err := s.saveConditionTree(ruleGroupData, tree); err != nil {
+			return err
+		}
+
 		// 更新通知渠道关联
 		// 先删除原有关联
 		if err := s.ruleGroupChannelRepo.DeleteByRuleGroupID(ruleGroupData.RuleGroup.ID); err != nil {
@@ -297,6 +345,168 @@ func (s *ruleGroupService) ProcessRuleGroupWithConditions(ruleGroupData *RuleGro
 	return nil
 }
 
+// TestRuleGroup 使用测试邮件试运行规则组（无需保存），配置错误（如表达式无法编译）时返回错误
+func (s *ruleGroupService) TestRuleGroup(ruleGroupData *RuleGroupData, emailData *model.EmailData) (*RuleGroupMatchResult, error) {
+	if ruleGroupData.RuleGroup == nil {
+		ruleGroupData.RuleGroup = &model.RuleGroup{}
+	}
+	ruleGroup := ruleGroupData.RuleGroup
+
+	if ruleGroup.Logic == "" {
+		ruleGroup.Logic = "and"
+	} else if !contains([]string{"and", "or"}, ruleGroup.Logic) {
+		return nil, errors.New("无效的逻辑类型")
+	}
+
+	if err := validateConditionsAndTree(ruleGroupData); err != nil {
+		return nil, err
+	}
+
+	// 未保存的条件没有ID，按下标分配临时ID（下标+1）供条件树引用
+	for i, condition := range ruleGroupData.Conditions {
+		condition.ID = uint(i + 1)
+		if condition.Status == "" {
+			condition.Status = "active"
+		}
+	}
+	if ruleGroup.ConditionTree != nil {
+		bindConditionTree(ruleGroup.ConditionTree, ruleGroupData.Conditions)
+	}
+
+	return s.ruleEngine.MatchRuleGroupWithConditions(emailData, ruleGroup, ruleGroupData.Conditions), nil
+}
+
+// validateConditionsAndTree 验证匹配条件和条件树，条件树叶子节点统一解析为条件下标
+func validateConditionsAndTree(ruleGroupData *RuleGroupData) error {
+	for _, condition := range ruleGroupData.Conditions {
+		if err := validateCondition(condition); err != nil {
+			return err
+		}
+	}
+
+	if tree := ruleGroupData.RuleGroup.ConditionTree; tree != nil {
+		if err := resolveConditionTree(tree, ruleGroupData.Conditions, 1); err != nil {
+			return fmt.Errorf("条件树无效: %v", err)
+		}
+	}
+	return nil
+}
+
+// saveConditionTree 将条件树叶子节点的条件下标转换为新创建的条件ID后保存
+func (s *ruleGroupService) saveConditionTree(ruleGroupData *RuleGroupData, tree *model.ConditionNode) error {
+	if tree != nil {
+		bindConditionTree(tree, ruleGroupData.Conditions)
+	}
+	if err := s.ruleGroupRepo.UpdateConditionTree(ruleGroupData.RuleGroup.ID, tree); err != nil {
+		return fmt.Errorf("保存条件树失败: %v", err)
+	}
+	ruleGroupData.RuleGroup.ConditionTree = tree
+	return nil
+}
+
+// maxConditionTreeDepth 条件树最大嵌套深度
+const maxConditionTreeDepth = 16
+
+// resolveConditionTree 校验条件树结构，并将叶子节点的条件引用解析为conditions中的下标
+// 叶子节点可以用condition_index引用下标，也可以用condition_id引用已有条件（编辑时回传的条件ID）
+func resolveConditionTree(node *model.ConditionNode, conditions []*model.MatchCondition, depth int) error {
+	if depth > maxConditionTreeDepth {
+		return fmt.Errorf("嵌套层级不能超过 %d 层", maxConditionTreeDepth)
+	}
+
+	node.Op = strings.ToLower(strings.TrimSpace(node.Op))
+	switch node.Op {
+	case "":
+		if len(node.Children) > 0 {
+			return errors.New("条件节点不能包含子节点")
+		}
+		return resolveConditionLeaf(node, conditions)
+	case model.ConditionOpAnd, model.ConditionOpOr:
+		if len(node.Children) == 0 {
+			return fmt.Errorf("%s节点至少需要一个子节点", strings.ToUpper(node.Op))
+		}
+	case model.ConditionOpNot:
+		if len(node.Children) != 1 {
+			return errors.New("NOT节点必须且只能有一个子节点")
+		}
+	default:
+		return fmt.Errorf("不支持的运算符: %s", node.Op)
+	}
+
+	if node.ConditionID != 0 || node.ConditionIndex != nil {
+		return fmt.Errorf("%s节点不能引用条件", strings.ToUpper(node.Op))
+	}
+	for _, child := range node.Children {
+		if child == nil {
+			return errors.New("子节点不能为空")
+		}
+		if err := resolveConditionTree(child, conditions, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveConditionLeaf 将叶子节点的条件引用解析为conditions中的下标
+func resolveConditionLeaf(node *model.ConditionNode, conditions []*model.MatchCondition) error {
+	if node.ConditionIndex != nil {
+		if *node.ConditionIndex < 0 || *node.ConditionIndex >= len(conditions) {
+			return fmt.Errorf("条件下标 %d 超出范围", *node.ConditionIndex)
+		}
+		node.ConditionID = 0
+		return nil
+	}
+
+	if node.ConditionID == 0 {
+		return errors.New("条件节点必须通过condition_index或condition_id引用一个匹配条件")
+	}
+	for i, condition := range conditions {
+		if condition.ID == node.ConditionID {
+			index := i
+			node.ConditionIndex = &index
+			node.ConditionID = 0
+			return nil
+		}
+	}
+	return fmt.Errorf("引用的条件 %d 不在本次提交的条件列表中", node.ConditionID)
+}
+
+// bindConditionTree 将叶子节点的条件下标替换为条件ID
+func bindConditionTree(node *model.ConditionNode, conditions []*model.MatchCondition) {
+	if node.IsLeaf() {
+		node.ConditionID = conditions[*node.ConditionIndex].ID
+		node.ConditionIndex = nil
+		return
+	}
+	for _, child := range node.Children {
+		bindConditionTree(child, conditions)
+	}
+}
+
+// validateCondition 验证匹配条件
+func validateCondition(condition *model.MatchCondition) error {
+	if condition.MatchType == "expression" {
+		condition.Keywords = strings.TrimSpace(condition.Keywords)
+		if _, err := CompileExpression(condition.Keywords); err != nil {
+			return err
+		}
+		return nil
+	}
+	if condition.FieldType == "header" {
+		condition.HeaderName = strings.TrimSpace(condition.HeaderName)
+		if condition.HeaderName == "" {
+			return errors.New("匹配字段为邮件头时必须指定邮件头名称")
+		}
+	}
+	if isNumericMatchType(condition.MatchType) && condition.FieldType != "attachment_size" {
+		condition.ValuePattern = strings.TrimSpace(condition.ValuePattern)
+		if err := compileCondition(condition).err; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // contains 检查字符串切片是否包含指定值
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"emailAlert/config"
+	"emailAlert/internal/model"
+	"emailAlert/internal/repository"
+	"emailAlert/pkg/email"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 平台告警类别
+const (
+	SelfAlertMailboxAuth     = "mailbox_auth"     // 邮箱认证失败或已自动暂停
+	SelfAlertMailboxStall    = "mailbox_stall"    // 邮箱长时间无法完成检查
+	SelfAlertChannelFailure  = "channel_failure"  // 通知渠道持续发送失败
+	SelfAlertQueueSaturation = "queue_saturation" // 告警分发队列接近饱和
+	SelfAlertDatabase        = "database"         // 数据库不可用
+)
+
+// minChannelFailures 判定渠道持续失败所需的最少连续失败次数
+const minChannelFailures = 3
+
+// SelfAlertIssue 平台自身的异常
+type SelfAlertIssue struct {
+	Key          string    `json:"key"`      // 去重键，同一问题持续存在时只按重复间隔通知
+	Category     string    `json:"category"` // 告警类别
+	Title        string    `json:"title"`
+	Message      string    `json:"message"`
+	FirstSeen    time.Time `json:"first_seen"`    // 首次发现时间
+	LastSeen     time.Time `json:"last_seen"`     // 最近一次检查仍存在的时间
+	LastNotified time.Time `json:"last_notified"` // 最近一次发送通知的时间
+	NotifyCount  int       `json:"notify_count"`  // 已发送通知次数
+}
+
+// SelfMonitorService 平台自监控服务
+// 定期检查邮箱认证、监控停滞、渠道连续失败、分发队列和数据库，
+// 通过管理员渠道发送平台告警，问题持续期间按重复间隔提醒，恢复时发送恢复通知
+type SelfMonitorService struct {
+	config              config.SelfMonitorConfig
+	db                  *gorm.DB
+	emailMonitorService *EmailMonitorService
+	dispatcher          NotificationDispatcherService
+	channelService      ChannelService
+	notificationLogRepo repository.NotificationLogRepository
+	channelIDs          []uint
+
+	mutex    sync.Mutex
+	issues   map[string]*SelfAlertIssue
+	channels map[uint]*model.Channel // 管理员渠道缓存，数据库不可用时仍可发送告警
+}
+
+// NewSelfMonitorService 创建平台自监控服务
+func NewSelfMonitorService(
+	cfg config.SelfMonitorConfig,
+	db *gorm.DB,
+	emailMonitorService *EmailMonitorService,
+	dispatcher NotificationDispatcherService,
+	channelService ChannelService,
+	notificationLogRepo repository.NotificationLogRepository,
+) (*SelfMonitorService, error) {
+	var channelIDs []uint
+	for _, item := range strings.Split(cfg.Channels, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(item, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("无效的管理员渠道ID: %s", item)
+		}
+		channelIDs = append(channelIDs, uint(id))
+	}
+
+	return &SelfMonitorService{
+		config:              cfg,
+		db:                  db,
+		emailMonitorService: emailMonitorService,
+		dispatcher:          dispatcher,
+		channelService:      channelService,
+		notificationLogRepo: notificationLogRepo,
+		channelIDs:          channelIDs,
+		issues:              make(map[string]*SelfAlertIssue),
+		channels:            make(map[uint]*model.Channel),
+	}, nil
+}
+
+// Enabled 是否配置了管理员渠道
+func (s *SelfMonitorService) Enabled() bool {
+	return len(s.channelIDs) > 0
+}
+
+// Start 启动后台检查，ctx取消时退出
+func (s *SelfMonitorService) Start(ctx context.Context) {
+	if !s.Enabled() {
+		return
+	}
+
+	interval := time.Duration(s.config.CheckInterval) * time.Second
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	log.Printf("平台自监控已启动，检查间隔 %v，管理员渠道 %v", interval, s.channelIDs)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Check()
+			}
+		}
+	}()
+}
+
+// GetIssues 获取当前存在的平台异常，按首次发现时间排序
+func (s *SelfMonitorService) GetIssues() []SelfAlertIssue {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	issues := make([]SelfAlertIssue, 0, len(s.issues))
+	for _, issue := range s.issues {
+		issues = append(issues, *issue)
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].FirstSeen.Before(issues[j].FirstSeen) })
+	return issues
+}
+
+// Check 执行一次检查，并对新出现、持续存在和已恢复的问题发送通知
+func (s *SelfMonitorService) Check() {
+	now := time.Now()
+
+	var found []SelfAlertIssue
+	dbErr := s.checkDatabase()
+	if dbErr != nil {
+		found = append(found, SelfAlertIssue{
+			Key:      SelfAlertDatabase,
+			Category: SelfAlertDatabase,
+			Title:    "数据库不可用",
+			Message:  fmt.Sprintf("数据库连接检查失败: %v", dbErr),
+		})
+	} else {
+		s.refreshChannels()
+		found = append(found, s.checkChannels(now)...)
+	}
+	found = append(found, s.checkMailboxes(now)...)
+	found = append(found, s.checkQueue()...)
+
+	s.reconcile(now, found)
+}
+
+// checkDatabase 检查数据库连接
+func (s *SelfMonitorService) checkDatabase() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// checkMailboxes 检查邮箱认证失败和监控停滞
+func (s *SelfMonitorService) checkMailboxes(now time.Time) []SelfAlertIssue {
+	if s.emailMonitorService == nil {
+		return nil
+	}
+
+	stallThreshold := time.Duration(s.config.StallThreshold) * time.Minute
+	var issues []SelfAlertIssue
+	for _, health := range s.emailMonitorService.GetMailboxHealth() {
+		switch {
+		case health.State == email.HealthStateSuspended:
+			issues = append(issues, SelfAlertIssue{
+				Key:      fmt.Sprintf("%s:%d", SelfAlertMailboxAuth, health.MailboxID),
+				Category: SelfAlertMailboxAuth,
+				Title:    fmt.Sprintf("邮箱 %s 已自动暂停监控", health.Name),
+				Message:  fmt.Sprintf("连续 %d 次认证失败，已停止监控，请检查账号密码或授权码后重新启用。最近错误: %s", health.AuthFailures, health.LastError),
+			})
+		case health.AuthFailures > 0:
+			issues = append(issues, SelfAlertIssue{
+				Key:      fmt.Sprintf("%s:%d", SelfAlertMailboxAuth, health.MailboxID),
+				Category: SelfAlertMailboxAuth,
+				Title:    fmt.Sprintf("邮箱 %s 登录失败", health.Name),
+				Message:  fmt.Sprintf("连续 %d 次认证失败，达到上限后将自动暂停监控。最近错误: %s", health.AuthFailures, health.LastError),
+			})
+		case health.State == email.HealthStateFailing && health.FailingSince != nil &&
+			stallThreshold > 0 && now.Sub(*health.FailingSince) >= stallThreshold:
+			lastSuccess := "从未成功"
+			if health.LastSuccess != nil {
+				lastSuccess = health.LastSuccess.Format("2006-01-02 15:04:05")
+			}
+			issues = append(issues, SelfAlertIssue{
+				Key:      fmt.Sprintf("%s:%d", SelfAlertMailboxStall, health.MailboxID),
+				Category: SelfAlertMailboxStall,
+				Title:    fmt.Sprintf("邮箱 %s 监控停滞", health.Name),
+				Message: fmt.Sprintf("已连续失败 %v（%d 次），最近成功检查: %s，期间的新邮件不会产生告警。最近错误: %s",
+					now.Sub(*health.FailingSince).Round(time.Minute), health.ConsecutiveFailures, lastSuccess, health.LastError),
+			})
+		}
+	}
+	return issues
+}
+
+// checkChannels 检查通知渠道是否在一段时间内持续发送失败
+func (s *SelfMonitorService) checkChannels(now time.Time) []SelfAlertIssue {
+	window := time.Duration(s.config.ChannelFailureWindow) * time.Minute
+	if window <= 0 {
+		return nil
+	}
+
+	// 多查询一个窗口，用于判断失败是否已持续超过窗口时长
+	logs, err := s.notificationLogRepo.GetRecentResults(now.Add(-2 * window))
+	if err != nil {
+		log.Printf("平台自监控: 查询通知日志失败: %v", err)
+		return nil
+	}
+
+	type streak struct {
+		count     int
+		since     time.Time
+		lastError string
+		done      bool // 已遇到成功记录，连续失败统计结束
+	}
+	streaks := make(map[uint]*streak)
+	var order []uint
+	// 日志按更新时间倒序，从最新一条开始统计每个渠道的连续失败
+	for _, entry := range logs {
+		st, ok := streaks[entry.ChannelID]
+		if !ok {
+			st = &streak{}
+			streaks[entry.ChannelID] = st
+			order = append(order, entry.ChannelID)
+		}
+		if st.done {
+			continue
+		}
+		if entry.Status != "failed" {
+			st.done = true
+			continue
+		}
+		st.count++
+		st.since = entry.UpdatedAt
+		if st.lastError == "" {
+			st.lastError = entry.ErrorMsg
+		}
+	}
+
+	var issues []SelfAlertIssue
+	for _, channelID := range order {
+		st := streaks[channelID]
+		if st.count < minChannelFailures || now.Sub(st.since) < window {
+			continue
+		}
+
+		name := fmt.Sprintf("ID %d", channelID)
+		if channel, err := s.channelService.GetChannel(channelID); err == nil {
+			name = channel.Name
+		}
+		issues = append(issues, SelfAlertIssue{
+			Key:      fmt.Sprintf("%s:%d", SelfAlertChannelFailure, channelID),
+			Category: SelfAlertChannelFailure,
+			Title:    fmt.Sprintf("通知渠道 %s 持续发送失败", name),
+			Message: fmt.Sprintf("自 %s 起连续 %d 次发送失败，该渠道的告警通知未能送达。最近错误: %s",
+				st.since.Format("2006-01-02 15:04:05"), st.count, st.lastError),
+		})
+	}
+	return issues
+}
+
+// checkQueue 检查告警分发队列是否接近饱和
+func (s *SelfMonitorService) checkQueue() []SelfAlertIssue {
+	length, capacity := s.dispatcher.GetQueueStatus()
+	threshold := s.config.QueueThresholdPercent
+	if capacity == 0 || threshold <= 0 || length*100 < capacity*threshold {
+		return nil
+	}
+
+	return []SelfAlertIssue{{
+		Key:      SelfAlertQueueSaturation,
+		Category: SelfAlertQueueSaturation,
+		Title:    "告警分发队列接近饱和",
+		Message:  fmt.Sprintf("队列长度 %d/%d，告警通知可能延迟，队列满时将在邮件处理流程中同步发送", length, capacity),
+	}}
+}
+
+// reconcile 对比本次检查结果与已记录的问题，发送新问题、重复提醒和恢复通知
+func (s *SelfMonitorService) reconcile(now time.Time, found []SelfAlertIssue) {
+	repeat := time.Duration(s.config.RepeatInterval) * time.Minute
+
+	s.mutex.Lock()
+	var notify, resolved []SelfAlertIssue
+	seen := make(map[string]bool)
+	for _, issue := range found {
+		seen[issue.Key] = true
+
+		existing, ok := s.issues[issue.Key]
+		if !ok {
+			issue.FirstSeen = now
+			issue.LastSeen = now
+			issue.LastNotified = now
+			issue.NotifyCount = 1
+			s.issues[issue.Key] = &issue
+			notify = append(notify, issue)
+			continue
+		}
+
+		existing.Title = issue.Title
+		existing.Message = issue.Message
+		existing.LastSeen = now
+		if repeat > 0 && now.Sub(existing.LastNotified) >= repeat {
+			existing.LastNotified = now
+			existing.NotifyCount++
+			notify = append(notify, *existing)
+		}
+	}
+	for key, issue := range s.issues {
+		if !seen[key] {
+			resolved = append(resolved, *issue)
+			delete(s.issues, key)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, issue := range notify {
+		title := "【平台告警】" + issue.Title
+		content := fmt.Sprintf("%s\n\n首次发现: %s\n检查时间: %s",
+			issue.Message, issue.FirstSeen.Format("2006-01-02 15:04:05"), now.Format("2006-01-02 15:04:05"))
+		if issue.NotifyCount > 1 {
+			content += fmt.Sprintf("\n持续时间: %v（第 %d 次提醒）", now.Sub(issue.FirstSeen).Round(time.Minute), issue.NotifyCount)
+		}
+		s.notify(title, content)
+	}
+	for _, issue := range resolved {
+		title := "【平台恢复】" + issue.Title
+		content := fmt.Sprintf("问题已恢复\n\n首次发现: %s\n恢复时间: %s\n持续时间: %v",
+			issue.FirstSeen.Format("2006-01-02 15:04:05"), now.Format("2006-01-02 15:04:05"), now.Sub(issue.FirstSeen).Round(time.Minute))
+		s.notify(title, content)
+	}
+}
+
+// refreshChannels 从数据库刷新管理员渠道缓存，读取失败时保留原有缓存
+func (s *SelfMonitorService) refreshChannels() {
+	for _, id := range s.channelIDs {
+		channel, err := s.channelService.GetChannel(id)
+		if err != nil {
+			log.Printf("平台自监控: 加载管理员渠道 %d 失败: %v", id, err)
+			continue
+		}
+		s.mutex.Lock()
+		s.channels[id] = channel
+		s.mutex.Unlock()
+	}
+}
+
+// notify 通过通知分发服务向全部管理员渠道发送平台告警，失败的通知由分发服务重试
+func (s *SelfMonitorService) notify(title, content string) {
+	s.mutex.Lock()
+	channels := make([]*model.Channel, 0, len(s.channels))
+	for _, id := range s.channelIDs {
+		if channel, ok := s.channels[id]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	s.mutex.Unlock()
+
+	if len(channels) == 0 {
+		log.Printf("平台自监控: 没有可用的管理员渠道，未发送: %s", title)
+		return
+	}
+
+	for _, channel := range channels {
+		if err := s.dispatcher.DispatchAdminNotification(channel, title, content); err != nil {
+			log.Printf("平台自监控: 通过渠道 %s 发送平台告警失败: %v", channel.Name, err)
+		} else {
+			log.Printf("平台自监控: 已通过渠道 %s 发送: %s", channel.Name, title)
+		}
+	}
+}
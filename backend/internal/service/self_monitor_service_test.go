@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"emailAlert/config"
+	"emailAlert/internal/model"
+	"emailAlert/internal/repository"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// fakeChannelService 记录发送内容的渠道服务，fail为true时发送失败
+type fakeChannelService struct {
+	ChannelService
+	mutex    sync.Mutex
+	channels map[uint]*model.Channel
+	fail     bool
+	sent     []string
+}
+
+func (f *fakeChannelService) GetChannel(id uint) (*model.Channel, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	channel, ok := f.channels[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return channel, nil
+}
+
+func (f *fakeChannelService) SendNotification(channelID uint, title, content string) error {
+	channel, err := f.GetChannel(channelID)
+	if err != nil {
+		return err
+	}
+	return f.SendNotificationToChannel(channel, title, content)
+}
+
+func (f *fakeChannelService) SendNotificationToChannel(channel *model.Channel, title, content string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if f.fail {
+		return errors.New("渠道不可用")
+	}
+	f.sent = append(f.sent, title)
+	return nil
+}
+
+// sentTitles 返回已发送的标题
+func (f *fakeChannelService) sentTitles() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+// setupSelfMonitorTest 创建临时数据库、管理员渠道和通知分发服务
+func setupSelfMonitorTest(t *testing.T) (*gorm.DB, *fakeChannelService, NotificationDispatcherService, repository.NotificationLogRepository) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Channel{}, &model.Template{}, &model.Alert{}, &model.AlertLabel{}, &model.NotificationLog{}); err != nil {
+		t.Fatalf("数据库迁移失败: %v", err)
+	}
+
+	channel := &model.Channel{Name: "admin", Type: "webhook", Config: "{}", Status: "active"}
+	if err := db.Create(channel).Error; err != nil {
+		t.Fatalf("创建渠道失败: %v", err)
+	}
+
+	channels := &fakeChannelService{channels: map[uint]*model.Channel{channel.ID: channel}}
+	notificationLogRepo := repository.NewNotificationLogRepository(db)
+	dispatcher := NewNotificationDispatcherService(
+		repository.NewRuleChannelRepository(db),
+		repository.NewRuleGroupChannelRepository(db),
+		notificationLogRepo,
+		repository.NewAlertRepository(db),
+		channels,
+		NewTemplateService(repository.NewTemplateRepository(db)),
+	)
+	return db, channels, dispatcher, notificationLogRepo
+}
+
+// TestSelfMonitorQueueDrained 队列被后台处理器消费到阈值以下后，饱和告警恢复且不再产生
+func TestSelfMonitorQueueDrained(t *testing.T) {
+	db, channels, dispatcher, notificationLogRepo := setupSelfMonitorTest(t)
+	monitor, err := NewSelfMonitorService(config.SelfMonitorConfig{
+		Channels:              "1",
+		QueueThresholdPercent: 80,
+	}, db, nil, dispatcher, channels, notificationLogRepo)
+	if err != nil {
+		t.Fatalf("创建自监控服务失败: %v", err)
+	}
+
+	// 没有规则组的告警不需要发送，只用于占满队列
+	_, capacity := dispatcher.GetQueueStatus()
+	for i := 0; i < capacity; i++ {
+		if err := dispatcher.DispatchAlert(&model.Alert{}); err != nil {
+			t.Fatalf("分发告警失败: %v", err)
+		}
+	}
+
+	monitor.Check()
+	issues := monitor.GetIssues()
+	if len(issues) != 1 || issues[0].Category != SelfAlertQueueSaturation {
+		t.Fatalf("队列已满时应产生饱和告警，实际为 %+v", issues)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := dispatcher.StartBackgroundProcessor(ctx); err != nil {
+		t.Fatalf("启动后台处理器失败: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if length, _ := dispatcher.GetQueueStatus(); length == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("后台处理器没有消费分发队列")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	monitor.Check()
+	if issues := monitor.GetIssues(); len(issues) != 0 {
+		t.Fatalf("队列低于阈值时不应存在平台告警，实际为 %+v", issues)
+	}
+
+	titles := channels.sentTitles()
+	if len(titles) != 2 || !strings.HasPrefix(titles[0], "【平台告警】") || !strings.HasPrefix(titles[1], "【平台恢复】") {
+		t.Fatalf("应依次发送平台告警和恢复通知，实际为 %v", titles)
+	}
+}
+
+// TestDispatchAdminNotificationRetry 平台告警发送失败时记录通知日志，并由重试流程重新发送
+func TestDispatchAdminNotificationRetry(t *testing.T) {
+	_, channels, dispatcher, notificationLogRepo := setupSelfMonitorTest(t)
+	channel, _ := channels.GetChannel(1)
+
+	channels.fail = true
+	if err := dispatcher.DispatchAdminNotification(channel, "【平台告警】数据库不可用", "数据库连接检查失败"); err == nil {
+		t.Fatal("渠道发送失败时应返回错误")
+	}
+
+	failed, err := notificationLogRepo.GetFailedLogs(3)
+	if err != nil {
+		t.Fatalf("查询失败通知失败: %v", err)
+	}
+	if len(failed) != 1 || failed[0].ChannelID != channel.ID || !strings.Contains(failed[0].Content, "数据库连接检查失败") {
+		t.Fatalf("应记录一条包含告警内容的失败通知日志，实际为 %+v", failed)
+	}
+
+	channels.fail = false
+	if err := dispatcher.RetryFailedNotifications(); err != nil {
+		t.Fatalf("重试失败通知失败: %v", err)
+	}
+	entry, err := notificationLogRepo.GetByID(failed[0].ID)
+	if err != nil {
+		t.Fatalf("查询通知日志失败: %v", err)
+	}
+	if entry.Status != "success" || entry.RetryCount != 1 {
+		t.Fatalf("重试后通知日志应为成功且重试1次，实际为 %s/%d", entry.Status, entry.RetryCount)
+	}
+	if titles := channels.sentTitles(); len(titles) != 1 {
+		t.Fatalf("重试应发送一次，实际为 %v", titles)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"crypto/tls"
+	"emailAlert/config"
+	"emailAlert/internal/repository"
+	"emailAlert/pkg/email"
+	"emailAlert/pkg/smtpd"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// SMTPReceiverService 内置SMTP/LMTP接收服务，将推送的邮件投递到对应的推送邮箱
+type SMTPReceiverService struct {
+	config              config.ReceiverConfig
+	mailboxRepo         *repository.MailboxRepository
+	emailMonitorService *EmailMonitorService
+
+	mutex   sync.Mutex
+	servers []*smtpd.Server
+}
+
+// NewSMTPReceiverService 创建接收服务
+func NewSMTPReceiverService(cfg config.ReceiverConfig, mailboxRepo *repository.MailboxRepository, emailMonitorService *EmailMonitorService) *SMTPReceiverService {
+	return &SMTPReceiverService{
+		config:              cfg,
+		mailboxRepo:         mailboxRepo,
+		emailMonitorService: emailMonitorService,
+	}
+}
+
+// Start 按配置启动SMTP和LMTP监听
+func (s *SMTPReceiverService) Start() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.servers) > 0 {
+		return fmt.Errorf("接收服务已在运行")
+	}
+	if s.config.SMTPAddr == "" && s.config.LMTPAddr == "" {
+		return fmt.Errorf("未配置SMTP或LMTP监听地址")
+	}
+
+	serverConfig, err := s.serverConfig()
+	if err != nil {
+		return err
+	}
+
+	if s.config.SMTPAddr != "" {
+		s.listen(serverConfig, s.config.SMTPAddr, false)
+	}
+	if s.config.LMTPAddr != "" {
+		s.listen(serverConfig, s.config.LMTPAddr, true)
+	}
+	return nil
+}
+
+// listen 创建接收服务并在后台监听，调用方需持有s.mutex
+func (s *SMTPReceiverService) listen(serverConfig smtpd.Config, addr string, lmtp bool) {
+	serverConfig.LMTP = lmtp
+	server := smtpd.NewServer(serverConfig, s)
+	s.servers = append(s.servers, server)
+
+	go func() {
+		if err := server.ListenAndServe(addr); err != nil {
+			log.Printf("接收服务 %s 异常退出: %v", addr, err)
+		}
+	}()
+}
+
+// Stop 停止接收服务
+func (s *SMTPReceiverService) Stop() {
+	s.mutex.Lock()
+	servers := s.servers
+	s.servers = nil
+	s.mutex.Unlock()
+
+	for _, server := range servers {
+		server.Close()
+	}
+}
+
+// serverConfig 将应用配置转换为接收服务配置
+func (s *SMTPReceiverService) serverConfig() (smtpd.Config, error) {
+	networks, err := smtpd.ParseNetworks(s.config.AllowedNetworks)
+	if err != nil {
+		return smtpd.Config{}, fmt.Errorf("允许的网段配置无效: %v", err)
+	}
+
+	serverConfig := smtpd.Config{
+		Hostname:          s.config.Hostname,
+		MaxMessageBytes:   int64(s.config.MaxMessageBytes),
+		MaxRecipients:     s.config.MaxRecipients,
+		Username:          s.config.Username,
+		Password:          s.config.Password,
+		AllowedNetworks:   networks,
+		AllowInsecureAuth: s.config.AllowInsecureAuth,
+	}
+
+	if s.config.TLSCertFile != "" || s.config.TLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(s.config.TLSCertFile, s.config.TLSKeyFile)
+		if err != nil {
+			return smtpd.Config{}, fmt.Errorf("加载TLS证书失败: %v", err)
+		}
+		serverConfig.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+	return serverConfig, nil
+}
+
+// LookupRecipient 查找收件地址对应的启用中的推送邮箱
+func (s *SMTPReceiverService) LookupRecipient(address string) (uint, error) {
+	mailbox, err := s.mailboxRepo.GetPushMailboxByEmail(strings.TrimSpace(address), email.ProtocolSMTP)
+	if err != nil {
+		return 0, fmt.Errorf("未配置该收件地址")
+	}
+	return mailbox.ID, nil
+}
+
+// Deliver 将推送的邮件交给邮件处理流程
+func (s *SMTPReceiverService) Deliver(mailboxID uint, envelope *smtpd.Envelope, data []byte) error {
+	s.emailMonitorService.addLog("info", fmt.Sprintf("接收服务收到来自 %s 的推送邮件（发件人: %s）",
+		envelope.RemoteAddr, envelope.From), mailboxID)
+
+	if _, err := s.emailMonitorService.ReceiveEmail(mailboxID, data); err != nil {
+		s.emailMonitorService.addLog("error", fmt.Sprintf("处理推送邮件失败: %v", err), mailboxID)
+		return err
+	}
+	return nil
+}
@@ -3,7 +3,10 @@ package service
 import (
 	"context"
 	"emailAlert/internal/model"
+	"emailAlert/pkg/email"
+	"emailAlert/pkg/proxy"
 	"fmt"
+	"net"
 	"runtime"
 	"time"
 
@@ -291,9 +294,23 @@ func (s *SystemStatusService) checkEmailMonitorHealth() *ServiceStatus {
 	isRunning := s.emailMonitorService.IsRunning()
 	monitorStatus := s.emailMonitorService.GetStatus()
 
-	if isRunning {
+	// 统计连接异常和自动暂停的邮箱
+	failing, suspended := 0, 0
+	for _, health := range s.emailMonitorService.GetMailboxHealth() {
+		switch health.State {
+		case email.HealthStateFailing:
+			failing++
+		case email.HealthStateSuspended:
+			suspended++
+		}
+	}
+
+	if isRunning && failing == 0 && suspended == 0 {
 		status.Status = "healthy"
 		status.Message = "邮件监控服务运行中"
+	} else if isRunning {
+		status.Status = "degraded"
+		status.Message = fmt.Sprintf("邮件监控服务运行中，%d 个邮箱连接异常，%d 个邮箱已自动暂停", failing, suspended)
 	} else {
 		status.Status = "degraded"
 		status.Message = "邮件监控服务已停止"
@@ -742,3 +759,31 @@ type CleanupResult struct {
 	Success     bool          `json:"success"`      // 是否成功
 	Error       string        `json:"error"`        // 错误信息
 }
+
+// ProxyTestResult 代理连通性测试结果
+type ProxyTestResult struct {
+	Proxy    string        `json:"proxy"`    // 实际使用的代理（已隐藏密码），为空表示直连
+	Target   string        `json:"target"`   // 目标地址
+	Duration time.Duration `json:"duration"` // 建立连接耗时
+}
+
+// TestProxy 通过代理连接目标地址，proxyURL为空时测试全局代理，为direct时测试直连
+func (s *SystemStatusService) TestProxy(proxyURL, target string) (*ProxyTestResult, error) {
+	if err := proxy.Validate(proxyURL); err != nil {
+		return nil, fmt.Errorf("代理配置无效: %v", err)
+	}
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		return nil, fmt.Errorf("目标地址格式错误，应为 host:port: %v", err)
+	}
+
+	startTime := time.Now()
+	if err := proxy.Test(proxyURL, target, 15*time.Second); err != nil {
+		return nil, err
+	}
+
+	return &ProxyTestResult{
+		Proxy:    proxy.Redact(proxy.Resolve(proxyURL)),
+		Target:   target,
+		Duration: time.Since(startTime),
+	}, nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"emailAlert/internal/model"
 	"emailAlert/internal/repository"
+	"emailAlert/pkg/timezone"
 	"errors"
 	"fmt"
 	"strings"
@@ -380,7 +381,7 @@ func (s *TemplateService) extractUsedVariables(content string) []string {
 
 // getDefaultRenderData 获取默认渲染数据
 func (s *TemplateService) getDefaultRenderData() *model.TemplateRenderData {
-	now := time.Now()
+	now := time.Now().In(timezone.System())
 	return &model.TemplateRenderData{
 		Email: &model.EmailData{
 			Subject:     "系统告警邮件",
@@ -418,13 +419,21 @@ func (s *TemplateService) getDefaultRenderData() *model.TemplateRenderData {
 			ServerName:  "localhost",
 			Environment: "development",
 		},
-		Time: model.TimeInfo{
-			Now:       now,
-			NowFormat: now.Format("2006-01-02 15:04:05"),
-			NowUnix:   now.Unix(),
-			Today:     now.Format("2006-01-02"),
-			Yesterday: now.AddDate(0, 0, -1).Format("2006-01-02"),
-		},
+		Time: newTimeInfo(now, now.Location()),
+		Vars: map[string]string{"host": "web-01"},
+	}
+}
+
+// newTimeInfo 构建指定时区下的时间信息
+func newTimeInfo(now time.Time, loc *time.Location) model.TimeInfo {
+	now = now.In(loc)
+	return model.TimeInfo{
+		Now:       now,
+		NowFormat: now.Format("2006-01-02 15:04:05"),
+		NowUnix:   now.Unix(),
+		Today:     now.Format("2006-01-02"),
+		Yesterday: now.AddDate(0, 0, -1).Format("2006-01-02"),
+		Timezone:  loc.String(),
 	}
 }
 
@@ -444,6 +453,7 @@ func (s *TemplateService) getAvailableVariables() []model.TemplateVariable {
 		{Name: ".Alert.Content", Description: "告警内容", Example: "告警详细信息", Category: "alert"},
 		{Name: ".Alert.Status", Description: "告警状态", Example: "pending", Category: "alert"},
 		{Name: ".Alert.ReceivedAt", Description: "告警时间", Example: "2024-01-01 12:00:00", Category: "alert"},
+		{Name: ".Vars.名称", Description: "规则组提取的变量（告警标签），如 .Vars.host", Example: "web-01", Category: "alert"},
 
 		// 规则变量
 		{Name: ".Rule.Name", Description: "规则名称", Example: "生产环境告警", Category: "rule"},
@@ -466,6 +476,7 @@ func (s *TemplateService) getAvailableVariables() []model.TemplateVariable {
 		{Name: ".Time.NowFormat", Description: "格式化当前时间", Example: "2024-01-01 12:00:00", Category: "time"},
 		{Name: ".Time.Today", Description: "今天日期", Example: "2024-01-01", Category: "time"},
 		{Name: ".Time.Yesterday", Description: "昨天日期", Example: "2023-12-31", Category: "time"},
+		{Name: ".Time.Timezone", Description: "展示时区（渠道未配置时为系统时区）", Example: "Asia/Shanghai", Category: "time"},
 	}
 
 	return vars
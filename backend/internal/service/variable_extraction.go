@@ -0,0 +1,224 @@
+package service
+
+import (
+	"emailAlert/internal/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxVariableValueLength 变量值的最大长度，与AlertLabel.Value的字段长度一致
+const maxVariableValueLength = 255
+
+// variableNamePattern 变量名格式，保证可以在模版中以 {{.Vars.名称}} 引用
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateExtractionRules 校验变量提取规则
+func ValidateExtractionRules(rules []model.ExtractionRule) error {
+	for i := range rules {
+		rule := &rules[i]
+		rule.Name = strings.TrimSpace(rule.Name)
+		rule.Source = strings.ToLower(strings.TrimSpace(rule.Source))
+		rule.Field = strings.TrimSpace(rule.Field)
+		rule.Pattern = strings.TrimSpace(rule.Pattern)
+
+		if rule.Pattern == "" {
+			return fmt.Errorf("第 %d 条提取规则缺少pattern", i+1)
+		}
+		if rule.Name != "" && !variableNamePattern.MatchString(rule.Name) {
+			return fmt.Errorf("变量名 %q 无效，只能包含字母、数字和下划线且不能以数字开头", rule.Name)
+		}
+
+		switch rule.Source {
+		case model.ExtractionSourceRegex:
+			regex, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return fmt.Errorf("提取规则正则表达式编译失败: %v", err)
+			}
+			named := false
+			for _, name := range regex.SubexpNames() {
+				if name == "" {
+					continue
+				}
+				if !variableNamePattern.MatchString(name) {
+					return fmt.Errorf("命名分组 %q 不能作为变量名", name)
+				}
+				named = true
+			}
+			if !named && rule.Name == "" {
+				return errors.New("正则没有命名分组时必须指定变量名")
+			}
+		case model.ExtractionSourceHeader, model.ExtractionSourceJSON:
+			if rule.Name == "" {
+				return fmt.Errorf("%s类型的提取规则必须指定变量名", rule.Source)
+			}
+		default:
+			return fmt.Errorf("不支持的提取来源: %s", rule.Source)
+		}
+	}
+	return nil
+}
+
+// compiledExtraction 预编译的变量提取规则，regex仅在regex来源时有值
+type compiledExtraction struct {
+	rule  model.ExtractionRule
+	regex *regexp.Regexp
+}
+
+// compileExtractionRules 编译规则组的变量提取规则，无法编译的规则会被跳过
+func compileExtractionRules(rules []model.ExtractionRule) []*compiledExtraction {
+	compiled := make([]*compiledExtraction, 0, len(rules))
+	for _, rule := range rules {
+		extraction := &compiledExtraction{rule: rule}
+		if rule.Source == model.ExtractionSourceRegex {
+			regex, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				continue
+			}
+			extraction.regex = regex
+		}
+		compiled = append(compiled, extraction)
+	}
+	return compiled
+}
+
+// extractVariables 按提取规则从邮件中提取变量，fields为ExtractEmailFields的结果
+// 提取失败的规则会被跳过，同名变量以后面的规则为准
+func extractVariables(emailData *model.EmailData, fields map[string]string, extractions []*compiledExtraction) map[string]string {
+	vars := make(map[string]string)
+	for _, extraction := range extractions {
+		rule := extraction.rule
+		switch rule.Source {
+		case model.ExtractionSourceRegex:
+			extractRegexVariables(extractionContent(emailData, fields, rule), extraction, vars)
+		case model.ExtractionSourceHeader:
+			values := emailData.Headers[textproto.CanonicalMIMEHeaderKey(rule.Pattern)]
+			if len(values) > 0 {
+				vars[rule.Name] = strings.Join(values, ", ")
+			}
+		case model.ExtractionSourceJSON:
+			if value, ok := extractJSONPath(extractionContent(emailData, fields, rule), rule.Pattern); ok {
+				vars[rule.Name] = value
+			}
+		}
+	}
+
+	for name, value := range vars {
+		if len(value) > maxVariableValueLength {
+			vars[name] = truncateUTF8(value, maxVariableValueLength)
+		}
+	}
+	return vars
+}
+
+// extractionContent 获取提取规则读取的字段内容，未指定字段时读取正文
+func extractionContent(emailData *model.EmailData, fields map[string]string, rule model.ExtractionRule) string {
+	field := rule.Field
+	if field == "" {
+		field = "body"
+	}
+	content, _ := conditionFieldContent(emailData, fields, &model.MatchCondition{FieldType: field})
+	return content
+}
+
+// extractRegexVariables 使用正则的第一个匹配提取变量
+// 有命名分组时每个分组对应一个变量，否则取第一个分组（没有分组时取整个匹配）作为rule.Name的值
+func extractRegexVariables(content string, extraction *compiledExtraction, vars map[string]string) {
+	regex := extraction.regex
+	match := regex.FindStringSubmatch(content)
+	if match == nil {
+		return
+	}
+
+	named := false
+	for i, name := range regex.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		named = true
+		if match[i] != "" {
+			vars[name] = match[i]
+		}
+	}
+	if named {
+		return
+	}
+
+	if len(match) > 1 {
+		vars[extraction.rule.Name] = match[1]
+	} else {
+		vars[extraction.rule.Name] = match[0]
+	}
+}
+
+// extractJSONPath 按路径读取JSON中的值，支持 $.a.b、a.b[0].c 和 a.b.0.c 形式
+// 内容不是纯JSON时尝试解析其中第一个{到最后一个}之间的部分
+func extractJSONPath(content, path string) (string, bool) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &data); err != nil {
+		start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+		if start < 0 || end <= start {
+			return "", false
+		}
+		if err := json.Unmarshal([]byte(content[start:end+1]), &data); err != nil {
+			return "", false
+		}
+	}
+
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	for _, key := range strings.Split(path, ".") {
+		if key == "" {
+			continue
+		}
+		switch node := data.(type) {
+		case map[string]interface{}:
+			value, ok := node[key]
+			if !ok {
+				return "", false
+			}
+			data = value
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(node) {
+				return "", false
+			}
+			data = node[index]
+		default:
+			return "", false
+		}
+	}
+
+	switch value := data.(type) {
+	case nil:
+		return "", false
+	case string:
+		return value, true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(value), true
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return "", false
+		}
+		return string(encoded), true
+	}
+}
+
+// truncateUTF8 按字节截断字符串，不截断多字节字符
+func truncateUTF8(value string, limit int) string {
+	if len(value) <= limit {
+		return value
+	}
+	for limit > 0 && !utf8.RuneStart(value[limit]) {
+		limit--
+	}
+	return value[:limit]
+}
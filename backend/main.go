@@ -6,6 +6,10 @@ import (
 	"emailAlert/internal/api"
 	"emailAlert/internal/repository"
 	"emailAlert/internal/service"
+	"emailAlert/pkg/email"
+	"emailAlert/pkg/oauth"
+	"emailAlert/pkg/proxy"
+	"emailAlert/pkg/timezone"
 	"log"
 	"os"
 	"os/signal"
@@ -15,8 +19,16 @@ import (
 )
 
 func main() {
+	// 子命令：导入邮件文件
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		os.Exit(runImportCommand(os.Args[2:]))
+	}
+
 	// 加载配置
 	cfg := config.LoadConfig()
+	loadProviderProfiles(cfg)
+	setupTimezone(cfg)
+	setupProxy(cfg)
 
 	// 初始化数据库
 	db, err := repository.NewDatabase(cfg)
@@ -49,6 +61,9 @@ func main() {
 	templateService := service.NewTemplateService(templateRepo)
 	channelService := service.NewChannelService(channelRepo)
 
+	// 令牌端点轮换的OAuth2 refresh token写回邮箱和邮件渠道，重启后仍然有效
+	oauth.DefaultTokenSource.SetRefreshTokenStore(service.NewOAuthTokenStore(repository.NewMailboxRepository(db.GetDB()), channelRepo))
+
 	// 创建通知分发服务
 	notificationDispatcherService := service.NewNotificationDispatcherService(
 		ruleChannelRepo,
@@ -84,8 +99,8 @@ func main() {
 	// 创建Gin实例
 	router := gin.Default()
 
-	// 设置路由
-	api.SetupRoutes(router, cfg, db)
+	// 设置路由，路由中的邮件监控、回溯和自监控使用已启动的通知分发服务
+	api.SetupRoutes(router, cfg, db, notificationDispatcherService)
 
 	// 启动服务器
 	log.Printf("邮件告警平台启动中，监听端口: %s", cfg.Server.Port)
@@ -108,3 +123,38 @@ func main() {
 	cancel()
 	log.Println("服务器已关闭")
 }
+
+// loadProviderProfiles 加载邮箱服务商配置文件
+func loadProviderProfiles(cfg *config.Config) {
+	count, err := email.LoadProviderFile(cfg.ProvidersFile)
+	if err != nil {
+		log.Printf("加载邮箱服务商配置失败: %v", err)
+		return
+	}
+	if count > 0 {
+		log.Printf("已从 %s 加载 %d 个邮箱服务商配置", cfg.ProvidersFile, count)
+	}
+}
+
+// setupTimezone 设置系统时区
+func setupTimezone(cfg *config.Config) {
+	loc, err := timezone.Load(cfg.Timezone)
+	if err != nil {
+		log.Printf("加载系统时区失败，使用默认时区 %s: %v", timezone.DefaultName, err)
+		return
+	}
+	timezone.SetSystem(loc)
+	log.Printf("系统时区: %s", loc)
+}
+
+// setupProxy 设置全局代理
+func setupProxy(cfg *config.Config) {
+	if cfg.Proxy == "" {
+		return
+	}
+	if err := proxy.SetGlobal(cfg.Proxy); err != nil {
+		log.Printf("全局代理配置无效，将直接连接: %v", err)
+		return
+	}
+	log.Printf("全局代理: %s", proxy.Redact(cfg.Proxy))
+}
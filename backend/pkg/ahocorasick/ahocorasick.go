@@ -0,0 +1,111 @@
+package ahocorasick
+
+// Matcher Aho-Corasick多模式匹配自动机，一次扫描文本即可找出所有出现的关键词
+// 按字节匹配，需要忽略大小写时由调用方统一转换大小写后再构建和匹配
+type Matcher struct {
+	classes  [256]int16 // 字节到字母表下标的映射，-1表示关键词中没有出现的字节
+	alphabet int        // 字母表大小
+	delta    []int32    // 状态转移表，delta[state*alphabet+class]
+	output   [][]int    // 每个状态命中的关键词下标（包含失配链上的关键词）
+	patterns int
+}
+
+// New 根据关键词构建自动机，空关键词视为在任何文本中都出现
+func New(patterns []string) *Matcher {
+	m := &Matcher{patterns: len(patterns)}
+
+	// 只为关键词中出现过的字节分配字母表下标，缩小转移表
+	for i := range m.classes {
+		m.classes[i] = -1
+	}
+	for _, pattern := range patterns {
+		for j := 0; j < len(pattern); j++ {
+			if m.classes[pattern[j]] < 0 {
+				m.classes[pattern[j]] = int16(m.alphabet)
+				m.alphabet++
+			}
+		}
+	}
+
+	// 构建字典树，-1表示没有子节点
+	newState := func() int32 {
+		for i := 0; i < m.alphabet; i++ {
+			m.delta = append(m.delta, -1)
+		}
+		m.output = append(m.output, nil)
+		return int32(len(m.output) - 1)
+	}
+	newState()
+	for i, pattern := range patterns {
+		state := int32(0)
+		for j := 0; j < len(pattern); j++ {
+			slot := int(state)*m.alphabet + int(m.classes[pattern[j]])
+			if m.delta[slot] < 0 {
+				child := newState()
+				m.delta[slot] = child
+			}
+			state = m.delta[slot]
+		}
+		m.output[state] = append(m.output[state], i)
+	}
+
+	// 按层序计算失配指针，并把缺失的转移补全为确定性自动机
+	fail := make([]int32, len(m.output))
+	queue := make([]int32, 0, len(m.output))
+	for c := 0; c < m.alphabet; c++ {
+		if child := m.delta[c]; child > 0 {
+			queue = append(queue, child)
+		} else {
+			m.delta[c] = 0
+		}
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		m.output[state] = append(m.output[state], m.output[fail[state]]...)
+		for c := 0; c < m.alphabet; c++ {
+			slot := int(state)*m.alphabet + c
+			fallback := m.delta[int(fail[state])*m.alphabet+c]
+			if child := m.delta[slot]; child >= 0 {
+				fail[child] = fallback
+				queue = append(queue, child)
+			} else {
+				m.delta[slot] = fallback
+			}
+		}
+	}
+
+	return m
+}
+
+// Match 扫描文本，返回每个关键词是否在文本中出现（与New的关键词顺序一致）
+func (m *Matcher) Match(text string) []bool {
+	found := make([]bool, m.patterns)
+	remaining := m.patterns
+
+	// 空关键词位于根节点
+	for _, p := range m.output[0] {
+		if !found[p] {
+			found[p] = true
+			remaining--
+		}
+	}
+
+	state := int32(0)
+	for i := 0; i < len(text) && remaining > 0; i++ {
+		class := m.classes[text[i]]
+		if class < 0 {
+			state = 0
+			continue
+		}
+		state = m.delta[int(state)*m.alphabet+int(class)]
+		for _, p := range m.output[state] {
+			if !found[p] {
+				found[p] = true
+				remaining--
+			}
+		}
+	}
+
+	return found
+}
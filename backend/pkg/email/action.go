@@ -0,0 +1,219 @@
+package email
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+)
+
+// 邮件处理后动作类型
+const (
+	ActionSeen   = "seen"   // 标记为已读
+	ActionFlag   = "flag"   // 添加自定义关键字，如 flag:$Alerted
+	ActionMove   = "move"   // 移动到指定文件夹，如 move:Processed
+	ActionDelete = "delete" // 删除并清除
+)
+
+// MailboxAction 邮件处理后对服务器上邮件执行的动作
+type MailboxAction struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"` // 关键字或目标文件夹
+}
+
+// String 格式化为配置字符串，如 move:Processed
+func (a MailboxAction) String() string {
+	if a.Value == "" {
+		return a.Type
+	}
+	return a.Type + ":" + a.Value
+}
+
+// HandleResult 邮件处理结果，用于决定处理后执行的动作
+type HandleResult struct {
+	Matched bool            // 是否匹配了规则组
+	Actions []MailboxAction // 匹配的规则组配置的动作，为空时使用邮箱配置的动作
+}
+
+// ActionResult 处理后动作的执行结果
+type ActionResult struct {
+	Folder string        // 文件夹
+	UIDs   []uint32      // 执行动作的邮件UID
+	Action MailboxAction // 执行的动作
+	Error  error         // 执行失败时的错误
+}
+
+// ActionResultHandler 可选接口，EmailHandler实现后可接收处理后动作的执行结果
+type ActionResultHandler interface {
+	HandleActionResult(mailboxID uint, result ActionResult)
+}
+
+// ParseActions 解析动作配置，多个动作用逗号分隔，如 "seen,flag:$Alerted,move:Processed"
+// 移动和删除互斥，且最多只能有一个移动动作
+func ParseActions(spec string) ([]MailboxAction, error) {
+	var actions []MailboxAction
+	terminal := ""
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		actionType, value, _ := strings.Cut(part, ":")
+		action := MailboxAction{Type: strings.ToLower(strings.TrimSpace(actionType)), Value: strings.TrimSpace(value)}
+
+		switch action.Type {
+		case ActionSeen, ActionDelete:
+			if action.Value != "" {
+				return nil, fmt.Errorf("动作 %s 不需要参数", action.Type)
+			}
+		case ActionFlag:
+			if action.Value == "" || strings.ContainsAny(action.Value, " (){%*\"\\]") {
+				return nil, fmt.Errorf("无效的关键字: %s", action.Value)
+			}
+		case ActionMove:
+			if action.Value == "" {
+				return nil, fmt.Errorf("移动动作必须指定目标文件夹，如 move:Processed")
+			}
+		default:
+			return nil, fmt.Errorf("不支持的动作: %s，可选 seen/flag:<关键字>/move:<文件夹>/delete", action.Type)
+		}
+
+		if action.Type == ActionMove || action.Type == ActionDelete {
+			if terminal != "" {
+				return nil, fmt.Errorf("动作 %s 与 %s 不能同时配置", terminal, action.Type)
+			}
+			terminal = action.Type
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+// ValidateActions 校验动作配置
+func ValidateActions(spec string) error {
+	_, err := ParseActions(spec)
+	return err
+}
+
+// resolveActions 根据处理结果确定邮件的处理后动作
+// 匹配的规则组配置了动作时优先使用，否则按是否匹配使用邮箱配置的动作
+func (m *Monitor) resolveActions(mailboxConfig MailboxConfig, result *HandleResult) []MailboxAction {
+	if result == nil {
+		return nil
+	}
+	if result.Matched {
+		if len(result.Actions) > 0 {
+			return result.Actions
+		}
+		return mailboxConfig.MatchedActions
+	}
+	return mailboxConfig.UnmatchedActions
+}
+
+// actionOrder 动作执行顺序：先设置标记，再移动或删除
+func actionOrder(actionType string) int {
+	switch actionType {
+	case ActionMove:
+		return 1
+	case ActionDelete:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// applyIMAPActions 对已处理的邮件执行处理后动作，相同动作的邮件合并为一条命令
+func (m *Monitor) applyIMAPActions(conn *client.Client, mailboxConfig MailboxConfig, folder string, pending map[uint32][]MailboxAction) {
+	if len(pending) == 0 {
+		return
+	}
+
+	groups := make(map[MailboxAction][]uint32)
+	for uid, actions := range pending {
+		for _, action := range actions {
+			groups[action] = append(groups[action], uid)
+		}
+	}
+
+	ordered := make([]MailboxAction, 0, len(groups))
+	for action := range groups {
+		ordered = append(ordered, action)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if actionOrder(ordered[i].Type) != actionOrder(ordered[j].Type) {
+			return actionOrder(ordered[i].Type) < actionOrder(ordered[j].Type)
+		}
+		return ordered[i].String() < ordered[j].String()
+	})
+
+	expunge := false
+	for _, action := range ordered {
+		uids := groups[action]
+		sort.Slice(uids, func(i, j int) bool { return uids[i] < uids[j] })
+		seqset := &imap.SeqSet{}
+		seqset.AddNum(uids...)
+
+		var err error
+		item := imap.FormatFlagsOp(imap.AddFlags, true)
+		switch action.Type {
+		case ActionSeen:
+			err = conn.UidStore(seqset, item, []interface{}{imap.SeenFlag}, nil)
+		case ActionFlag:
+			err = conn.UidStore(seqset, item, []interface{}{action.Value}, nil)
+		case ActionMove:
+			// 服务器不支持MOVE扩展时自动回退为COPY+STORE+EXPUNGE
+			err = conn.UidMove(seqset, action.Value)
+		case ActionDelete:
+			if err = conn.UidStore(seqset, item, []interface{}{imap.DeletedFlag}, nil); err == nil {
+				expunge = true
+			}
+		}
+
+		m.reportActionResult(mailboxConfig, ActionResult{Folder: folder, UIDs: uids, Action: action, Error: err})
+	}
+
+	// EXPUNGE会清除文件夹中所有带\Deleted标记的邮件
+	if expunge {
+		if err := conn.Expunge(nil); err != nil {
+			log.Printf("邮箱监控: 邮箱 %s/%s 清除已删除邮件失败: %v", mailboxConfig.Name, folder, err)
+		}
+	}
+}
+
+// reportActionResult 记录动作执行结果并通知处理器
+func (m *Monitor) reportActionResult(mailboxConfig MailboxConfig, result ActionResult) {
+	if result.Error != nil {
+		log.Printf("邮箱监控: 邮箱 %s/%s 执行动作 %s 失败 (%d封): %v", mailboxConfig.Name, result.Folder, result.Action, len(result.UIDs), result.Error)
+	} else {
+		log.Printf("邮箱监控: 邮箱 %s/%s 执行动作 %s 成功 (%d封)", mailboxConfig.Name, result.Folder, result.Action, len(result.UIDs))
+	}
+
+	if handler, ok := m.handler.(ActionResultHandler); ok {
+		handler.HandleActionResult(mailboxConfig.ID, result)
+	}
+}
+
+// applyPOP3Actions 对POP3邮件执行处理后动作，返回邮件是否已标记删除
+// POP3协议没有文件夹和标记，只支持删除动作
+func (m *Monitor) applyPOP3Actions(conn *pop3Conn, mailboxConfig MailboxConfig, msg pop3Message, actions []MailboxAction) bool {
+	deleted := false
+	for _, action := range actions {
+		result := ActionResult{Folder: "INBOX", UIDs: []uint32{uint32(msg.Number)}, Action: action}
+		if action.Type == ActionDelete {
+			if result.Error = conn.Dele(msg.Number); result.Error == nil {
+				deleted = true
+			}
+		} else {
+			result.Error = fmt.Errorf("POP3协议不支持动作 %s", action)
+		}
+		m.reportActionResult(mailboxConfig, result)
+	}
+	return deleted
+}
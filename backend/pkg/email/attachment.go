@@ -0,0 +1,173 @@
+package email
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultAttachmentContentLimit 默认保留附件内容的大小上限（超过时只记录元数据）
+const DefaultAttachmentContentLimit = 1 << 20
+
+// maxMIMEDepth MIME嵌套的最大深度，防止畸形邮件导致无限递归
+const maxMIMEDepth = 10
+
+// ExtractAttachments 遍历MIME结构提取附件，记录文件名、类型、大小和SHA-256
+// 不超过p.attachmentContentLimit的附件保留原始内容，文本/CSV附件同时保留解码后的文本
+func (p *EmailParser) ExtractAttachments(rawEmail string) []AttachmentData {
+	msg, err := mail.ReadMessage(strings.NewReader(rawEmail))
+	if err != nil {
+		return nil
+	}
+
+	var attachments []AttachmentData
+	p.walkMIMEPart(msg.Header, msg.Body, 0, &attachments)
+	return attachments
+}
+
+// mimeHeader MIME部分头的最小接口，兼容mail.Header和textproto.MIMEHeader
+type mimeHeader interface {
+	Get(key string) string
+}
+
+// walkMIMEPart 递归遍历MIME部分
+func (p *EmailParser) walkMIMEPart(header mimeHeader, body io.Reader, depth int, attachments *[]AttachmentData) {
+	if depth > maxMIMEDepth {
+		return
+	}
+
+	contentType := header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "text/plain"
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "application/octet-stream"
+		params = map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		boundary := params["boundary"]
+		if boundary == "" {
+			return
+		}
+		reader := multipart.NewReader(body, boundary)
+		for {
+			part, err := reader.NextRawPart()
+			if err != nil {
+				if err != io.EOF {
+					log.Printf("解析MIME结构失败: %v", err)
+				}
+				return
+			}
+			p.walkMIMEPart(part.Header, part, depth+1, attachments)
+		}
+	}
+
+	filename := p.attachmentFilename(header, params)
+	disposition, _, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if filename == "" && !strings.EqualFold(disposition, "attachment") {
+		// 正文部分
+		return
+	}
+	if filename == "" {
+		filename = "attachment"
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			filename += exts[0]
+		}
+	}
+
+	*attachments = append(*attachments, p.readAttachment(header, body, filename, mediaType, params["charset"]))
+}
+
+// attachmentFilename 获取附件文件名，支持RFC 2231和RFC 2047编码
+func (p *EmailParser) attachmentFilename(header mimeHeader, contentTypeParams map[string]string) string {
+	filename := ""
+	if _, params, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		filename = params["filename"]
+	}
+	if filename == "" {
+		filename = contentTypeParams["name"]
+	}
+	if filename == "" {
+		return ""
+	}
+	return filepath.Base(p.decodeHeader(newWordDecoder(), filename))
+}
+
+// readAttachment 读取并解码附件内容，同时计算大小和SHA-256
+func (p *EmailParser) readAttachment(header mimeHeader, body io.Reader, filename, mediaType, charset string) AttachmentData {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+
+	hash := sha256.New()
+	var content bytes.Buffer
+	limit := p.attachmentContentLimit
+
+	// 内容超过上限后只继续计算哈希和大小
+	size, err := io.Copy(io.MultiWriter(hash, &limitedBuffer{buf: &content, limit: limit}), body)
+	if err != nil {
+		log.Printf("读取附件 %s 失败: %v", filename, err)
+	}
+
+	attachment := AttachmentData{
+		Name:     filename,
+		Size:     size,
+		MimeType: mediaType,
+		SHA256:   hex.EncodeToString(hash.Sum(nil)),
+	}
+	if size <= limit && err == nil {
+		attachment.Content = content.Bytes()
+		if isTextAttachment(filename, mediaType) {
+			attachment.TextContent = decodeCharset(content.String(), charset)
+		}
+	}
+	return attachment
+}
+
+// isTextAttachment 判断附件是否为可匹配内容的文本类附件（text/*、CSV等）
+func isTextAttachment(filename, mediaType string) bool {
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/csv", "application/json", "application/xml":
+		return true
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".txt", ".log", ".json", ".xml":
+		return true
+	}
+	return false
+}
+
+// limitedBuffer 只写入前limit个字节，超出部分丢弃但不报错
+type limitedBuffer struct {
+	buf   *bytes.Buffer
+	limit int64
+}
+
+// Write 实现io.Writer
+func (w *limitedBuffer) Write(data []byte) (int, error) {
+	if remaining := w.limit - int64(w.buf.Len()); remaining > 0 {
+		if int64(len(data)) > remaining {
+			w.buf.Write(data[:remaining])
+		} else {
+			w.buf.Write(data)
+		}
+	}
+	return len(data), nil
+}
@@ -0,0 +1,92 @@
+package email
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"emailAlert/pkg/oauth"
+
+	"github.com/emersion/go-imap/client"
+)
+
+// isOAuth2 判断邮箱是否使用OAuth2认证
+func isOAuth2(config MailboxConfig) bool {
+	return strings.EqualFold(config.AuthType, oauth.AuthTypeOAuth2)
+}
+
+// oauthSASLClient SASL XOAUTH2/OAUTHBEARER客户端
+type oauthSASLClient struct {
+	mechanism string
+	response  []byte
+}
+
+// Start 返回认证机制和初始响应
+func (c *oauthSASLClient) Start() (string, []byte, error) {
+	return c.mechanism, c.response, nil
+}
+
+// Next 认证失败时服务器会返回错误详情作为质询，回复空响应以结束本次认证
+func (c *oauthSASLClient) Next(challenge []byte) ([]byte, error) {
+	return []byte{}, nil
+}
+
+// newOAuthSASLClient 根据服务器声明的认证机制构造SASL客户端，优先使用XOAUTH2
+func newOAuthSASLClient(supports func(mech string) bool, username, accessToken string) (*oauthSASLClient, error) {
+	switch {
+	case supports("XOAUTH2"):
+		return &oauthSASLClient{mechanism: "XOAUTH2", response: oauth.XOAuth2Response(username, accessToken)}, nil
+	case supports("OAUTHBEARER"):
+		return &oauthSASLClient{mechanism: "OAUTHBEARER", response: oauth.OAuthBearerResponse(username, accessToken)}, nil
+	default:
+		return nil, fmt.Errorf("服务器不支持XOAUTH2/OAUTHBEARER认证")
+	}
+}
+
+// imapLogin 按邮箱配置的认证方式登录IMAP服务器
+func imapLogin(conn *client.Client, config MailboxConfig) error {
+	if !isOAuth2(config) {
+		return conn.Login(config.Username, config.Password)
+	}
+
+	token, err := oauth.DefaultTokenSource.Token(config.OAuth)
+	if err != nil {
+		return err
+	}
+
+	supports := func(mech string) bool {
+		ok, _ := conn.SupportAuth(mech)
+		return ok
+	}
+	saslClient, err := newOAuthSASLClient(supports, config.Username, token)
+	if err != nil {
+		return err
+	}
+
+	if err := conn.Authenticate(saslClient); err != nil {
+		// 令牌可能已被吊销，下次重新获取
+		oauth.DefaultTokenSource.Invalidate(config.OAuth)
+		return fmt.Errorf("%s认证失败: %v", saslClient.mechanism, err)
+	}
+	return nil
+}
+
+// pop3Login 按邮箱配置的认证方式登录POP3服务器（OAuth2使用RFC 5034 AUTH命令）
+func pop3Login(conn *pop3Conn, config MailboxConfig) error {
+	if !isOAuth2(config) {
+		return conn.Login(config.Username, config.Password)
+	}
+
+	token, err := oauth.DefaultTokenSource.Token(config.OAuth)
+	if err != nil {
+		return err
+	}
+
+	// POP3服务器通常不在CAPA中列出全部SASL机制，直接使用XOAUTH2
+	response := base64.StdEncoding.EncodeToString(oauth.XOAuth2Response(config.Username, token))
+	if err := conn.Auth("XOAUTH2", response); err != nil {
+		oauth.DefaultTokenSource.Invalidate(config.OAuth)
+		return fmt.Errorf("XOAUTH2认证失败: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/emersion/go-imap"
+)
+
+// backfillBatchSize 历史回溯每批获取的邮件数量
+const backfillBatchSize = 50
+
+// RangeQuery 按时间范围获取历史邮件的查询条件
+type RangeQuery struct {
+	Folder string    // 文件夹，为空时使用INBOX
+	Since  time.Time // 起始时间（包含）
+	Until  time.Time // 结束时间（不包含），为零值表示到当前时间
+}
+
+// contains 判断时间是否在查询范围内
+func (q RangeQuery) contains(t time.Time) bool {
+	if t.IsZero() {
+		return false
+	}
+	if t.Before(q.Since) {
+		return false
+	}
+	return q.Until.IsZero() || t.Before(q.Until)
+}
+
+// RangeHandler 历史邮件处理器
+type RangeHandler interface {
+	// RangeTotal 获取到候选邮件总数后、处理第一封邮件前调用
+	RangeTotal(total int)
+	// HandleRangeEmail 处理一封候选邮件，emailData为nil表示邮件不在时间范围内或解析失败，仅用于更新进度
+	HandleRangeEmail(emailData *EmailData) error
+}
+
+// FetchRange 获取时间范围内的历史邮件，不受监控启动时间限制，也不会改变邮件的已读状态
+// ctx取消时在当前批次结束后停止并返回ctx.Err()
+func (c *Client) FetchRange(ctx context.Context, query RangeQuery, handler RangeHandler) error {
+	if query.Folder == "" {
+		query.Folder = "INBOX"
+	}
+	if !query.Until.IsZero() && !query.Until.After(query.Since) {
+		return fmt.Errorf("结束时间必须晚于起始时间")
+	}
+
+	if c.isPush() {
+		return fmt.Errorf("推送邮箱不支持历史回溯")
+	}
+	if c.isPOP3() {
+		return c.fetchRangePOP3(ctx, query, handler)
+	}
+	return c.fetchRangeIMAP(ctx, query, handler)
+}
+
+// fetchRangeIMAP 使用IMAP SEARCH按日期搜索后分批获取
+func (c *Client) fetchRangeIMAP(ctx context.Context, query RangeQuery, handler RangeHandler) error {
+	conn, err := c.connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Logout()
+
+	if _, err := conn.Select(query.Folder, true); err != nil {
+		return fmt.Errorf("选择文件夹 %s 失败: %v", query.Folder, err)
+	}
+
+	// IMAP的SINCE/BEFORE只精确到日期，这里放宽一天，获取后再按精确时间过滤
+	criteria := imap.NewSearchCriteria()
+	criteria.Since = query.Since.AddDate(0, 0, -1)
+	if !query.Until.IsZero() {
+		criteria.Before = query.Until.AddDate(0, 0, 1)
+	}
+	uids, err := conn.UidSearch(criteria)
+	if err != nil {
+		return fmt.Errorf("搜索邮件失败: %v", err)
+	}
+
+	log.Printf("历史回溯: 文件夹 %s 中搜索到 %d 封候选邮件", query.Folder, len(uids))
+	handler.RangeTotal(len(uids))
+
+	parser := NewEmailParser()
+	section := &imap.BodySectionName{Peek: true}
+	items := []imap.FetchItem{imap.FetchUid, imap.FetchFlags, imap.FetchInternalDate, imap.FetchRFC822Size, section.FetchItem()}
+
+	for start := 0; start < len(uids); start += backfillBatchSize {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start + backfillBatchSize
+		if end > len(uids) {
+			end = len(uids)
+		}
+		seqset := &imap.SeqSet{}
+		seqset.AddNum(uids[start:end]...)
+
+		messages := make(chan *imap.Message, end-start)
+		done := make(chan error, 1)
+		go func() {
+			done <- conn.UidFetch(seqset, items, messages)
+		}()
+
+		var handlerErr error
+		for msg := range messages {
+			if handlerErr != nil {
+				continue
+			}
+			emailData := c.rangeEmailData(parser, msg, query)
+			handlerErr = handler.HandleRangeEmail(emailData)
+		}
+
+		if err := <-done; err != nil {
+			return fmt.Errorf("获取邮件失败: %v", err)
+		}
+		if handlerErr != nil {
+			return handlerErr
+		}
+	}
+
+	return nil
+}
+
+// rangeEmailData 解析IMAP邮件，不在时间范围内时返回nil
+func (c *Client) rangeEmailData(parser *EmailParser, msg *imap.Message, query RangeQuery) *EmailData {
+	var raw []byte
+	for _, body := range msg.Body {
+		if body == nil {
+			continue
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			log.Printf("历史回溯: 读取邮件 %d 失败: %v", msg.Uid, err)
+			return nil
+		}
+		raw = data
+		break
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+
+	emailData, err := parser.ParseMessage(string(raw))
+	if err != nil {
+		log.Printf("历史回溯: 解析邮件 %d 失败: %v", msg.Uid, err)
+		return nil
+	}
+	emailData.UID = int(msg.Uid)
+	emailData.Folder = query.Folder
+	emailData.Flags = msg.Flags
+	if msg.Size > 0 {
+		emailData.Size = uint64(msg.Size)
+	}
+	if emailData.ReceivedAt.IsZero() {
+		emailData.ReceivedAt = msg.InternalDate
+	}
+
+	if !query.contains(emailData.ReceivedAt) {
+		return nil
+	}
+	return emailData
+}
+
+// fetchRangePOP3 POP3不支持搜索，逐封获取后按邮件日期过滤
+func (c *Client) fetchRangePOP3(ctx context.Context, query RangeQuery, handler RangeHandler) error {
+	conn, err := c.connectPOP3()
+	if err != nil {
+		return err
+	}
+	defer conn.Quit()
+
+	messages, err := conn.Uidl()
+	if err != nil {
+		return fmt.Errorf("获取邮件列表失败: %v", err)
+	}
+	handler.RangeTotal(len(messages))
+
+	parser := NewEmailParser()
+	for _, msg := range messages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		raw, err := conn.Retr(msg.Number)
+		if err != nil {
+			return fmt.Errorf("获取邮件 %s 失败: %v", msg.UID, err)
+		}
+
+		var emailData *EmailData
+		if parsed, err := parser.ParseMessage(raw); err != nil {
+			log.Printf("历史回溯: 解析POP3邮件 %s 失败: %v", msg.UID, err)
+		} else if query.contains(parsed.ReceivedAt) {
+			parsed.UID = pop3UID(msg.UID)
+			parsed.UIDL = msg.UID
+			parsed.Folder = "INBOX"
+			emailData = parsed
+		}
+
+		if err := handler.HandleRangeEmail(emailData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package email
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"strings"
+
+	"github.com/emersion/go-imap"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func init() {
+	// go-imap解码信封主题和姓名时同样支持GBK等字符集
+	imap.CharsetReader = charsetReader
+}
+
+// lookupCharset 根据字符集名称查找编码，支持WHATWG定义的全部字符集
+func lookupCharset(charset string) (encoding.Encoding, error) {
+	name := strings.ToLower(strings.Trim(strings.TrimSpace(charset), `"`))
+	switch name {
+	case "gb18030":
+		return simplifiedchinese.GB18030, nil
+	case "gb2312", "gbk", "cp936", "x-gbk", "euc-cn":
+		// GB2312邮件中常混有GBK扩展字符，统一按GBK解码
+		return simplifiedchinese.GBK, nil
+	}
+
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("不支持的字符集: %s", charset)
+	}
+	return enc, nil
+}
+
+// charsetReader 将指定字符集的输入转换为UTF-8
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	enc, err := lookupCharset(charset)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+// decodeCharset 将指定字符集的内容转换为UTF-8，失败时返回原始内容
+func decodeCharset(content, charset string) string {
+	name := strings.ToLower(strings.TrimSpace(charset))
+	if name == "" || name == "utf-8" || name == "utf8" || name == "us-ascii" {
+		return content
+	}
+
+	enc, err := lookupCharset(charset)
+	if err != nil {
+		log.Printf("字符集转换失败: %v", err)
+		return content
+	}
+	decoded, err := enc.NewDecoder().String(content)
+	if err != nil {
+		log.Printf("字符集 %s 转换失败: %v", charset, err)
+		return content
+	}
+	return decoded
+}
+
+// newWordDecoder 创建支持全部字符集的RFC 2047解码器
+func newWordDecoder() *mime.WordDecoder {
+	return &mime.WordDecoder{CharsetReader: charsetReader}
+}
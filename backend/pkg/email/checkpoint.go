@@ -0,0 +1,208 @@
+package email
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/emersion/go-imap"
+)
+
+// Checkpoint 邮箱文件夹检查点
+type Checkpoint struct {
+	UIDValidity uint32   `json:"uid_validity"`         // 文件夹UIDVALIDITY
+	LastUID     uint32   `json:"last_uid"`             // 最后处理的UID
+	SeenUIDLs   []string `json:"seen_uidls,omitempty"` // POP3邮箱已处理的UIDL（IMAP邮箱为nil）
+}
+
+// CheckpointStore 检查点持久化接口
+type CheckpointStore interface {
+	// LoadCheckpoint 读取检查点，不存在时返回nil
+	LoadCheckpoint(mailboxID uint, folder string) (*Checkpoint, error)
+	// SaveCheckpoint 保存检查点
+	SaveCheckpoint(mailboxID uint, folder string, checkpoint Checkpoint) error
+}
+
+// SetCheckpointStore 设置检查点存储，设置后监控重启时从上次处理的位置继续
+func (m *Monitor) SetCheckpointStore(store CheckpointStore) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.checkpointStore = store
+}
+
+// ResetCheckpoint 清除邮箱在内存中的检查点，下次检查时重新建立基线
+// folder为空时清除该邮箱全部文件夹的检查点
+func (m *Monitor) ResetCheckpoint(mailboxID uint, folder string) {
+	mailboxMutex := m.getMailboxMutex(mailboxID)
+	mailboxMutex.Lock()
+	defer mailboxMutex.Unlock()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if folder == "" || folder == pop3Folder {
+		delete(m.pop3SeenUIDs, mailboxID)
+	}
+	if folder == "" {
+		delete(m.lastCheckUID, mailboxID)
+		delete(m.uidValidity, mailboxID)
+		delete(m.resumedFolders, mailboxID)
+		delete(m.messageFailures, mailboxID)
+		log.Printf("邮箱 %d: 检查点已重置", mailboxID)
+		return
+	}
+
+	delete(m.lastCheckUID[mailboxID], folder)
+	delete(m.uidValidity[mailboxID], folder)
+	delete(m.resumedFolders[mailboxID], folder)
+	for key := range m.messageFailures[mailboxID] {
+		if key.folder == folder {
+			delete(m.messageFailures[mailboxID], key)
+		}
+	}
+	log.Printf("邮箱 %d/%s: 检查点已重置", mailboxID, folder)
+}
+
+// prepareCheckpoint 根据当前文件夹状态确定本次检查的起点
+// 返回上次处理的UID，以及该UID是否来自持久化的检查点（停机期间到达的邮件不受启动时间限制）
+func (m *Monitor) prepareCheckpoint(mailboxConfig MailboxConfig, folder string, mbox *imap.MailboxStatus) (int, bool) {
+	mailboxID := mailboxConfig.ID
+	lastUID := m.getAndPrepareLastCheckUID(mailboxID, folder)
+
+	m.mutex.Lock()
+	store := m.checkpointStore
+	validity, known := m.uidValidity[mailboxID][folder]
+	resumed := m.resumedFolders[mailboxID][folder]
+
+	// UIDVALIDITY变化说明服务器重建了UID，原有检查点失效
+	if known && validity != mbox.UidValidity {
+		log.Printf("邮箱 %s/%s: UIDVALIDITY由 %d 变为 %d，重置检查点", mailboxConfig.Name, folder, validity, mbox.UidValidity)
+		delete(m.lastCheckUID[mailboxID], folder)
+		delete(m.resumedFolders[mailboxID], folder)
+		lastUID, resumed, known = 0, false, false
+	}
+	if _, ok := m.uidValidity[mailboxID]; !ok {
+		m.uidValidity[mailboxID] = make(map[string]uint32)
+	}
+	m.uidValidity[mailboxID][folder] = mbox.UidValidity
+	m.mutex.Unlock()
+
+	if known || store == nil {
+		return lastUID, resumed
+	}
+
+	// 本次运行首次检查该文件夹，尝试从持久化检查点恢复
+	checkpoint, err := store.LoadCheckpoint(mailboxID, folder)
+	if err != nil {
+		log.Printf("邮箱 %s/%s: 读取检查点失败: %v", mailboxConfig.Name, folder, err)
+		return lastUID, false
+	}
+	if checkpoint == nil || checkpoint.LastUID == 0 {
+		return lastUID, false
+	}
+	if checkpoint.UIDValidity != mbox.UidValidity {
+		log.Printf("邮箱 %s/%s: 检查点UIDVALIDITY(%d)与服务器(%d)不一致，忽略检查点", mailboxConfig.Name, folder, checkpoint.UIDValidity, mbox.UidValidity)
+		return lastUID, false
+	}
+
+	m.mutex.Lock()
+	if _, ok := m.lastCheckUID[mailboxID]; !ok {
+		m.lastCheckUID[mailboxID] = make(map[string]int)
+	}
+	m.lastCheckUID[mailboxID][folder] = int(checkpoint.LastUID)
+	if _, ok := m.resumedFolders[mailboxID]; !ok {
+		m.resumedFolders[mailboxID] = make(map[string]bool)
+	}
+	m.resumedFolders[mailboxID][folder] = true
+	m.mutex.Unlock()
+
+	log.Printf("邮箱 %s/%s: 从检查点恢复，上次处理到UID %d", mailboxConfig.Name, folder, checkpoint.LastUID)
+	return int(checkpoint.LastUID), true
+}
+
+// commitCheckpoint 更新并持久化检查点
+// lastUID为0时以当前UIDNEXT-1作为基线，之后到达的邮件在重启后也能被处理
+func (m *Monitor) commitCheckpoint(mailboxConfig MailboxConfig, folder string, mbox *imap.MailboxStatus, lastUID int) {
+	if lastUID == 0 && mbox.UidNext > 1 {
+		lastUID = int(mbox.UidNext - 1)
+	}
+	if lastUID == 0 {
+		return
+	}
+
+	m.mutex.RLock()
+	store := m.checkpointStore
+	current, exists := m.lastCheckUID[mailboxConfig.ID][folder]
+	m.mutex.RUnlock()
+
+	// 检查点未前进时无需更新
+	if exists && lastUID <= current {
+		return
+	}
+
+	m.updateLastCheckUID(mailboxConfig.ID, folder, lastUID)
+	if store == nil {
+		return
+	}
+
+	checkpoint := Checkpoint{
+		UIDValidity: mbox.UidValidity,
+		LastUID:     uint32(lastUID),
+	}
+	if err := store.SaveCheckpoint(mailboxConfig.ID, folder, checkpoint); err != nil {
+		log.Printf("邮箱 %s/%s: 保存检查点失败: %v", mailboxConfig.Name, folder, err)
+	}
+}
+
+// messageKey 文件夹中的一封邮件
+type messageKey struct {
+	folder string
+	uid    uint32
+}
+
+// messageFailureLimit 获取单封邮件允许的处理失败次数，小于0表示一直重试
+func (m *Monitor) messageFailureLimit() int {
+	if m.config.MessageFailureLimit == 0 {
+		return DefaultMessageFailureLimit
+	}
+	return m.config.MessageFailureLimit
+}
+
+// recordMessageFailure 记录一次邮件处理失败，达到上限时返回true，调用方跳过该邮件
+// 跳过的邮件记入邮箱健康状态，检查点不再停在它之前
+func (m *Monitor) recordMessageFailure(mailboxConfig MailboxConfig, folder string, uid uint32) bool {
+	key := messageKey{folder: folder, uid: uid}
+
+	m.mutex.Lock()
+	failures, ok := m.messageFailures[mailboxConfig.ID]
+	if !ok {
+		failures = make(map[messageKey]int)
+		m.messageFailures[mailboxConfig.ID] = failures
+	}
+	failures[key]++
+	attempts := failures[key]
+	limit := m.messageFailureLimit()
+	skipped := limit > 0 && attempts >= limit
+	if skipped {
+		delete(failures, key)
+	}
+	m.mutex.Unlock()
+
+	if !skipped {
+		return false
+	}
+
+	log.Printf("邮箱 %s/%s: UID %d 连续 %d 次处理失败，跳过该邮件", mailboxConfig.Name, folder, uid, attempts)
+	m.healthMutex.Lock()
+	health := m.mailboxHealth(mailboxConfig)
+	health.SkippedMessages++
+	health.LastSkipped = fmt.Sprintf("%s/%d", folder, uid)
+	m.healthMutex.Unlock()
+	return true
+}
+
+// clearMessageFailure 邮件处理成功后清除其失败次数
+func (m *Monitor) clearMessageFailure(mailboxID uint, folder string, uid uint32) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.messageFailures[mailboxID], messageKey{folder: folder, uid: uid})
+}
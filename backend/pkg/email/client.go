@@ -2,12 +2,12 @@ package email
 
 import (
 	"context"
-	"crypto/tls"
 	"fmt"
 	"log"
-	"strings"
 	"time"
 
+	"emailAlert/pkg/oauth"
+
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
 )
@@ -19,6 +19,11 @@ type Client struct {
 	username string
 	password string
 	ssl      bool
+	protocol string
+	authType string
+	oauth    oauth.Config
+	tls      TLSOptions
+	proxy    string // 代理地址，为空时使用全局代理，为direct时直连
 }
 
 // NewClient 创建新的邮箱客户端
@@ -32,37 +37,74 @@ func NewClient(host string, port int, username, password string, ssl bool) *Clie
 	}
 }
 
-// connect 创建IMAP连接
-func (c *Client) connect() (*client.Client, error) {
-	// 构建连接地址
-	addr := fmt.Sprintf("%s:%d", c.host, c.port)
+// NewClientFromConfig 根据邮箱配置创建客户端（支持IMAP/POP3）
+func NewClientFromConfig(config MailboxConfig) *Client {
+	return &Client{
+		host:     config.Host,
+		port:     config.Port,
+		username: config.Username,
+		password: config.Password,
+		ssl:      config.SSL,
+		protocol: config.Protocol,
+		authType: config.AuthType,
+		oauth:    config.OAuth,
+		tls:      config.TLS,
+		proxy:    config.Proxy,
+	}
+}
+
+// mailboxConfig 将客户端参数转换为邮箱配置
+func (c *Client) mailboxConfig() MailboxConfig {
+	return MailboxConfig{
+		Host:     c.host,
+		Port:     c.port,
+		Username: c.username,
+		Password: c.password,
+		SSL:      c.ssl,
+		Protocol: c.protocol,
+		AuthType: c.authType,
+		OAuth:    c.oauth,
+		TLS:      c.tls,
+		Proxy:    c.proxy,
+	}
+}
 
-	var conn *client.Client
-	var err error
+// isPOP3 判断客户端是否使用POP3协议
+func (c *Client) isPOP3() bool {
+	return isPOP3(c.mailboxConfig())
+}
 
-	if c.ssl {
-		// SSL/TLS连接
-		tlsConfig := &tls.Config{
-			ServerName: c.host,
-		}
-		conn, err = client.DialTLS(addr, tlsConfig)
-	} else {
-		// 普通连接
-		conn, err = client.Dial(addr)
+// connectPOP3 创建POP3连接并登录
+func (c *Client) connectPOP3() (*pop3Conn, error) {
+	conn, err := dialPOP3(c.mailboxConfig())
+	if err != nil {
+		return nil, err
 	}
 
+	if err := pop3Login(conn, c.mailboxConfig()); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("认证失败: %v", err)
+	}
+
+	return conn, nil
+}
+
+// connect 创建IMAP连接
+func (c *Client) connect() (*client.Client, error) {
+	// 按TLS配置建立连接（明文、SSL/TLS或STARTTLS）
+	conn, err := dialIMAP(c.mailboxConfig())
 	if err != nil {
-		return nil, fmt.Errorf("连接服务器失败: %v", err)
+		return nil, err
 	}
 
 	// 登录认证
-	err = conn.Login(c.username, c.password)
+	err = imapLogin(conn, c.mailboxConfig())
 	if err != nil {
 		conn.Logout()
 		return nil, fmt.Errorf("认证失败: %v", err)
 	}
 
-	// 针对126/163邮箱发送ID命令
+	// 服务商配置要求时发送ID命令（如126/163邮箱）
 	if c.shouldSendID() {
 		err = c.sendIMAPID(conn)
 		if err != nil {
@@ -84,6 +126,25 @@ func (c *Client) TestConnection() error {
 	resultChan := make(chan error, 1)
 
 	go func() {
+		// 推送邮箱没有远程服务器，无需测试
+		if c.isPush() {
+			resultChan <- nil
+			return
+		}
+
+		if c.isPOP3() {
+			conn, err := c.connectPOP3()
+			if err != nil {
+				resultChan <- err
+				return
+			}
+			defer conn.Quit()
+
+			_, _, err = conn.Stat()
+			resultChan <- err
+			return
+		}
+
 		conn, err := c.connect()
 		if err != nil {
 			resultChan <- err
@@ -107,6 +168,11 @@ func (c *Client) TestConnection() error {
 
 // GetFolders 获取邮箱文件夹列表
 func (c *Client) GetFolders() ([]string, error) {
+	// POP3协议和推送邮箱没有文件夹概念，只有收件箱
+	if c.isPOP3() || c.isPush() {
+		return []string{"INBOX"}, nil
+	}
+
 	// 设置15秒超时
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
@@ -162,6 +228,24 @@ func (c *Client) GetFolders() ([]string, error) {
 
 // GetEmailCount 获取指定文件夹的邮件数量
 func (c *Client) GetEmailCount(folder string) (int, error) {
+	if c.isPush() {
+		return 0, fmt.Errorf("推送邮箱不支持获取邮件数量")
+	}
+
+	if c.isPOP3() {
+		conn, err := c.connectPOP3()
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Quit()
+
+		count, _, err := conn.Stat()
+		if err != nil {
+			return 0, fmt.Errorf("获取邮件数量失败: %v", err)
+		}
+		return count, nil
+	}
+
 	conn, err := c.connect()
 	if err != nil {
 		return 0, err
@@ -183,6 +267,7 @@ type ConnectionInfo struct {
 	Port     int       `json:"port"`
 	Username string    `json:"username"`
 	SSL      bool      `json:"ssl"`
+	Protocol string    `json:"protocol"`
 	Status   string    `json:"status"`    // connected, disconnected, error
 	Message  string    `json:"message"`   // 连接状态消息
 	Folders  []string  `json:"folders"`   // 文件夹列表
@@ -196,6 +281,7 @@ func (c *Client) GetConnectionInfo() *ConnectionInfo {
 		Port:     c.port,
 		Username: c.username,
 		SSL:      c.ssl,
+		Protocol: c.protocol,
 		TestTime: time.Now(),
 	}
 
@@ -220,10 +306,7 @@ func (c *Client) GetConnectionInfo() *ConnectionInfo {
 
 // shouldSendID 判断是否需要发送IMAP ID命令
 func (c *Client) shouldSendID() bool {
-	// 针对126/163/阿里云企业邮箱需要发送ID命令
-	return strings.Contains(c.host, "126.com") ||
-		strings.Contains(c.host, "163.com") ||
-		strings.Contains(c.host, "qiye.aliyun.com")
+	return shouldSendIMAPID(c.host)
 }
 
 // sendIMAPID 发送IMAP ID命令，用于解决126/163邮箱的"Unsafe Login"问题
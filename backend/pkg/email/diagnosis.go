@@ -1,7 +1,6 @@
 package email
 
 import (
-	"crypto/tls"
 	"fmt"
 	"log"
 	"strings"
@@ -57,6 +56,26 @@ func sendIMAPID(conn *client.Client) error {
 func DiagnoseMailbox(mailboxConfig MailboxConfig) *EmailDiagnosis {
 	diagnosis := &EmailDiagnosis{}
 
+	// 推送邮箱没有远程服务器，只需确认接收服务可以投递
+	if isPush(mailboxConfig) {
+		diagnosis.Results = append(diagnosis.Results, DiagnosisResult{
+			Step:       "推送接收",
+			Success:    true,
+			Message:    fmt.Sprintf("邮件由内置SMTP/LMTP接收服务投递到 %s", mailboxConfig.Email),
+			Suggestion: "请确认已启用接收服务（SMTP_RECEIVER_ENABLED=true），并将发件系统的SMTP服务器指向接收服务地址",
+		})
+		return diagnosis
+	}
+
+	// POP3邮箱使用独立的诊断流程
+	if isPOP3(mailboxConfig) {
+		diagnosis.testPOP3Connection(mailboxConfig)
+		diagnosis.testPOP3Authentication(mailboxConfig)
+		diagnosis.testPOP3Access(mailboxConfig)
+		diagnosis.provideProviderSuggestions(mailboxConfig)
+		return diagnosis
+	}
+
 	// 步骤1: 基础连接测试
 	diagnosis.testConnection(mailboxConfig)
 
@@ -75,25 +94,19 @@ func DiagnoseMailbox(mailboxConfig MailboxConfig) *EmailDiagnosis {
 // testConnection 测试基础连接
 func (d *EmailDiagnosis) testConnection(config MailboxConfig) {
 	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
+	mode := tlsMode(config)
 
-	var conn *client.Client
-	var err error
-
-	if config.SSL {
-		tlsConfig := &tls.Config{
-			ServerName: config.Host,
-		}
-		conn, err = client.DialTLS(addr, tlsConfig)
-	} else {
-		conn, err = client.Dial(addr)
-	}
-
+	conn, err := dialIMAP(config)
 	if err != nil {
+		suggestion := "请检查服务器地址、端口号和网络连接"
+		if mode != TLSModeNone {
+			suggestion = "请检查服务器地址、端口号和网络连接；若为证书错误，请检查TLS模式（993端口通常为implicit，143端口通常为starttls）、CA证书、客户端证书和最低TLS版本"
+		}
 		d.Results = append(d.Results, DiagnosisResult{
 			Step:       "连接测试",
 			Success:    false,
-			Message:    fmt.Sprintf("无法连接到服务器 %s", addr),
-			Suggestion: "请检查服务器地址、端口号和网络连接",
+			Message:    fmt.Sprintf("无法连接到服务器 %s（TLS模式: %s）: %v", addr, mode, err),
+			Suggestion: suggestion,
 		})
 		return
 	}
@@ -102,26 +115,13 @@ func (d *EmailDiagnosis) testConnection(config MailboxConfig) {
 	d.Results = append(d.Results, DiagnosisResult{
 		Step:    "连接测试",
 		Success: true,
-		Message: fmt.Sprintf("成功连接到服务器 %s", addr),
+		Message: fmt.Sprintf("成功连接到服务器 %s（TLS模式: %s）", addr, mode),
 	})
 }
 
 // testAuthentication 测试认证
 func (d *EmailDiagnosis) testAuthentication(config MailboxConfig) {
-	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
-
-	var conn *client.Client
-	var err error
-
-	if config.SSL {
-		tlsConfig := &tls.Config{
-			ServerName: config.Host,
-		}
-		conn, err = client.DialTLS(addr, tlsConfig)
-	} else {
-		conn, err = client.Dial(addr)
-	}
-
+	conn, err := dialIMAP(config)
 	if err != nil {
 		d.Results = append(d.Results, DiagnosisResult{
 			Step:    "认证测试",
@@ -133,13 +133,16 @@ func (d *EmailDiagnosis) testAuthentication(config MailboxConfig) {
 	defer conn.Logout()
 
 	// 尝试登录
-	err = conn.Login(config.Username, config.Password)
+	err = imapLogin(conn, config)
 	if err != nil {
 		suggestion := "请检查用户名和密码"
 		if strings.Contains(err.Error(), "authentication") ||
 			strings.Contains(err.Error(), "auth") {
 			suggestion = "认证失败。如果是126/163/Gmail等邮箱，可能需要使用授权码而非密码"
 		}
+		if isOAuth2(config) {
+			suggestion = "请检查OAuth2的client_id、client_secret、refresh_token和令牌端点，并确认应用已获得IMAP访问权限"
+		}
 
 		d.Results = append(d.Results, DiagnosisResult{
 			Step:       "认证测试",
@@ -159,20 +162,7 @@ func (d *EmailDiagnosis) testAuthentication(config MailboxConfig) {
 
 // testIMAPAccess 测试IMAP访问权限
 func (d *EmailDiagnosis) testIMAPAccess(config MailboxConfig) {
-	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
-
-	var conn *client.Client
-	var err error
-
-	if config.SSL {
-		tlsConfig := &tls.Config{
-			ServerName: config.Host,
-		}
-		conn, err = client.DialTLS(addr, tlsConfig)
-	} else {
-		conn, err = client.Dial(addr)
-	}
-
+	conn, err := dialIMAP(config)
 	if err != nil {
 		d.Results = append(d.Results, DiagnosisResult{
 			Step:    "IMAP访问测试",
@@ -184,7 +174,7 @@ func (d *EmailDiagnosis) testIMAPAccess(config MailboxConfig) {
 	defer conn.Logout()
 
 	// 先登录
-	err = conn.Login(config.Username, config.Password)
+	err = imapLogin(conn, config)
 	if err != nil {
 		d.Results = append(d.Results, DiagnosisResult{
 			Step:    "IMAP访问测试",
@@ -194,10 +184,8 @@ func (d *EmailDiagnosis) testIMAPAccess(config MailboxConfig) {
 		return
 	}
 
-	// 针对126/163/阿里云企业邮箱发送ID命令
-	if strings.Contains(config.Host, "126.com") ||
-		strings.Contains(config.Host, "163.com") ||
-		strings.Contains(config.Host, "qiye.aliyun.com") {
+	// 服务商配置要求时发送ID命令（如126/163/阿里云企业邮箱）
+	if shouldSendIMAPID(config.Host) {
 		err = sendIMAPID(conn)
 		if err != nil {
 			log.Printf("发送IMAP ID失败: %v", err)
@@ -231,51 +219,156 @@ func (d *EmailDiagnosis) testIMAPAccess(config MailboxConfig) {
 		Success: true,
 		Message: "成功访问INBOX文件夹，邮件监控应该可以正常工作",
 	})
+
+	// 启用IDLE推送模式时检查服务器能力
+	if config.IdleMode {
+		supported, err := conn.Support("IDLE")
+		if err != nil || !supported {
+			d.Results = append(d.Results, DiagnosisResult{
+				Step:       "IDLE支持检测",
+				Success:    false,
+				Message:    "服务器未声明IDLE能力，监控将回退到轮询模式",
+				Suggestion: "可关闭IDLE推送模式，使用定时轮询",
+			})
+			return
+		}
+
+		d.Results = append(d.Results, DiagnosisResult{
+			Step:    "IDLE支持检测",
+			Success: true,
+			Message: "服务器支持IDLE，新邮件将实时推送",
+		})
+	}
 }
 
-// provideProviderSuggestions 提供特定邮箱提供商的建议
-func (d *EmailDiagnosis) provideProviderSuggestions(config MailboxConfig) {
-	host := strings.ToLower(config.Host)
-
-	suggestions := []string{}
-
-	if strings.Contains(host, "126.com") || strings.Contains(host, "imap.126.com") {
-		suggestions = append(suggestions, "126邮箱设置建议：")
-		suggestions = append(suggestions, "1. 登录126邮箱网页版")
-		suggestions = append(suggestions, "2. 进入设置 > POP3/SMTP/IMAP")
-		suggestions = append(suggestions, "3. 开启IMAP/SMTP服务")
-		suggestions = append(suggestions, "4. 使用授权码作为密码，而非登录密码")
-		suggestions = append(suggestions, "5. 服务器地址：imap.126.com，端口：993，启用SSL")
-	} else if strings.Contains(host, "163.com") || strings.Contains(host, "imap.163.com") {
-		suggestions = append(suggestions, "163邮箱设置建议：")
-		suggestions = append(suggestions, "1. 登录163邮箱网页版")
-		suggestions = append(suggestions, "2. 进入设置 > POP3/SMTP/IMAP")
-		suggestions = append(suggestions, "3. 开启IMAP/SMTP服务")
-		suggestions = append(suggestions, "4. 使用授权码作为密码")
-		suggestions = append(suggestions, "5. 服务器地址：imap.163.com，端口：993，启用SSL")
-	} else if strings.Contains(host, "qq.com") || strings.Contains(host, "imap.qq.com") {
-		suggestions = append(suggestions, "QQ邮箱设置建议：")
-		suggestions = append(suggestions, "1. 登录QQ邮箱网页版")
-		suggestions = append(suggestions, "2. 进入设置 > 账户")
-		suggestions = append(suggestions, "3. 开启IMAP/SMTP服务")
-		suggestions = append(suggestions, "4. 生成授权码作为密码")
-		suggestions = append(suggestions, "5. 服务器地址：imap.qq.com，端口：993，启用SSL")
-	} else if strings.Contains(host, "gmail.com") || strings.Contains(host, "imap.gmail.com") {
-		suggestions = append(suggestions, "Gmail设置建议：")
-		suggestions = append(suggestions, "1. 开启两步验证")
-		suggestions = append(suggestions, "2. 生成应用专用密码")
-		suggestions = append(suggestions, "3. 使用应用专用密码而非Google账号密码")
-		suggestions = append(suggestions, "4. 服务器地址：imap.gmail.com，端口：993，启用SSL")
+// testPOP3Connection 测试POP3基础连接
+func (d *EmailDiagnosis) testPOP3Connection(config MailboxConfig) {
+	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
+
+	conn, err := dialPOP3(config)
+	if err != nil {
+		d.Results = append(d.Results, DiagnosisResult{
+			Step:       "连接测试",
+			Success:    false,
+			Message:    fmt.Sprintf("无法连接到服务器 %s", addr),
+			Suggestion: "请检查服务器地址、端口号和网络连接（POP3常用端口：995启用SSL，110不启用SSL）",
+		})
+		return
 	}
+	defer conn.Quit()
 
-	if len(suggestions) > 0 {
+	d.Results = append(d.Results, DiagnosisResult{
+		Step:    "连接测试",
+		Success: true,
+		Message: fmt.Sprintf("成功连接到服务器 %s", addr),
+	})
+}
+
+// testPOP3Authentication 测试POP3认证
+func (d *EmailDiagnosis) testPOP3Authentication(config MailboxConfig) {
+	conn, err := dialPOP3(config)
+	if err != nil {
 		d.Results = append(d.Results, DiagnosisResult{
-			Step:       "邮箱提供商建议",
-			Success:    true,
-			Message:    "根据您的邮箱提供商，提供以下设置建议",
-			Suggestion: strings.Join(suggestions, "\n"),
+			Step:    "认证测试",
+			Success: false,
+			Message: "连接失败，跳过认证测试",
+		})
+		return
+	}
+	defer conn.Quit()
+
+	if err := pop3Login(conn, config); err != nil {
+		d.Results = append(d.Results, DiagnosisResult{
+			Step:       "认证测试",
+			Success:    false,
+			Message:    fmt.Sprintf("认证失败: %v", err),
+			Suggestion: "请检查用户名和密码。如果是126/163/QQ等邮箱，需要开启POP3服务并使用授权码",
 		})
+		return
 	}
+
+	d.Results = append(d.Results, DiagnosisResult{
+		Step:    "认证测试",
+		Success: true,
+		Message: "认证成功",
+	})
+}
+
+// testPOP3Access 测试POP3访问权限（STAT和UIDL）
+func (d *EmailDiagnosis) testPOP3Access(config MailboxConfig) {
+	conn, err := dialPOP3(config)
+	if err != nil {
+		d.Results = append(d.Results, DiagnosisResult{
+			Step:    "POP3访问测试",
+			Success: false,
+			Message: "连接失败，跳过POP3访问测试",
+		})
+		return
+	}
+	defer conn.Quit()
+
+	if err := pop3Login(conn, config); err != nil {
+		d.Results = append(d.Results, DiagnosisResult{
+			Step:    "POP3访问测试",
+			Success: false,
+			Message: "登录失败，跳过POP3访问测试",
+		})
+		return
+	}
+
+	count, _, err := conn.Stat()
+	if err != nil {
+		d.Results = append(d.Results, DiagnosisResult{
+			Step:       "POP3访问测试",
+			Success:    false,
+			Message:    fmt.Sprintf("无法读取邮箱状态: %v", err),
+			Suggestion: "请检查邮箱POP3权限设置",
+		})
+		return
+	}
+
+	if _, err := conn.Uidl(); err != nil {
+		d.Results = append(d.Results, DiagnosisResult{
+			Step:       "POP3访问测试",
+			Success:    false,
+			Message:    fmt.Sprintf("服务器不支持UIDL命令: %v", err),
+			Suggestion: "系统依赖UIDL追踪新邮件，请改用IMAP协议",
+		})
+		return
+	}
+
+	d.Results = append(d.Results, DiagnosisResult{
+		Step:    "POP3访问测试",
+		Success: true,
+		Message: fmt.Sprintf("成功读取邮箱（共 %d 封邮件），邮件监控应该可以正常工作", count),
+	})
+}
+
+// provideProviderSuggestions 根据服务商配置提供设置建议
+func (d *EmailDiagnosis) provideProviderSuggestions(config MailboxConfig) {
+	provider := LookupProvider(config.Host)
+	if provider == nil {
+		return
+	}
+
+	suggestions := []string{provider.Name + "设置建议："}
+	hints := provider.Hints
+	if hint := provider.serverHint(config.Protocol); hint != "" {
+		hints = append(hints[:len(hints):len(hints)], hint)
+	}
+	for i, hint := range hints {
+		suggestions = append(suggestions, fmt.Sprintf("%d. %s", i+1, hint))
+	}
+	if len(hints) == 0 {
+		return
+	}
+
+	d.Results = append(d.Results, DiagnosisResult{
+		Step:       "邮箱提供商建议",
+		Success:    true,
+		Message:    "根据您的邮箱提供商，提供以下设置建议",
+		Suggestion: strings.Join(suggestions, "\n"),
+	})
 }
 
 // PrintDiagnosis 打印诊断结果
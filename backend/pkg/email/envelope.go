@@ -0,0 +1,151 @@
+package email
+
+import (
+	"mime"
+	"net/mail"
+	"net/textproto"
+	"strings"
+
+	"github.com/emersion/go-imap"
+)
+
+// Address 邮件地址（含显示名称）
+type Address struct {
+	Name    string `json:"name"`    // 显示名称（已解码）
+	Address string `json:"address"` // 邮箱地址
+}
+
+// String 格式化为 "名称 <地址>"，无显示名称时只返回地址
+func (a Address) String() string {
+	if a.Name == "" {
+		return a.Address
+	}
+	if a.Address == "" {
+		return a.Name
+	}
+	return a.Name + " <" + a.Address + ">"
+}
+
+// FormatAddresses 将地址列表格式化为字符串列表
+func FormatAddresses(addresses []Address) []string {
+	result := make([]string, 0, len(addresses))
+	for _, addr := range addresses {
+		result = append(result, addr.String())
+	}
+	return result
+}
+
+// parseAddressList 解析地址列表头，格式不规范时逐项尽量解析
+func parseAddressList(decoder *mime.WordDecoder, value string) []Address {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	addressParser := &mail.AddressParser{WordDecoder: decoder}
+	if list, err := addressParser.ParseList(value); err == nil {
+		result := make([]Address, 0, len(list))
+		for _, addr := range list {
+			result = append(result, Address{Name: addr.Name, Address: addr.Address})
+		}
+		return result
+	}
+
+	// 部分邮件客户端生成的地址头不符合RFC 5322（如未加引号的逗号、裸露的8位字符）
+	var result []Address
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if addr, err := addressParser.Parse(part); err == nil {
+			result = append(result, Address{Name: addr.Name, Address: addr.Address})
+			continue
+		}
+
+		decoded, err := decoder.DecodeHeader(part)
+		if err != nil {
+			decoded = part
+		}
+		result = append(result, Address{Address: strings.Trim(decoded, "<> ")})
+	}
+	return result
+}
+
+// ExtractHeaders 提取邮件的全部头字段，RFC 2047编码的值会被解码
+func (p *EmailParser) ExtractHeaders(rawEmail string) map[string][]string {
+	msg, err := mail.ReadMessage(strings.NewReader(rawEmail))
+	if err != nil {
+		return nil
+	}
+	return p.decodeHeaders(msg.Header)
+}
+
+// decodeHeaders 解码全部头字段，键为规范化的头名称（如 X-Priority）
+func (p *EmailParser) decodeHeaders(header mail.Header) map[string][]string {
+	decoder := newWordDecoder()
+	headers := make(map[string][]string, len(header))
+	for name, values := range header {
+		decoded := make([]string, 0, len(values))
+		for _, value := range values {
+			decoded = append(decoded, p.decodeHeader(decoder, value))
+		}
+		headers[textproto.CanonicalMIMEHeaderKey(name)] = decoded
+	}
+	return headers
+}
+
+// parseEnvelope 从邮件头中提取发件人、收件人等信封信息
+func (p *EmailParser) parseEnvelope(header mail.Header, emailData *EmailData) {
+	decoder := newWordDecoder()
+
+	emailData.From = parseAddressList(decoder, header.Get("From"))
+	emailData.To = parseAddressList(decoder, header.Get("To"))
+	emailData.CC = parseAddressList(decoder, header.Get("Cc"))
+	emailData.BCC = parseAddressList(decoder, header.Get("Bcc"))
+	emailData.ReplyTo = parseAddressList(decoder, header.Get("Reply-To"))
+
+	if sender := parseAddressList(decoder, header.Get("Sender")); len(sender) > 0 {
+		emailData.SenderHeader = &sender[0]
+	}
+	emailData.applyFrom()
+}
+
+// applyEnvelope 从IMAP信封中提取发件人、收件人等信息
+func (emailData *EmailData) applyEnvelope(envelope *imap.Envelope) {
+	emailData.From = convertIMAPAddresses(envelope.From)
+	emailData.To = convertIMAPAddresses(envelope.To)
+	emailData.CC = convertIMAPAddresses(envelope.Cc)
+	emailData.BCC = convertIMAPAddresses(envelope.Bcc)
+	emailData.ReplyTo = convertIMAPAddresses(envelope.ReplyTo)
+
+	if sender := convertIMAPAddresses(envelope.Sender); len(sender) > 0 {
+		emailData.SenderHeader = &sender[0]
+	}
+	emailData.applyFrom()
+}
+
+// applyFrom 根据From列表设置发件人地址和名称
+// 服务器在没有Sender头时会以From填充，此时不再单独记录Sender
+func (emailData *EmailData) applyFrom() {
+	if len(emailData.From) > 0 {
+		emailData.Sender = emailData.From[0].Address
+		emailData.FromName = emailData.From[0].Name
+	}
+	if emailData.SenderHeader != nil && len(emailData.From) > 0 &&
+		strings.EqualFold(emailData.SenderHeader.Address, emailData.From[0].Address) {
+		emailData.SenderHeader = nil
+	}
+}
+
+// convertIMAPAddresses 转换IMAP信封地址列表，跳过RFC 3501中的组起止标记
+func convertIMAPAddresses(addresses []*imap.Address) []Address {
+	var result []Address
+	for _, addr := range addresses {
+		if addr == nil || addr.HostName == "" {
+			continue
+		}
+		result = append(result, Address{Name: addr.PersonalName, Address: addr.Address()})
+	}
+	return result
+}
@@ -0,0 +1,254 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"emailAlert/pkg/oauth"
+)
+
+// 邮箱健康状态
+const (
+	HealthStateHealthy   = "healthy"   // 最近一次检查成功
+	HealthStateFailing   = "failing"   // 连续检查失败，按退避间隔重试
+	HealthStateSuspended = "suspended" // 连续认证失败，已自动暂停监控
+)
+
+// MailboxStatusSuspended 自动暂停后的邮箱状态，需手动重新启用
+const MailboxStatusSuspended = "suspended"
+
+// 默认的退避和暂停策略
+const (
+	DefaultMaxBackoff       = 30 * time.Minute
+	DefaultAuthFailureLimit = 5
+
+	DefaultMessageFailureLimit = 3
+)
+
+// MailboxHealth 邮箱连接健康状态
+type MailboxHealth struct {
+	MailboxID           uint       `json:"mailbox_id"`
+	Name                string     `json:"name"`
+	State               string     `json:"state"`                   // healthy/failing/suspended
+	LastSuccess         *time.Time `json:"last_success,omitempty"`  // 最近一次成功检查的时间
+	LastFailure         *time.Time `json:"last_failure,omitempty"`  // 最近一次失败的时间
+	FailingSince        *time.Time `json:"failing_since,omitempty"` // 本轮连续失败开始的时间
+	ConsecutiveFailures int        `json:"consecutive_failures"`    // 连续失败次数
+	AuthFailures        int        `json:"auth_failures"`           // 连续认证失败次数
+	LastError           string     `json:"last_error,omitempty"`    // 最近一次失败的错误信息
+	NextRetry           *time.Time `json:"next_retry,omitempty"`    // 失败后下次重试的时间
+	SuspendedAt         *time.Time `json:"suspended_at,omitempty"`  // 自动暂停的时间
+	SkippedMessages     int        `json:"skipped_messages"`        // 多次处理失败后被跳过的邮件数
+	LastSkipped         string     `json:"last_skipped,omitempty"`  // 最近一封被跳过的邮件（文件夹/UID）
+}
+
+// MailboxSuspender 邮箱自动暂停的处理者，负责持久化暂停状态
+type MailboxSuspender interface {
+	SuspendMailbox(mailboxID uint, reason string)
+}
+
+// authError 登录认证失败，重试无法恢复，连续出现时自动暂停邮箱
+type authError struct {
+	err error
+}
+
+func (e *authError) Error() string {
+	return fmt.Sprintf("登录失败: %v", e.err)
+}
+
+func (e *authError) Unwrap() error {
+	return e.err
+}
+
+// loginError 包装登录失败，令牌端点暂时不可用（网络错误、5xx、限流）不是认证失败，不计入自动暂停
+func loginError(err error) error {
+	if oauth.IsTransient(err) {
+		return err
+	}
+	return &authError{err: err}
+}
+
+// isAuthError 判断是否为认证失败
+func isAuthError(err error) bool {
+	var target *authError
+	return errors.As(err, &target)
+}
+
+// SetSuspender 设置邮箱自动暂停的处理者
+func (m *Monitor) SetSuspender(suspender MailboxSuspender) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.suspender = suspender
+}
+
+// GetHealth 获取全部邮箱的健康状态，按邮箱ID排序
+func (m *Monitor) GetHealth() []MailboxHealth {
+	m.healthMutex.Lock()
+	defer m.healthMutex.Unlock()
+
+	result := make([]MailboxHealth, 0, len(m.health))
+	for _, health := range m.health {
+		result = append(result, *health)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].MailboxID < result[j].MailboxID })
+	return result
+}
+
+// resetHealth 清除邮箱的健康状态，邮箱重新启动监控时调用
+func (m *Monitor) resetHealth(mailboxID uint) {
+	m.healthMutex.Lock()
+	defer m.healthMutex.Unlock()
+	delete(m.health, mailboxID)
+}
+
+// mailboxHealth 获取邮箱的健康状态记录，不存在时创建，调用方需持有m.healthMutex
+func (m *Monitor) mailboxHealth(mailboxConfig MailboxConfig) *MailboxHealth {
+	health, ok := m.health[mailboxConfig.ID]
+	if !ok {
+		health = &MailboxHealth{MailboxID: mailboxConfig.ID}
+		m.health[mailboxConfig.ID] = health
+	}
+	health.Name = mailboxConfig.Name
+	return health
+}
+
+// recordSuccess 记录一次成功的检查
+func (m *Monitor) recordSuccess(mailboxConfig MailboxConfig) {
+	m.healthMutex.Lock()
+	defer m.healthMutex.Unlock()
+
+	now := time.Now()
+	health := m.mailboxHealth(mailboxConfig)
+	if health.State == HealthStateFailing {
+		log.Printf("邮箱监控: 邮箱 %s 已恢复（此前连续失败 %d 次）", mailboxConfig.Name, health.ConsecutiveFailures)
+	}
+	health.State = HealthStateHealthy
+	health.LastSuccess = &now
+	health.ConsecutiveFailures = 0
+	health.AuthFailures = 0
+	health.LastError = ""
+	health.NextRetry = nil
+	health.FailingSince = nil
+}
+
+// recordFailure 记录一次失败的检查，返回下次重试前的等待时间
+// base为退避的基础间隔；连续认证失败达到上限时暂停邮箱并返回suspended=true
+func (m *Monitor) recordFailure(mailboxConfig MailboxConfig, err error, base time.Duration) (delay time.Duration, suspended bool) {
+	m.healthMutex.Lock()
+
+	now := time.Now()
+	health := m.mailboxHealth(mailboxConfig)
+	health.State = HealthStateFailing
+	health.LastFailure = &now
+	if health.FailingSince == nil {
+		health.FailingSince = &now
+	}
+	health.ConsecutiveFailures++
+	health.LastError = err.Error()
+	if isAuthError(err) {
+		health.AuthFailures++
+	}
+
+	if limit := m.authFailureLimit(); limit > 0 && health.AuthFailures >= limit {
+		health.State = HealthStateSuspended
+		health.SuspendedAt = &now
+		health.NextRetry = nil
+		reason := fmt.Sprintf("连续 %d 次认证失败: %v", health.AuthFailures, err)
+		m.healthMutex.Unlock()
+
+		m.suspendMailbox(mailboxConfig, reason)
+		return 0, true
+	}
+
+	delay = m.backoffDelay(base, health.ConsecutiveFailures)
+	nextRetry := now.Add(delay)
+	health.NextRetry = &nextRetry
+	m.healthMutex.Unlock()
+
+	return delay, false
+}
+
+// nextCheckDelay 计算下次检查前的等待时间，连续失败时不短于退避间隔
+func (m *Monitor) nextCheckDelay(mailboxConfig MailboxConfig) time.Duration {
+	delay := m.checkInterval(mailboxConfig)
+
+	m.healthMutex.Lock()
+	defer m.healthMutex.Unlock()
+
+	if health, ok := m.health[mailboxConfig.ID]; ok && health.State == HealthStateFailing && health.NextRetry != nil {
+		if wait := time.Until(*health.NextRetry); wait > delay {
+			delay = wait
+		}
+	}
+	return delay
+}
+
+// suspendMailbox 停止邮箱的监控协程并通知处理者持久化暂停状态
+func (m *Monitor) suspendMailbox(mailboxConfig MailboxConfig, reason string) {
+	m.mutex.Lock()
+	m.stopWorker(mailboxConfig.ID)
+	for i := range m.mailboxes {
+		if m.mailboxes[i].ID == mailboxConfig.ID {
+			m.mailboxes[i].Status = MailboxStatusSuspended
+		}
+	}
+	suspender := m.suspender
+	m.mutex.Unlock()
+
+	log.Printf("邮箱监控: 邮箱 %s 已自动暂停监控: %s", mailboxConfig.Name, reason)
+	if suspender != nil {
+		suspender.SuspendMailbox(mailboxConfig.ID, reason)
+	}
+}
+
+// maxBackoff 获取退避间隔上限
+func (m *Monitor) maxBackoff() time.Duration {
+	if m.config.MaxBackoff > 0 {
+		return m.config.MaxBackoff
+	}
+	return DefaultMaxBackoff
+}
+
+// authFailureLimit 获取自动暂停前允许的连续认证失败次数，小于0表示不自动暂停
+func (m *Monitor) authFailureLimit() int {
+	if m.config.AuthFailureLimit == 0 {
+		return DefaultAuthFailureLimit
+	}
+	return m.config.AuthFailureLimit
+}
+
+// backoffDelay 计算第failures次失败后的退避时间：基础间隔按2的幂递增，不超过上限，
+// 并在 [delay/2, delay] 范围内随机抖动，避免大量邮箱同时重连
+func (m *Monitor) backoffDelay(base time.Duration, failures int) time.Duration {
+	max := m.maxBackoff()
+	if base <= 0 {
+		base = time.Second
+	}
+	delay := base
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+
+	half := delay / 2
+	return half + time.Duration(m.random(int64(delay-half)+1))
+}
+
+// sleepContext 等待指定时间，ctx取消时提前返回false
+func sleepContext(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,168 @@
+package email
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// recordingSuspender 记录自动暂停的邮箱
+type recordingSuspender struct {
+	suspended []uint
+}
+
+func (s *recordingSuspender) SuspendMailbox(mailboxID uint, reason string) {
+	s.suspended = append(s.suspended, mailboxID)
+}
+
+// newHealthTestMonitor 创建使用指定随机数来源的监控器
+func newHealthTestMonitor(maxBackoff time.Duration, authFailureLimit int, random func(n int64) int64) *Monitor {
+	config := DefaultMonitorConfig()
+	config.MaxBackoff = maxBackoff
+	config.AuthFailureLimit = authFailureLimit
+	monitor := NewMonitor(config, nil)
+	monitor.random = random
+	return monitor
+}
+
+// maxJitter 总是返回最大随机数，退避时间等于未抖动的间隔
+func maxJitter(n int64) int64 {
+	return n - 1
+}
+
+// TestBackoffDelay 退避间隔按2的幂递增且不超过上限，抖动范围为 [delay/2, delay]
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     time.Duration
+		max      time.Duration
+		failures int
+		want     time.Duration // 未抖动的间隔
+	}{
+		{"首次失败", time.Second, time.Minute, 1, time.Second},
+		{"第3次失败", time.Second, time.Minute, 3, 4 * time.Second},
+		{"达到上限", time.Second, time.Minute, 7, time.Minute},
+		{"大量失败不溢出", time.Second, time.Minute, 1000, time.Minute},
+		{"未设置基础间隔", 0, time.Minute, 2, 2 * time.Second},
+		{"基础间隔超过上限", 10 * time.Second, 5 * time.Second, 1, 5 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			low, high := tt.want/2, tt.want
+
+			min := newHealthTestMonitor(tt.max, 0, func(n int64) int64 {
+				if n != int64(high-low)+1 {
+					t.Fatalf("随机数范围为 %d，期望 %d", n, int64(high-low)+1)
+				}
+				return 0
+			})
+			if got := min.backoffDelay(tt.base, tt.failures); got != low {
+				t.Fatalf("最小抖动时退避 %v，期望 %v", got, low)
+			}
+			if got := newHealthTestMonitor(tt.max, 0, maxJitter).backoffDelay(tt.base, tt.failures); got != high {
+				t.Fatalf("最大抖动时退避 %v，期望 %v", got, high)
+			}
+
+			seeded := newHealthTestMonitor(tt.max, 0, rand.New(rand.NewSource(1)).Int63n)
+			for i := 0; i < 1000; i++ {
+				if got := seeded.backoffDelay(tt.base, tt.failures); got < low || got > high {
+					t.Fatalf("退避 %v 超出范围 [%v, %v]", got, low, high)
+				}
+			}
+		})
+	}
+}
+
+// TestRecordFailureSuspension 连续认证失败达到上限时暂停邮箱，网络错误不计入，成功后重新计数和退避
+func TestRecordFailureSuspension(t *testing.T) {
+	authErr := loginError(errors.New("invalid credentials"))
+	netErr := errors.New("connection reset by peer")
+
+	tests := []struct {
+		name       string
+		limit      int
+		steps      []error         // nil表示一次成功的检查
+		wantDelays []time.Duration // 每次失败后的退避时间，成功和暂停时为0
+		suspendAt  int             // 第几步暂停邮箱，0表示不暂停
+	}{
+		{
+			name:       "达到上限时暂停",
+			limit:      3,
+			steps:      []error{authErr, authErr, authErr},
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 0},
+			suspendAt:  3,
+		},
+		{
+			name:       "默认上限",
+			steps:      []error{authErr, authErr, authErr, authErr, authErr},
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 0},
+			suspendAt:  DefaultAuthFailureLimit,
+		},
+		{
+			name:       "网络错误不计入认证失败",
+			limit:      2,
+			steps:      []error{netErr, authErr, netErr, netErr, authErr},
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 0},
+			suspendAt:  5,
+		},
+		{
+			name:       "成功后重新计数",
+			limit:      3,
+			steps:      []error{authErr, authErr, nil, authErr, authErr, authErr},
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 0, time.Second, 2 * time.Second, 0},
+			suspendAt:  6,
+		},
+		{
+			name:       "不自动暂停",
+			limit:      -1,
+			steps:      []error{authErr, authErr, authErr, authErr, authErr, authErr},
+			wantDelays: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second, 8 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := newHealthTestMonitor(8*time.Second, tt.limit, maxJitter)
+			suspender := &recordingSuspender{}
+			monitor.SetSuspender(suspender)
+			mailbox := MailboxConfig{ID: 1, Name: "ops", Status: "active"}
+			monitor.mailboxes = []MailboxConfig{mailbox}
+
+			for i, err := range tt.steps {
+				step := i + 1
+				if err == nil {
+					monitor.recordSuccess(mailbox)
+					health := monitor.GetHealth()[0]
+					if health.State != HealthStateHealthy || health.ConsecutiveFailures != 0 || health.AuthFailures != 0 || health.NextRetry != nil {
+						t.Fatalf("第%d步成功后健康状态未重置: %+v", step, health)
+					}
+					continue
+				}
+
+				delay, suspended := monitor.recordFailure(mailbox, err, time.Second)
+				if suspended != (step == tt.suspendAt) {
+					t.Fatalf("第%d步 suspended=%v，期望在第%d步暂停", step, suspended, tt.suspendAt)
+				}
+				if delay != tt.wantDelays[i] {
+					t.Fatalf("第%d步退避 %v，期望 %v", step, delay, tt.wantDelays[i])
+				}
+			}
+
+			health := monitor.GetHealth()[0]
+			if tt.suspendAt == 0 {
+				if len(suspender.suspended) != 0 || health.State != HealthStateFailing {
+					t.Fatalf("不应暂停邮箱，实际暂停 %v，状态 %s", suspender.suspended, health.State)
+				}
+				return
+			}
+			if len(suspender.suspended) != 1 || suspender.suspended[0] != mailbox.ID {
+				t.Fatalf("应通知暂停邮箱一次，实际 %v", suspender.suspended)
+			}
+			if health.State != HealthStateSuspended || monitor.mailboxes[0].Status != MailboxStatusSuspended {
+				t.Fatalf("暂停后健康状态为 %s，邮箱状态为 %s", health.State, monitor.mailboxes[0].Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"emailAlert/pkg/timezone"
+
+	"github.com/emersion/go-imap/client"
+)
+
+// errIdleNotSupported 服务器未声明IDLE能力
+var errIdleNotSupported = errors.New("服务器不支持IDLE")
+
+const (
+	idleLogoutTimeout     = 25 * time.Minute // 定期重发IDLE，避免被服务器断开（RFC 2177建议不超过29分钟）
+	idleMinReconnectDelay = 1 * time.Second  // 重连最小等待时间
+	idleMaxReconnectDelay = 5 * time.Minute  // 重连最大等待时间
+	idleMaxSetupFailures  = 3                // 连续多少次未能进入IDLE后放弃推送模式，回退到轮询
+)
+
+// idleMailbox 使用IMAP IDLE推送模式监控邮箱
+// IDLE只能监听当前选中的文件夹，因此每个文件夹各自保持一个长连接。
+// 返回nil表示监控已停止，返回错误表示需要回退到轮询模式：
+// errIdleNotSupported为服务器不支持IDLE，其他错误为某个文件夹持续无法进入IDLE。
+func (m *Monitor) idleMailbox(ctx context.Context, mailboxConfig MailboxConfig) error {
+	m.setIdleState(mailboxConfig.ID, true)
+	defer m.setIdleState(mailboxConfig.ID, false)
+
+	idleCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	folders := m.mailboxFolders(mailboxConfig)
+	results := make(chan error, len(folders))
+	for _, folder := range folders {
+		go func(folder string) {
+			results <- m.idleFolder(idleCtx, mailboxConfig, folder)
+		}(folder)
+	}
+
+	// 轮询按邮箱整体检查，任一文件夹需要回退时停止其他文件夹的IDLE，整体回退
+	var result error
+	for range folders {
+		if err := <-results; err != nil && result == nil {
+			result = err
+			cancel()
+		}
+	}
+	return result
+}
+
+// idleFolder 使用IDLE监控单个文件夹
+// 收到EXISTS通知后立即拉取新邮件；连接断开时按指数退避（带随机抖动）重连。
+// 会话成功建立时健康状态恢复，中途断开后从最小间隔开始重连；
+// 连续idleMaxSetupFailures次未能进入IDLE时返回最后一次的错误，由调用方回退到轮询。
+func (m *Monitor) idleFolder(ctx context.Context, mailboxConfig MailboxConfig, folder string) error {
+	setupFailures := 0
+	for {
+		established, err := m.runIdleSession(ctx, mailboxConfig, folder)
+		if err == nil || err == errIdleNotSupported {
+			return err
+		}
+		if established {
+			setupFailures = 0
+		} else {
+			setupFailures++
+		}
+
+		// 计入健康状态，连续认证失败时邮箱被自动暂停
+		delay, suspended := m.recordFailure(mailboxConfig, err, idleMinReconnectDelay)
+		if suspended {
+			return nil
+		}
+		if setupFailures >= idleMaxSetupFailures {
+			return fmt.Errorf("文件夹 %s 连续 %d 次无法进入IDLE: %v", folder, setupFailures, err)
+		}
+		if delay > idleMaxReconnectDelay {
+			delay = idleMaxReconnectDelay
+		}
+
+		log.Printf("邮箱监控: 邮箱 %s/%s IDLE连接中断，%v 后重连: %v", mailboxConfig.Name, folder, delay.Round(time.Millisecond), err)
+
+		if !sleepContext(ctx, delay) {
+			log.Printf("邮箱监控: 停止监控邮箱 %s", mailboxConfig.Name)
+			return nil
+		}
+		mailboxConfig = m.currentMailboxConfig(mailboxConfig)
+	}
+}
+
+// runIdleSession 执行一次IDLE会话，直到连接出错或监控停止
+// established表示会话是否已成功进入IDLE状态
+func (m *Monitor) runIdleSession(ctx context.Context, mailboxConfig MailboxConfig, folder string) (established bool, err error) {
+	conn, err := m.connectIMAP(mailboxConfig)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Logout()
+
+	supported, err := conn.Support("IDLE")
+	if err != nil {
+		return false, fmt.Errorf("查询服务器能力失败: %v", err)
+	}
+	if !supported {
+		return false, errIdleNotSupported
+	}
+
+	// 接收服务器推送的邮箱更新，缓冲区避免拉取邮件期间阻塞连接
+	updates := make(chan client.Update, 100)
+	conn.Updates = updates
+
+	// 进入IDLE前先拉取一次，同时完成文件夹选择
+	if err := m.fetchNewEmailsWithConn(conn, mailboxConfig, folder); err != nil {
+		return false, err
+	}
+
+	m.recordSuccess(mailboxConfig)
+	log.Printf("邮箱监控: 邮箱 %s/%s 已进入IDLE推送模式", mailboxConfig.Name, folder)
+
+	// 定期检查监控时间段，时间段外到达的邮件在进入时间段后再处理
+	scheduleTicker := time.NewTicker(time.Minute)
+	defer scheduleTicker.Stop()
+
+	for {
+		stop := make(chan struct{})
+		idleDone := make(chan error, 1)
+		go func() {
+			idleDone <- conn.Idle(stop, &client.IdleOptions{LogoutTimeout: idleLogoutTimeout})
+		}()
+
+		pending := false
+	wait:
+		for {
+			select {
+			case update := <-updates:
+				// EXISTS/RECENT等邮箱状态变化
+				if _, ok := update.(*client.MailboxUpdate); ok {
+					mailboxConfig = m.currentMailboxConfig(mailboxConfig)
+					if inSchedule(mailboxConfig.Schedule, time.Now().In(timezone.System())) {
+						break wait
+					}
+					pending = true
+				}
+			case <-scheduleTicker.C:
+				mailboxConfig = m.currentMailboxConfig(mailboxConfig)
+				if pending && inSchedule(mailboxConfig.Schedule, time.Now().In(timezone.System())) {
+					break wait
+				}
+			case err := <-idleDone:
+				if err == nil {
+					err = fmt.Errorf("IDLE意外结束")
+				}
+				return true, err
+			case <-ctx.Done():
+				close(stop)
+				<-idleDone
+				log.Printf("邮箱监控: 停止监控邮箱 %s", mailboxConfig.Name)
+				return true, nil
+			}
+		}
+
+		close(stop)
+		if err := <-idleDone; err != nil {
+			return true, err
+		}
+
+		// 丢弃已积压的更新，本次拉取会一并处理；拉取期间新到的更新会触发下一轮拉取
+		for drained := false; !drained; {
+			select {
+			case <-updates:
+			default:
+				drained = true
+			}
+		}
+
+		if err := m.fetchNewEmailsWithConn(conn, mailboxConfig, folder); err != nil {
+			return true, err
+		}
+	}
+}
+
+// setIdleState 记录邮箱是否处于IDLE推送模式
+func (m *Monitor) setIdleState(mailboxID uint, active bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if active {
+		m.idleMailboxes[mailboxID] = true
+	} else {
+		delete(m.idleMailboxes, mailboxID)
+	}
+}
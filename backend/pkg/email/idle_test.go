@@ -0,0 +1,90 @@
+package email
+
+import (
+	"testing"
+	"time"
+)
+
+// idleCount 返回处于IDLE推送模式的邮箱数
+func idleCount(monitor *Monitor) int {
+	return monitor.GetStatus()["idle_count"].(int)
+}
+
+// TestIdleFallbackWhenNotSupported 服务器不支持IDLE时回退到轮询，新邮件仍能被处理
+func TestIdleFallbackWhenNotSupported(t *testing.T) {
+	srv := startTestIMAPServer(t, false, "INBOX")
+	handler := newRecordingHandler()
+	monitor := newTestMonitor(handler)
+
+	mailbox := srv.mailboxConfig(1)
+	mailbox.IdleMode = true
+	monitor.AddMailbox(mailbox)
+	if err := monitor.Start(); err != nil {
+		t.Fatalf("启动监控失败: %v", err)
+	}
+	defer monitor.Stop()
+
+	uid := srv.deliver("INBOX", "disk usage 95%")
+	waitFor(t, 5*time.Second, "轮询处理新邮件", func() bool { return handler.count("INBOX", uid) == 1 })
+	if n := idleCount(monitor); n != 0 {
+		t.Fatalf("回退到轮询后不应处于IDLE模式，实际 idle_count=%d", n)
+	}
+}
+
+// TestIdleFallbackOnPersistentError 某个文件夹持续无法进入IDLE时整体回退到轮询，其他文件夹的邮件仍能被处理
+func TestIdleFallbackOnPersistentError(t *testing.T) {
+	srv := startTestIMAPServer(t, true, "INBOX", "Archive")
+	// 访问验证时可以打开，之后文件夹被删除
+	srv.backend.removeAfter("Archive", 1)
+	handler := newRecordingHandler()
+	monitor := newTestMonitor(handler)
+
+	mailbox := srv.mailboxConfig(1, "INBOX", "Archive")
+	mailbox.IdleMode = true
+	monitor.AddMailbox(mailbox)
+	if err := monitor.Start(); err != nil {
+		t.Fatalf("启动监控失败: %v", err)
+	}
+	defer monitor.Stop()
+
+	waitFor(t, 5*time.Second, "进入IDLE模式", func() bool { return idleCount(monitor) == 1 })
+	waitFor(t, 10*time.Second, "回退到轮询模式", func() bool { return idleCount(monitor) == 0 })
+	if n := monitor.GetStatus()["worker_count"]; n != 1 {
+		t.Fatalf("回退后监控协程应继续运行，实际 worker_count=%v", n)
+	}
+
+	uid := srv.deliver("INBOX", "cpu usage 99%")
+	waitFor(t, 5*time.Second, "轮询处理新邮件", func() bool { return handler.count("INBOX", uid) == 1 })
+}
+
+// TestIdleReconnect IDLE连接断开后重连，断开期间到达的邮件在重连后被处理
+func TestIdleReconnect(t *testing.T) {
+	srv := startTestIMAPServer(t, true, "INBOX")
+	handler := newRecordingHandler()
+	monitor := newTestMonitor(handler)
+
+	mailbox := srv.mailboxConfig(1)
+	mailbox.IdleMode = true
+	mailbox.CheckInterval = time.Hour // 轮询不会在测试期间触发，新邮件只能通过IDLE推送处理
+	monitor.AddMailbox(mailbox)
+	if err := monitor.Start(); err != nil {
+		t.Fatalf("启动监控失败: %v", err)
+	}
+	defer monitor.Stop()
+
+	// 会话进入IDLE前会先拉取一次
+	initial := srv.deliver("INBOX", "disk usage 95%")
+	waitFor(t, 5*time.Second, "建立IDLE会话", func() bool { return handler.count("INBOX", initial) == 1 })
+	first := srv.deliver("INBOX", "cpu usage 99%")
+	waitFor(t, 5*time.Second, "IDLE推送新邮件", func() bool { return handler.count("INBOX", first) == 1 })
+
+	srv.dropConnections()
+	second := srv.deliver("INBOX", "memory usage 90%")
+	waitFor(t, 5*time.Second, "重连后处理断开期间的邮件", func() bool { return handler.count("INBOX", second) == 1 })
+
+	third := srv.deliver("INBOX", "load average 20")
+	waitFor(t, 5*time.Second, "重连后IDLE推送新邮件", func() bool { return handler.count("INBOX", third) == 1 })
+	if handler.count("INBOX", first) != 1 || idleCount(monitor) != 1 {
+		t.Fatalf("重连后应保持IDLE模式且不重复处理，first=%d idle_count=%d", handler.count("INBOX", first), idleCount(monitor))
+	}
+}
@@ -0,0 +1,466 @@
+package email
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/backend"
+	"github.com/emersion/go-imap/server"
+)
+
+// 测试IMAP服务器的账号
+const (
+	testIMAPUsername = "alert@example.com"
+	testIMAPPassword = "secret"
+)
+
+// testIMAPServer 基于go-imap服务端的测试IMAP服务器，邮件保存在内存中
+type testIMAPServer struct {
+	server   *server.Server
+	addr     *net.TCPAddr
+	backend  *testIMAPBackend
+	listener *testIMAPListener
+}
+
+// startTestIMAPServer 启动测试IMAP服务器，folders为服务器上的文件夹
+// idle为false时不声明IDLE能力，也不向客户端推送新邮件通知
+func startTestIMAPServer(t *testing.T, idle bool, folders ...string) *testIMAPServer {
+	t.Helper()
+	bkd := &testIMAPBackend{folders: make(map[string]*testIMAPFolder)}
+	for _, name := range folders {
+		bkd.folders[name] = &testIMAPFolder{backend: bkd, name: name, uidNext: 1}
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	listener := &testIMAPListener{Listener: l, idle: idle, conns: make(map[*testIMAPConn]bool)}
+	s := server.New(bkd)
+	s.AllowInsecureAuth = true
+	s.ErrorLog = log.New(io.Discard, "", 0)
+	go s.Serve(listener)
+	t.Cleanup(func() { s.Close() })
+
+	return &testIMAPServer{server: s, addr: l.Addr().(*net.TCPAddr), backend: bkd, listener: listener}
+}
+
+// mailboxConfig 返回连接该服务器的邮箱配置
+func (s *testIMAPServer) mailboxConfig(id uint, folders ...string) MailboxConfig {
+	return MailboxConfig{
+		ID:       id,
+		Name:     "test-" + strconv.Itoa(int(id)),
+		Email:    testIMAPUsername,
+		Host:     s.addr.IP.String(),
+		Port:     s.addr.Port,
+		Username: testIMAPUsername,
+		Password: testIMAPPassword,
+		Protocol: "imap",
+		Status:   "active",
+		Folders:  folders,
+		Proxy:    "direct",
+	}
+}
+
+// deliver 向文件夹投递一封新邮件，支持IDLE时通知全部连接
+func (s *testIMAPServer) deliver(folder, subject string) uint32 {
+	uid, exists := s.backend.deliver(folder, subject)
+	if s.listener.idle {
+		s.listener.notify(exists)
+	}
+	return uid
+}
+
+// dropConnections 断开全部客户端连接，模拟网络中断
+func (s *testIMAPServer) dropConnections() {
+	s.server.ForEachConn(func(conn server.Conn) { conn.Close() })
+}
+
+// testIMAPBackend 测试服务器的内存后端，只有一个账号
+type testIMAPBackend struct {
+	mutex   sync.Mutex
+	folders map[string]*testIMAPFolder
+	opens   map[string]int // 文件夹还可以被打开的次数，用完后视为已删除
+}
+
+// removeAfter 文件夹再被打开opens次后视为已删除
+func (b *testIMAPBackend) removeAfter(folder string, opens int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.opens == nil {
+		b.opens = make(map[string]int)
+	}
+	b.opens[folder] = opens
+}
+
+// deliver 向文件夹添加一封邮件，返回邮件UID和文件夹中的邮件数
+func (b *testIMAPBackend) deliver(folder, subject string) (uint32, int) {
+	b.mutex.Lock()
+	f := b.folders[folder]
+	uid := f.uidNext
+	f.uidNext++
+	date := time.Now().Add(time.Hour) // 晚于监控启动时间
+	raw := fmt.Sprintf("From: monitor@example.com\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMessage-ID: <%d@%s>\r\nContent-Type: text/plain\r\n\r\n%s body\r\n",
+		testIMAPUsername, subject, date.Format(time.RFC1123Z), uid, folder, subject)
+	f.messages = append(f.messages, &testIMAPMessage{uid: uid, date: date, subject: subject, raw: []byte(raw)})
+	exists := len(f.messages)
+	b.mutex.Unlock()
+	return uid, exists
+}
+
+func (b *testIMAPBackend) Login(_ *imap.ConnInfo, username, password string) (backend.User, error) {
+	if username != testIMAPUsername || password != testIMAPPassword {
+		return nil, backend.ErrInvalidCredentials
+	}
+	return &testIMAPUser{backend: b}, nil
+}
+
+// testIMAPUser 测试账号
+type testIMAPUser struct {
+	backend *testIMAPBackend
+}
+
+func (u *testIMAPUser) Username() string { return testIMAPUsername }
+
+func (u *testIMAPUser) ListMailboxes(bool) ([]backend.Mailbox, error) {
+	u.backend.mutex.Lock()
+	defer u.backend.mutex.Unlock()
+	var mailboxes []backend.Mailbox
+	for _, f := range u.backend.folders {
+		mailboxes = append(mailboxes, f)
+	}
+	return mailboxes, nil
+}
+
+func (u *testIMAPUser) GetMailbox(name string) (backend.Mailbox, error) {
+	b := u.backend
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	f, ok := b.folders[name]
+	if !ok {
+		return nil, backend.ErrNoSuchMailbox
+	}
+	if remaining, limited := b.opens[name]; limited {
+		if remaining <= 0 {
+			return nil, backend.ErrNoSuchMailbox
+		}
+		b.opens[name] = remaining - 1
+	}
+	return f, nil
+}
+
+func (u *testIMAPUser) CreateMailbox(string) error         { return errors.New("不支持") }
+func (u *testIMAPUser) DeleteMailbox(string) error         { return errors.New("不支持") }
+func (u *testIMAPUser) RenameMailbox(string, string) error { return errors.New("不支持") }
+func (u *testIMAPUser) Logout() error                      { return nil }
+
+// testIMAPFolder 测试文件夹
+type testIMAPFolder struct {
+	backend  *testIMAPBackend
+	name     string
+	uidNext  uint32
+	messages []*testIMAPMessage
+}
+
+// testIMAPMessage 测试邮件
+type testIMAPMessage struct {
+	uid     uint32
+	date    time.Time
+	subject string
+	flags   []string
+	raw     []byte
+}
+
+func (f *testIMAPFolder) Name() string { return f.name }
+
+func (f *testIMAPFolder) Info() (*imap.MailboxInfo, error) {
+	return &imap.MailboxInfo{Name: f.name, Delimiter: "/"}, nil
+}
+
+// status 生成文件夹状态，调用方需持有backend.mutex
+func (f *testIMAPFolder) status(items []imap.StatusItem) *imap.MailboxStatus {
+	status := imap.NewMailboxStatus(f.name, items)
+	status.Flags = []string{imap.SeenFlag, imap.DeletedFlag}
+	status.PermanentFlags = []string{"\\*"}
+	for _, item := range items {
+		switch item {
+		case imap.StatusMessages:
+			status.Messages = uint32(len(f.messages))
+		case imap.StatusUidNext:
+			status.UidNext = f.uidNext
+		case imap.StatusUidValidity:
+			status.UidValidity = 1
+		}
+	}
+	return status
+}
+
+func (f *testIMAPFolder) Status(items []imap.StatusItem) (*imap.MailboxStatus, error) {
+	f.backend.mutex.Lock()
+	defer f.backend.mutex.Unlock()
+	return f.status(items), nil
+}
+
+func (f *testIMAPFolder) SetSubscribed(bool) error { return nil }
+func (f *testIMAPFolder) Check() error             { return nil }
+
+func (f *testIMAPFolder) ListMessages(uid bool, seqset *imap.SeqSet, items []imap.FetchItem, ch chan<- *imap.Message) error {
+	defer close(ch)
+	f.backend.mutex.Lock()
+	messages := make([]testIMAPMessage, len(f.messages))
+	for i, msg := range f.messages {
+		messages[i] = *msg
+		messages[i].flags = append([]string(nil), msg.flags...)
+	}
+	f.backend.mutex.Unlock()
+
+	for i, msg := range messages {
+		seqNum := uint32(i + 1)
+		id := seqNum
+		if uid {
+			id = msg.uid
+		}
+		if !seqset.Contains(id) {
+			continue
+		}
+
+		fetched := imap.NewMessage(seqNum, items)
+		for _, item := range items {
+			switch item {
+			case imap.FetchEnvelope:
+				fetched.Envelope = &imap.Envelope{
+					Date:      msg.date,
+					Subject:   msg.subject,
+					From:      []*imap.Address{{MailboxName: "monitor", HostName: "example.com"}},
+					MessageId: fmt.Sprintf("<%d@%s>", msg.uid, f.name),
+				}
+			case imap.FetchFlags:
+				fetched.Flags = msg.flags
+			case imap.FetchRFC822Size:
+				fetched.Size = uint32(len(msg.raw))
+			case imap.FetchUid:
+				fetched.Uid = msg.uid
+			case imap.FetchInternalDate:
+				fetched.InternalDate = msg.date
+			default:
+				if section, err := imap.ParseBodySectionName(item); err == nil {
+					fetched.Body[section] = bytes.NewBuffer(msg.raw)
+				}
+			}
+		}
+		ch <- fetched
+	}
+	return nil
+}
+
+func (f *testIMAPFolder) SearchMessages(uid bool, criteria *imap.SearchCriteria) ([]uint32, error) {
+	f.backend.mutex.Lock()
+	defer f.backend.mutex.Unlock()
+
+	var ids []uint32
+	for i, msg := range f.messages {
+		if criteria.Uid != nil && !criteria.Uid.Contains(msg.uid) {
+			continue
+		}
+		if !criteria.Since.IsZero() && msg.date.Before(criteria.Since) {
+			continue
+		}
+		seen := false
+		for _, flag := range msg.flags {
+			seen = seen || flag == imap.SeenFlag
+		}
+		if seen && len(criteria.WithoutFlags) > 0 {
+			continue
+		}
+		if uid {
+			ids = append(ids, msg.uid)
+		} else {
+			ids = append(ids, uint32(i+1))
+		}
+	}
+	return ids, nil
+}
+
+func (f *testIMAPFolder) CreateMessage([]string, time.Time, imap.Literal) error {
+	return errors.New("不支持")
+}
+
+func (f *testIMAPFolder) UpdateMessagesFlags(uid bool, seqset *imap.SeqSet, op imap.FlagsOp, flags []string) error {
+	f.backend.mutex.Lock()
+	defer f.backend.mutex.Unlock()
+	for i, msg := range f.messages {
+		id := uint32(i + 1)
+		if uid {
+			id = msg.uid
+		}
+		if seqset.Contains(id) && op == imap.AddFlags {
+			msg.flags = append(msg.flags, flags...)
+		}
+	}
+	return nil
+}
+
+func (f *testIMAPFolder) CopyMessages(bool, *imap.SeqSet, string) error {
+	return errors.New("不支持")
+}
+func (f *testIMAPFolder) Expunge() error { return nil }
+
+// testIMAPListener 记录测试服务器的连接，idle为false时从服务器响应中去掉IDLE能力
+// 新邮件通知直接写入连接，不使用go-imap服务端的更新推送，其实现与连接上的SELECT存在数据竞争
+type testIMAPListener struct {
+	net.Listener
+	idle  bool
+	mutex sync.Mutex
+	conns map[*testIMAPConn]bool
+}
+
+func (l *testIMAPListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	c := &testIMAPConn{Conn: conn, listener: l}
+	l.mutex.Lock()
+	l.conns[c] = true
+	l.mutex.Unlock()
+	return c, nil
+}
+
+// notify 向已收到问候语的连接发送EXISTS通知
+func (l *testIMAPListener) notify(exists int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for c := range l.conns {
+		c.writeUntagged(fmt.Sprintf("* %d EXISTS\r\n", exists))
+	}
+}
+
+// testIMAPConn 测试服务器的连接，服务端响应和注入的通知串行写入
+type testIMAPConn struct {
+	net.Conn
+	listener *testIMAPListener
+	mutex    sync.Mutex
+	greeted  bool
+}
+
+func (c *testIMAPConn) Write(b []byte) (int, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.greeted = true
+	data := b
+	if !c.listener.idle {
+		data = bytes.ReplaceAll(b, []byte(" IDLE"), nil)
+	}
+	if _, err := c.Conn.Write(data); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// writeUntagged 在两次服务端响应之间写入一条未标记响应
+func (c *testIMAPConn) writeUntagged(line string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.greeted {
+		c.Conn.Write([]byte(line))
+	}
+}
+
+func (c *testIMAPConn) Close() error {
+	c.listener.mutex.Lock()
+	delete(c.listener.conns, c)
+	c.listener.mutex.Unlock()
+	return c.Conn.Close()
+}
+
+// recordingHandler 记录收到的邮件，block不为nil时第一封邮件的处理会阻塞到block关闭
+type recordingHandler struct {
+	mutex   sync.Mutex
+	counts  map[string]int // 按文件夹/UID统计处理次数
+	entered chan struct{}  // 每次进入HandleEmail时发送一次
+	fail    map[int]bool   // 处理时返回错误的UID
+	block   chan struct{}
+	blocked bool
+	active  int // 正在处理的邮件数，用于检测并发处理
+	overlap bool
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{counts: make(map[string]int), entered: make(chan struct{}, 100)}
+}
+
+func (h *recordingHandler) HandleEmail(mailboxID uint, email *EmailData) (*HandleResult, error) {
+	h.mutex.Lock()
+	h.counts[fmt.Sprintf("%s/%d", email.Folder, email.UID)]++
+	h.active++
+	if h.active > 1 {
+		h.overlap = true
+	}
+	block := h.block
+	if block != nil && !h.blocked {
+		h.blocked = true
+	} else {
+		block = nil
+	}
+	h.mutex.Unlock()
+
+	h.entered <- struct{}{}
+	if block != nil {
+		<-block
+	}
+
+	h.mutex.Lock()
+	h.active--
+	fail := h.fail[email.UID]
+	h.mutex.Unlock()
+	if fail {
+		return nil, errors.New("处理失败")
+	}
+	return nil, nil
+}
+
+// count 返回邮件被处理的次数
+func (h *recordingHandler) count(folder string, uid uint32) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.counts[fmt.Sprintf("%s/%d", folder, uid)]
+}
+
+// waitEntered 等待下一次HandleEmail调用
+func (h *recordingHandler) waitEntered(t *testing.T, timeout time.Duration) {
+	t.Helper()
+	select {
+	case <-h.entered:
+	case <-time.After(timeout):
+		t.Fatal("等待邮件处理超时")
+	}
+}
+
+// waitFor 轮询等待条件成立
+func waitFor(t *testing.T, timeout time.Duration, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待超时: %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newTestMonitor 创建检查间隔和退避间隔都很短的监控器
+func newTestMonitor(handler EmailHandler) *Monitor {
+	config := DefaultMonitorConfig()
+	config.CheckInterval = 50 * time.Millisecond
+	config.MaxBackoff = 50 * time.Millisecond
+	return NewMonitor(config, handler)
+}
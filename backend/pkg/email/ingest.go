@@ -0,0 +1,158 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 导入文件格式
+const (
+	ImportFormatAuto    = "auto"    // 根据文件名和内容自动识别
+	ImportFormatEML     = "eml"     // 单封RFC822邮件
+	ImportFormatMbox    = "mbox"    // mbox邮箱文件（mboxo/mboxrd）
+	ImportFormatMaildir = "maildir" // Maildir目录
+)
+
+// RawMessageHandler 导入时逐封回调原始邮件，name为邮件来源（文件名或mbox中的序号）
+type RawMessageHandler func(name, raw string) error
+
+// DetectImportFormat 根据文件名和开头内容识别导入格式
+func DetectImportFormat(filename string, head []byte) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mbox", ".mbx":
+		return ImportFormatMbox
+	case ".eml":
+		return ImportFormatEML
+	}
+	// mbox文件以 "From " 分隔行开头，普通邮件的首行是头字段
+	if bytes.HasPrefix(head, []byte("From ")) {
+		return ImportFormatMbox
+	}
+	return ImportFormatEML
+}
+
+// ReadMessages 按格式读取文件中的邮件，format为auto时自动识别
+func ReadMessages(filename, format string, r io.Reader, handler RawMessageHandler) error {
+	reader := bufio.NewReaderSize(r, 64*1024)
+	if format == "" || format == ImportFormatAuto {
+		head, _ := reader.Peek(5)
+		format = DetectImportFormat(filename, head)
+	}
+
+	switch format {
+	case ImportFormatEML:
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("读取文件 %s 失败: %v", filename, err)
+		}
+		return handler(filename, string(data))
+	case ImportFormatMbox:
+		return readMbox(filename, reader, handler)
+	default:
+		return fmt.Errorf("不支持的导入格式: %s", format)
+	}
+}
+
+// readMbox 按 "From " 分隔行拆分mbox文件
+// 正文中被转义的 ">From " 行（mboxrd为 ">>From " 等）去掉一层转义
+func readMbox(filename string, reader *bufio.Reader, handler RawMessageHandler) error {
+	var message strings.Builder
+	count := 0
+	started := false
+	previousBlank := true
+
+	flush := func() error {
+		if !started {
+			return nil
+		}
+		count++
+		// 分隔行前的空行属于mbox格式，不属于邮件内容
+		raw := strings.TrimSuffix(message.String(), "\n")
+		raw = strings.TrimSuffix(raw, "\r")
+		message.Reset()
+		return handler(fmt.Sprintf("%s#%d", filename, count), raw)
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			if strings.HasPrefix(line, "From ") && previousBlank {
+				if flushErr := flush(); flushErr != nil {
+					return flushErr
+				}
+				started = true
+			} else if started {
+				if unescaped := strings.TrimLeft(line, ">"); len(unescaped) < len(line) && strings.HasPrefix(unescaped, "From ") {
+					line = line[1:]
+				}
+				message.WriteString(line)
+			}
+			previousBlank = strings.TrimRight(line, "\r\n") == ""
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("读取mbox文件 %s 失败: %v", filename, err)
+		}
+	}
+
+	if !started {
+		return fmt.Errorf("文件 %s 不是有效的mbox格式", filename)
+	}
+	return flush()
+}
+
+// ReadMaildir 读取Maildir目录cur和new子目录中的邮件，按文件名排序（Maildir文件名以投递时间开头）
+func ReadMaildir(dir string, handler RawMessageHandler) error {
+	if !IsMaildir(dir) {
+		return fmt.Errorf("%s 不是有效的Maildir目录（缺少cur/new子目录）", dir)
+	}
+
+	var files []string
+	for _, sub := range []string{"cur", "new"} {
+		entries, err := os.ReadDir(filepath.Join(dir, sub))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("读取Maildir目录失败: %v", err)
+		}
+		for _, entry := range entries {
+			// 跳过隐藏文件和子目录
+			if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			files = append(files, filepath.Join(dir, sub, entry.Name()))
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return filepath.Base(files[i]) < filepath.Base(files[j]) })
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("读取邮件文件 %s 失败: %v", path, err)
+		}
+		if err := handler(path, string(data)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsMaildir 判断目录是否为Maildir（包含cur或new子目录）
+func IsMaildir(dir string) bool {
+	for _, sub := range []string{"cur", "new"} {
+		if info, err := os.Stat(filepath.Join(dir, sub)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
@@ -1,54 +1,95 @@
 package email
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/base64"
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
+	"reflect"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"emailAlert/pkg/oauth"
+	"emailAlert/pkg/timezone"
+
 	"github.com/emersion/go-imap"
 	"github.com/emersion/go-imap/client"
 )
 
 // EmailData 邮件数据结构
 type EmailData struct {
-	UID         int              `json:"uid"`
-	Subject     string           `json:"subject"`
-	Sender      string           `json:"sender"`
-	Content     string           `json:"content"`
-	HTMLContent string           `json:"html_content"`
-	ReceivedAt  time.Time        `json:"received_at"`
-	Size        uint64           `json:"size"`
-	Flags       []string         `json:"flags"`
-	MessageID   string           `json:"message_id"`
-	Attachments []AttachmentData `json:"attachments"`
+	UID          int                 `json:"uid"`
+	Subject      string              `json:"subject"`
+	Sender       string              `json:"sender"`    // 发件人地址（From头第一个地址）
+	FromName     string              `json:"from_name"` // 发件人显示名称
+	From         []Address           `json:"from"`
+	SenderHeader *Address            `json:"sender_header,omitempty"` // Sender头，仅在与From不同时（代发）记录
+	To           []Address           `json:"to"`
+	CC           []Address           `json:"cc"`
+	BCC          []Address           `json:"bcc"`
+	ReplyTo      []Address           `json:"reply_to"`
+	Content      string              `json:"content"`
+	HTMLContent  string              `json:"html_content"`
+	ReceivedAt   time.Time           `json:"received_at"`
+	Size         uint64              `json:"size"`
+	Flags        []string            `json:"flags"`
+	MessageID    string              `json:"message_id"`
+	UIDL         string              `json:"uidl,omitempty"` // POP3邮件的UIDL（跨会话稳定的唯一标识），没有MessageID时据此去重
+	Folder       string              `json:"folder"`         // 邮件所在文件夹
+	Attachments  []AttachmentData    `json:"attachments"`
+	Headers      map[string][]string `json:"headers"`  // 全部邮件头，键为规范化的头名称
+	Resumed      bool                `json:"resumed"`  // 是否为从检查点恢复后获取的邮件（停机期间到达，不受启动时间限制）
+	Imported     bool                `json:"imported"` // 是否为通过.eml/mbox/Maildir文件导入的邮件（不受启动时间限制）
+	Pushed       bool                `json:"pushed"`   // 是否为内置SMTP/LMTP接收服务推送的邮件（不受启动时间限制）
 }
 
 // AttachmentData 附件数据结构
 type AttachmentData struct {
-	Name     string `json:"name"`
-	Size     int64  `json:"size"`
-	MimeType string `json:"mime_type"`
-	Content  []byte `json:"content"`
+	Name        string `json:"name"`
+	Size        int64  `json:"size"`
+	MimeType    string `json:"mime_type"`
+	SHA256      string `json:"sha256"`
+	Content     []byte `json:"content"`                // 附件原始内容，超过大小上限时为空
+	TextContent string `json:"text_content,omitempty"` // 文本/CSV附件解码后的内容
 }
 
 // MailboxConfig 邮箱配置结构
 type MailboxConfig struct {
-	ID       uint   `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Protocol string `json:"protocol"`
-	SSL      bool   `json:"ssl"`
-	Status   string `json:"status"`
+	ID       uint     `json:"id"`
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Protocol string   `json:"protocol"`
+	SSL      bool     `json:"ssl"`
+	Status   string   `json:"status"`
+	Folders  []string `json:"folders"` // 监控的文件夹，为空时使用默认文件夹
+
+	AuthType string       `json:"auth_type"` // 认证方式：password（默认）/oauth2
+	OAuth    oauth.Config `json:"oauth"`     // OAuth2凭据，AuthType为oauth2时使用
+
+	TLS   TLSOptions `json:"tls"`   // TLS连接配置：STARTTLS、自定义CA、客户端证书等
+	Proxy string     `json:"proxy"` // 代理地址（socks5://或http://），为空时使用全局代理，为direct时直连
+
+	// 以下为邮箱级监控策略，零值表示使用MonitorConfig中的全局配置
+	CheckInterval time.Duration `json:"check_interval"` // 检查间隔
+	MaxRetries    int           `json:"max_retries"`    // 最大重试次数
+	RetryInterval time.Duration `json:"retry_interval"` // 重试基础间隔
+	OnlyUnread    *bool         `json:"only_unread"`    // 是否只处理未读邮件，为nil时使用全局配置
+	MarkAsRead    *bool         `json:"mark_as_read"`   // 处理后是否标记为已读，为nil时使用全局配置
+	Schedule      string        `json:"schedule"`       // 监控时间段，如 "08:00-20:00"，为空表示全天
+
+	DeleteAfterFetch bool `json:"delete_after_fetch"` // POP3：处理后是否删除服务器上的邮件
+	IdleMode         bool `json:"idle_mode"`          // IMAP：是否使用IDLE推送模式，服务器不支持时回退到轮询
+
+	MatchedActions   []MailboxAction `json:"matched_actions"`   // 匹配规则后对邮件执行的动作
+	UnmatchedActions []MailboxAction `json:"unmatched_actions"` // 未匹配规则时对邮件执行的动作
 }
 
 // MonitorConfig 监控配置
@@ -58,6 +99,13 @@ type MonitorConfig struct {
 	Folder        string        // 监控的文件夹，默认为INBOX
 	MarkAsRead    bool          // 是否标记为已读
 	OnlyUnread    bool          // 是否只处理未读邮件
+
+	AttachmentContentLimit int64 // 保留附件内容的大小上限（字节），超过时只记录元数据
+
+	MaxBackoff       time.Duration // 连续失败时重试间隔的上限
+	AuthFailureLimit int           // 连续认证失败达到该次数时自动暂停邮箱，0使用默认值，小于0不暂停
+
+	MessageFailureLimit int // 单封邮件处理失败达到该次数时跳过该邮件，检查点越过它继续推进，0使用默认值，小于0一直重试
 }
 
 // DefaultMonitorConfig 默认监控配置
@@ -68,27 +116,47 @@ func DefaultMonitorConfig() *MonitorConfig {
 		Folder:        "INBOX",
 		MarkAsRead:    false,
 		OnlyUnread:    false, // 临时改为false，监控所有邮件
+
+		AttachmentContentLimit: DefaultAttachmentContentLimit,
+
+		MaxBackoff:       DefaultMaxBackoff,
+		AuthFailureLimit: DefaultAuthFailureLimit,
+
+		MessageFailureLimit: DefaultMessageFailureLimit,
 	}
 }
 
 // EmailHandler 邮件处理器接口
+// 返回的HandleResult决定处理后对邮件执行的动作，为nil时（如重复或跳过的邮件）不执行任何动作
 type EmailHandler interface {
-	HandleEmail(mailboxID uint, email *EmailData) error
+	HandleEmail(mailboxID uint, email *EmailData) (*HandleResult, error)
 }
 
 // Monitor 邮件监控器
 type Monitor struct {
-	mailboxes      []MailboxConfig
-	config         *MonitorConfig
-	handler        EmailHandler
-	stopChan       chan struct{}
-	wg             sync.WaitGroup
-	isRunning      bool
-	mutex          sync.RWMutex
-	lastCheckUID   map[uint]int         // 记录每个邮箱的最后检查UID
-	mailboxMutexes map[uint]*sync.Mutex // 每个邮箱的专用互斥锁，防止同一邮箱并发处理
-	startTime      time.Time            // 监控启动时间，只处理此时间之后的邮件
-	parser         *EmailParser         // 邮件解析器
+	mailboxes       []MailboxConfig
+	config          *MonitorConfig
+	handler         EmailHandler
+	workers         map[uint]*mailboxWorker // 每个邮箱的监控协程
+	stopping        map[uint]chan struct{}  // 每个邮箱最近一个已停止但尚未退出的监控协程，退出时关闭
+	wg              sync.WaitGroup
+	isRunning       bool
+	mutex           sync.RWMutex
+	lastCheckUID    map[uint]map[string]int     // 记录每个邮箱各文件夹的最后检查UID
+	pop3SeenUIDs    map[uint]map[string]bool    // 记录每个POP3邮箱已处理的UIDL
+	idleMailboxes   map[uint]bool               // 当前处于IDLE推送模式的邮箱
+	uidValidity     map[uint]map[string]uint32  // 记录每个邮箱各文件夹的UIDVALIDITY
+	resumedFolders  map[uint]map[string]bool    // 本次运行从持久化检查点恢复的邮箱文件夹
+	messageFailures map[uint]map[messageKey]int // 每个邮箱处理失败待重试的邮件及失败次数
+	checkpointStore CheckpointStore             // 检查点持久化存储
+	mailboxMutexes  map[uint]*sync.Mutex        // 每个邮箱的专用互斥锁，防止同一邮箱并发处理
+	startTime       time.Time                   // 监控启动时间，只处理此时间之后的邮件
+	parser          *EmailParser                // 邮件解析器
+	random          func(n int64) int64         // 返回 [0, n) 的随机数，用于退避抖动
+
+	healthMutex sync.Mutex              // 保护health
+	health      map[uint]*MailboxHealth // 每个邮箱的连接健康状态
+	suspender   MailboxSuspender        // 邮箱自动暂停的处理者
 }
 
 // NewMonitor 创建新的邮件监控器
@@ -97,43 +165,78 @@ func NewMonitor(config *MonitorConfig, handler EmailHandler) *Monitor {
 		config = DefaultMonitorConfig()
 	}
 
+	parser := NewEmailParser()
+	parser.SetAttachmentContentLimit(config.AttachmentContentLimit)
+
 	return &Monitor{
-		config:         config,
-		handler:        handler,
-		stopChan:       make(chan struct{}),
-		lastCheckUID:   make(map[uint]int),
-		mailboxMutexes: make(map[uint]*sync.Mutex), // 初始化邮箱互斥锁映射
-		parser:         NewEmailParser(),           // 初始化邮件解析器
+		config:          config,
+		handler:         handler,
+		workers:         make(map[uint]*mailboxWorker),
+		stopping:        make(map[uint]chan struct{}),
+		lastCheckUID:    make(map[uint]map[string]int),
+		pop3SeenUIDs:    make(map[uint]map[string]bool),
+		idleMailboxes:   make(map[uint]bool),
+		uidValidity:     make(map[uint]map[string]uint32),
+		resumedFolders:  make(map[uint]map[string]bool),
+		messageFailures: make(map[uint]map[messageKey]int),
+		mailboxMutexes:  make(map[uint]*sync.Mutex), // 初始化邮箱互斥锁映射
+		parser:          parser,                     // 初始化邮件解析器
+		random:          rand.Int63n,
+		health:          make(map[uint]*MailboxHealth),
 	}
 }
 
+// mailboxWorker 单个邮箱的监控协程
+type mailboxWorker struct {
+	config MailboxConfig
+	cancel context.CancelFunc
+	done   chan struct{} // 协程退出时关闭
+}
+
 // AddMailbox 添加邮箱到监控列表
+// 监控运行中时立即为该邮箱启动（或以新配置重启）监控协程
 func (m *Monitor) AddMailbox(mailbox MailboxConfig) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
 	// 检查是否已存在
+	exists := false
 	for i, mb := range m.mailboxes {
 		if mb.ID == mailbox.ID {
 			m.mailboxes[i] = mailbox // 更新配置
-			return
+			exists = true
+			break
 		}
 	}
 
-	m.mailboxes = append(m.mailboxes, mailbox)
-	log.Printf("邮箱监控: 添加邮箱 %s (%s) 到监控列表", mailbox.Name, mailbox.Email)
+	if !exists {
+		m.mailboxes = append(m.mailboxes, mailbox)
+		log.Printf("邮箱监控: 添加邮箱 %s (%s) 到监控列表", mailbox.Name, mailbox.Email)
+	}
+
+	if m.isRunning {
+		m.stopWorker(mailbox.ID)
+		m.startWorker(mailbox)
+	}
 }
 
-// RemoveMailbox 从监控列表移除邮箱
+// RemoveMailbox 从监控列表移除邮箱，并停止该邮箱的监控协程
 func (m *Monitor) RemoveMailbox(mailboxID uint) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	m.stopWorker(mailboxID)
+
 	for i, mb := range m.mailboxes {
 		if mb.ID == mailboxID {
 			m.mailboxes = append(m.mailboxes[:i], m.mailboxes[i+1:]...)
 			delete(m.lastCheckUID, mailboxID)
+			delete(m.pop3SeenUIDs, mailboxID)
+			delete(m.uidValidity, mailboxID)
+			delete(m.resumedFolders, mailboxID)
+			delete(m.messageFailures, mailboxID)
 			delete(m.mailboxMutexes, mailboxID) // 清理邮箱专用互斥锁
+			m.resetHealth(mailboxID)
 			log.Printf("邮箱监控: 从监控列表移除邮箱 ID %d", mailboxID)
 			return
 		}
@@ -141,6 +244,7 @@ func (m *Monitor) RemoveMailbox(mailboxID uint) {
 }
 
 // UpdateMailboxes 更新邮箱列表
+// 监控运行中时只启停发生变化的邮箱，未变化的邮箱不受影响
 func (m *Monitor) UpdateMailboxes(mailboxes []MailboxConfig) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
@@ -158,10 +262,101 @@ func (m *Monitor) UpdateMailboxes(mailboxes []MailboxConfig) {
 			delete(m.mailboxMutexes, id) // 清理不存在邮箱的互斥锁
 		}
 	}
+	for id := range m.pop3SeenUIDs {
+		if !existingIDs[id] {
+			delete(m.pop3SeenUIDs, id)
+		}
+	}
+	for id := range m.uidValidity {
+		if !existingIDs[id] {
+			delete(m.uidValidity, id)
+			delete(m.resumedFolders, id)
+		}
+	}
+	for id := range m.messageFailures {
+		if !existingIDs[id] {
+			delete(m.messageFailures, id)
+		}
+	}
+	// 已暂停的邮箱保留健康状态，便于查看暂停原因
+	m.healthMutex.Lock()
+	for id, health := range m.health {
+		if !existingIDs[id] && health.State != HealthStateSuspended {
+			delete(m.health, id)
+		}
+	}
+	m.healthMutex.Unlock()
+
+	if m.isRunning {
+		for id := range m.workers {
+			if !existingIDs[id] {
+				m.stopWorker(id)
+			}
+		}
+		for _, mailbox := range mailboxes {
+			if worker, ok := m.workers[mailbox.ID]; ok && reflect.DeepEqual(worker.config, mailbox) {
+				continue
+			}
+			m.stopWorker(mailbox.ID)
+			m.startWorker(mailbox)
+		}
+	}
 
 	log.Printf("邮箱监控: 更新邮箱列表，当前监控 %d 个邮箱", len(mailboxes))
 }
 
+// startWorker 为邮箱启动监控协程，调用方需持有m.mutex
+func (m *Monitor) startWorker(mailbox MailboxConfig) {
+	// 推送邮箱由接收服务投递邮件，不需要轮询
+	if mailbox.Status != "active" || isPush(mailbox) {
+		return
+	}
+	// 重新启动监控时（如修改配置或重新启用）从健康状态开始
+	m.resetHealth(mailbox.ID)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	worker := &mailboxWorker{config: mailbox, cancel: cancel, done: make(chan struct{})}
+	m.workers[mailbox.ID] = worker
+	previous := m.stopping[mailbox.ID]
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer close(worker.done)
+
+		// 等待同一邮箱已停止的协程完成当前检查后再开始，避免新旧协程重复拉取和处理同一批邮件
+		// 本协程在等待期间被停止时也要等旧协程退出，后续启动的协程只需等待本协程即可
+		if previous != nil {
+			<-previous
+		}
+		if ctx.Err() == nil {
+			m.monitorMailbox(ctx, mailbox)
+		}
+
+		// 协程自行退出（如访问验证失败）时清理记录
+		m.mutex.Lock()
+		if m.workers[mailbox.ID] == worker {
+			delete(m.workers, mailbox.ID)
+		}
+		if m.stopping[mailbox.ID] == worker.done {
+			delete(m.stopping, mailbox.ID)
+		}
+		m.mutex.Unlock()
+		cancel()
+	}()
+}
+
+// stopWorker 停止邮箱的监控协程，调用方需持有m.mutex
+// 只通知协程退出而不等待（协程退出时需要获取m.mutex），协程在当前检查完成后退出；
+// 之后为该邮箱启动的协程会先等待其退出，保证同一邮箱不会有两个协程同时检查
+func (m *Monitor) stopWorker(mailboxID uint) {
+	if worker, ok := m.workers[mailboxID]; ok {
+		worker.cancel()
+		delete(m.workers, mailboxID)
+		m.stopping[mailboxID] = worker.done
+	}
+}
+
 // Start 开始监控
 func (m *Monitor) Start() error {
 	m.mutex.Lock()
@@ -172,23 +367,19 @@ func (m *Monitor) Start() error {
 	}
 
 	m.isRunning = true
-	m.stopChan = make(chan struct{})
 
-	// 使用北京时间作为启动时间
-	beijingTZ, err := time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		beijingTZ = time.FixedZone("CST", 8*3600) // 使用固定时区作为后备
-	}
-	m.startTime = time.Now().In(beijingTZ) // 记录监控启动时间（北京时间）
+	// 每次启动都重新读取持久化检查点，停止期间到达的邮件也会被处理
+	m.uidValidity = make(map[uint]map[string]uint32)
+	m.resumedFolders = make(map[uint]map[string]bool)
 
-	log.Printf("邮箱监控: 开始监控，检查间隔 %v，启动时间 %v", m.config.CheckInterval, m.startTime.Format("2006-01-02 15:04:05"))
+	// 记录监控启动时间，内部统一使用UTC时刻比较
+	m.startTime = time.Now().UTC()
 
-	// 为每个邮箱启动一个goroutine
+	log.Printf("邮箱监控: 开始监控，检查间隔 %v，启动时间 %v", m.config.CheckInterval, m.startTime.In(timezone.System()).Format("2006-01-02 15:04:05"))
+
+	// 为每个邮箱启动一个监控协程
 	for _, mailbox := range m.mailboxes {
-		if mailbox.Status == "active" {
-			m.wg.Add(1)
-			go m.monitorMailbox(mailbox)
-		}
+		m.startWorker(mailbox)
 	}
 
 	return nil
@@ -203,7 +394,9 @@ func (m *Monitor) Stop() {
 	}
 
 	m.isRunning = false
-	close(m.stopChan)
+	for id := range m.workers {
+		m.stopWorker(id)
+	}
 	m.mutex.Unlock()
 
 	log.Printf("邮箱监控: 正在停止监控...")
@@ -240,42 +433,106 @@ func (m *Monitor) GetStatus() map[string]interface{} {
 		"mailbox_count":  len(m.mailboxes),
 		"check_interval": m.config.CheckInterval.String(),
 		"last_check_uid": m.lastCheckUID,
+		"idle_count":     len(m.idleMailboxes),
+		"worker_count":   len(m.workers),
 	}
 
+	// 邮箱连接健康状态
+	health := m.GetHealth()
+	failing, suspended := 0, 0
+	for _, h := range health {
+		switch h.State {
+		case HealthStateFailing:
+			failing++
+		case HealthStateSuspended:
+			suspended++
+		}
+	}
+	status["health"] = health
+	status["failing_count"] = failing
+	status["suspended_count"] = suspended
+
 	// 如果监控正在运行，添加启动时间信息
 	if m.isRunning && !m.startTime.IsZero() {
-		status["start_time"] = m.startTime.Format("2006-01-02 15:04:05")
+		status["start_time"] = m.startTime.In(timezone.System()).Format("2006-01-02 15:04:05")
 		status["running_duration"] = time.Since(m.startTime).String()
 	}
 
 	return status
 }
 
-// monitorMailbox 监控单个邮箱
-func (m *Monitor) monitorMailbox(mailboxConfig MailboxConfig) {
-	defer m.wg.Done()
+// GetStartTime 获取监控启动时间（UTC），未运行时返回零值
+func (m *Monitor) GetStartTime() time.Time {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	if !m.isRunning {
+		return time.Time{}
+	}
+	return m.startTime
+}
+
+// monitorMailbox 监控单个邮箱，ctx取消时退出
+func (m *Monitor) monitorMailbox(ctx context.Context, mailboxConfig MailboxConfig) {
 
 	log.Printf("邮箱监控: 开始监控邮箱 %s (%s)", mailboxConfig.Name, mailboxConfig.Email)
 
-	// 在开始监控前验证邮箱访问权限
-	if err := m.validateMailboxAccess(mailboxConfig); err != nil {
-		log.Printf("邮箱监控: 邮箱 %s 访问验证失败，跳过监控: %v", mailboxConfig.Name, err)
-		return
+	// 在开始监控前验证邮箱访问权限，失败时按指数退避重试，直到成功、邮箱被暂停或监控停止
+	for {
+		err := m.validateMailboxAccess(mailboxConfig)
+		if err == nil {
+			m.recordSuccess(mailboxConfig)
+			break
+		}
+
+		delay, suspended := m.recordFailure(mailboxConfig, err, m.checkInterval(mailboxConfig))
+		if suspended {
+			return
+		}
+		log.Printf("邮箱监控: 邮箱 %s 访问验证失败，%v 后重试: %v", mailboxConfig.Name, delay.Round(time.Second), err)
+
+		if !sleepContext(ctx, delay) {
+			log.Printf("邮箱监控: 停止监控邮箱 %s", mailboxConfig.Name)
+			return
+		}
+		mailboxConfig = m.currentMailboxConfig(mailboxConfig)
 	}
 
 	log.Printf("邮箱监控: 邮箱 %s 访问验证成功，开始监控", mailboxConfig.Name)
 
-	ticker := time.NewTicker(m.config.CheckInterval)
-	defer ticker.Stop()
+	// IDLE推送模式，服务器不支持IDLE或文件夹持续无法进入IDLE时回退到轮询
+	if mailboxConfig.IdleMode && !isPOP3(mailboxConfig) {
+		err := m.idleMailbox(ctx, mailboxConfig)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if err == errIdleNotSupported {
+			log.Printf("邮箱监控: 邮箱 %s 服务器不支持IDLE，回退到轮询模式", mailboxConfig.Name)
+		} else {
+			log.Printf("邮箱监控: 邮箱 %s IDLE连接持续失败，回退到轮询模式: %v", mailboxConfig.Name, err)
+		}
+	}
 
 	// 立即执行一次检查
-	m.checkMailbox(mailboxConfig)
+	if inSchedule(mailboxConfig.Schedule, time.Now().In(timezone.System())) {
+		m.checkMailbox(ctx, mailboxConfig)
+	}
 
 	for {
+		// 每轮读取最新配置，检查间隔等修改无需重启即可生效；连续失败时按退避间隔延后检查
+		mailboxConfig = m.currentMailboxConfig(mailboxConfig)
+		timer := time.NewTimer(m.nextCheckDelay(mailboxConfig))
+
 		select {
-		case <-ticker.C:
-			m.checkMailbox(mailboxConfig)
-		case <-m.stopChan:
+		case <-timer.C:
+			mailboxConfig = m.currentMailboxConfig(mailboxConfig)
+			// 不在监控时间段内时跳过，期间到达的邮件在下次检查时处理
+			if !inSchedule(mailboxConfig.Schedule, time.Now().In(timezone.System())) {
+				continue
+			}
+			m.checkMailbox(ctx, mailboxConfig)
+		case <-ctx.Done():
+			timer.Stop()
 			log.Printf("邮箱监控: 停止监控邮箱 %s", mailboxConfig.Name)
 			return
 		}
@@ -283,71 +540,72 @@ func (m *Monitor) monitorMailbox(mailboxConfig MailboxConfig) {
 }
 
 // checkMailbox 检查邮箱是否有新邮件
-func (m *Monitor) checkMailbox(mailboxConfig MailboxConfig) {
-	retries := 0
-	maxRetries := m.config.MaxRetries
+// 失败时按指数退避（带随机抖动）重试，认证失败不重试；最终失败计入健康状态并延后下次检查
+func (m *Monitor) checkMailbox(ctx context.Context, mailboxConfig MailboxConfig) {
+	maxRetries := m.maxRetries(mailboxConfig)
+	retryInterval := m.retryInterval(mailboxConfig)
 
-	for retries <= maxRetries {
-		err := m.fetchNewEmails(mailboxConfig)
-		if err == nil {
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = m.fetchNewEmails(mailboxConfig); err == nil {
+			m.recordSuccess(mailboxConfig)
+			return
+		}
+		if isAuthError(err) || attempt >= maxRetries {
 			break
 		}
 
-		retries++
-		if retries <= maxRetries {
-			log.Printf("邮箱监控: 检查邮箱 %s 失败，重试 %d/%d: %v", mailboxConfig.Name, retries, maxRetries, err)
-			time.Sleep(time.Duration(retries) * time.Second)
-		} else {
-			log.Printf("邮箱监控: 检查邮箱 %s 失败，已达最大重试次数: %v", mailboxConfig.Name, err)
+		delay := m.backoffDelay(retryInterval, attempt+1)
+		log.Printf("邮箱监控: 检查邮箱 %s 失败，%v 后重试 %d/%d: %v", mailboxConfig.Name, delay.Round(time.Millisecond), attempt+1, maxRetries, err)
+		if !sleepContext(ctx, delay) {
+			return
 		}
 	}
+
+	delay, suspended := m.recordFailure(mailboxConfig, err, m.checkInterval(mailboxConfig))
+	if !suspended {
+		log.Printf("邮箱监控: 检查邮箱 %s 失败，%v 后再次检查: %v", mailboxConfig.Name, delay.Round(time.Second), err)
+	}
 }
 
 // validateMailboxAccess 验证邮箱是否支持完整访问
 func (m *Monitor) validateMailboxAccess(mailboxConfig MailboxConfig) error {
-	addr := fmt.Sprintf("%s:%d", mailboxConfig.Host, mailboxConfig.Port)
-
-	var conn *client.Client
-	var err error
-
-	if mailboxConfig.SSL {
-		tlsConfig := &tls.Config{
-			ServerName: mailboxConfig.Host,
-		}
-		conn, err = client.DialTLS(addr, tlsConfig)
-	} else {
-		conn, err = client.Dial(addr)
+	if isPOP3(mailboxConfig) {
+		return m.validatePOP3Access(mailboxConfig)
 	}
 
+	conn, err := dialIMAP(mailboxConfig)
 	if err != nil {
-		return fmt.Errorf("连接服务器失败: %v", err)
+		return err
 	}
 	defer conn.Logout()
 
 	// 登录
-	if err := conn.Login(mailboxConfig.Username, mailboxConfig.Password); err != nil {
-		return fmt.Errorf("登录失败: %v", err)
+	if err := imapLogin(conn, mailboxConfig); err != nil {
+		return loginError(err)
 	}
 
-	// 针对126/163邮箱发送ID命令
-	if strings.Contains(mailboxConfig.Host, "126.com") || strings.Contains(mailboxConfig.Host, "163.com") {
+	// 服务商配置要求时发送ID命令（如126/163邮箱）
+	if shouldSendIMAPID(mailboxConfig.Host) {
 		err = sendIMAPIDInMonitor(conn)
 		if err != nil {
 			log.Printf("发送IMAP ID失败: %v", err)
 		} else {
-			log.Printf("邮箱监控: 检测到126/163邮箱，已发送ID命令")
+			log.Printf("邮箱监控: 已按服务商配置发送ID命令")
 		}
 	}
 
-	// 尝试访问INBOX文件夹
-	_, err = conn.Select("INBOX", true)
-	if err != nil {
-		if strings.Contains(err.Error(), "Unsafe Login") ||
-			strings.Contains(err.Error(), "authorization") ||
-			strings.Contains(err.Error(), "auth") {
-			return fmt.Errorf("邮箱安全限制，无法访问文件夹。请检查邮箱设置：\n1. 确保已开启IMAP服务\n2. 如果是126/163等邮箱，请使用授权码而非密码\n3. 检查邮箱安全设置\n错误详情: %v", err)
+	// 尝试访问所有监控的文件夹
+	for _, folder := range m.mailboxFolders(mailboxConfig) {
+		_, err = conn.Select(folder, true)
+		if err != nil {
+			if strings.Contains(err.Error(), "Unsafe Login") ||
+				strings.Contains(err.Error(), "authorization") ||
+				strings.Contains(err.Error(), "auth") {
+				return fmt.Errorf("邮箱安全限制，无法访问文件夹。请检查邮箱设置：\n1. 确保已开启IMAP服务\n2. 如果是126/163等邮箱，请使用授权码而非密码\n3. 检查邮箱安全设置\n错误详情: %v", err)
+			}
+			return fmt.Errorf("无法访问邮箱文件夹 %s: %v", folder, err)
 		}
-		return fmt.Errorf("无法访问邮箱文件夹: %v", err)
 	}
 
 	return nil
@@ -355,45 +613,61 @@ func (m *Monitor) validateMailboxAccess(mailboxConfig MailboxConfig) error {
 
 // fetchNewEmails 获取新邮件
 func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
-	// 创建IMAP连接
-	addr := fmt.Sprintf("%s:%d", mailboxConfig.Host, mailboxConfig.Port)
+	if isPOP3(mailboxConfig) {
+		return m.fetchNewEmailsPOP3(mailboxConfig)
+	}
 
-	var conn *client.Client
-	var err error
+	conn, err := m.connectIMAP(mailboxConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Logout()
 
-	if mailboxConfig.SSL {
-		tlsConfig := &tls.Config{
-			ServerName: mailboxConfig.Host,
+	// 依次检查每个文件夹，单个文件夹失败不影响其他文件夹
+	var errs []string
+	for _, folder := range m.mailboxFolders(mailboxConfig) {
+		if err := m.fetchNewEmailsWithConn(conn, mailboxConfig, folder); err != nil {
+			errs = append(errs, err.Error())
 		}
-		conn, err = client.DialTLS(addr, tlsConfig)
-	} else {
-		conn, err = client.Dial(addr)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
 	}
 
+	return nil
+}
+
+// connectIMAP 建立IMAP连接并完成登录
+func (m *Monitor) connectIMAP(mailboxConfig MailboxConfig) (*client.Client, error) {
+	conn, err := dialIMAP(mailboxConfig)
 	if err != nil {
-		return fmt.Errorf("连接服务器失败: %v", err)
+		return nil, err
 	}
-	defer conn.Logout()
 
 	// 登录
-	if err := conn.Login(mailboxConfig.Username, mailboxConfig.Password); err != nil {
-		return fmt.Errorf("登录失败: %v", err)
+	if err := imapLogin(conn, mailboxConfig); err != nil {
+		conn.Logout()
+		return nil, loginError(err)
 	}
 
-	// 针对126/163/阿里云企业邮箱发送ID命令
-	if strings.Contains(mailboxConfig.Host, "126.com") ||
-		strings.Contains(mailboxConfig.Host, "163.com") ||
-		strings.Contains(mailboxConfig.Host, "qiye.aliyun.com") {
+	// 服务商配置要求时发送ID命令（如126/163/阿里云企业邮箱）
+	if shouldSendIMAPID(mailboxConfig.Host) {
 		err = sendIMAPIDInMonitor(conn)
 		if err != nil {
 			log.Printf("发送IMAP ID失败: %v", err)
 		} else {
-			log.Printf("邮箱监控: 检测到企业邮箱，已发送ID命令")
+			log.Printf("邮箱监控: 已按服务商配置发送ID命令")
 		}
 	}
 
-	// 选择邮箱文件夹
-	mbox, err := conn.Select(m.config.Folder, true)
+	return conn, nil
+}
+
+// fetchNewEmailsWithConn 使用已登录的IMAP连接获取新邮件
+func (m *Monitor) fetchNewEmailsWithConn(conn *client.Client, mailboxConfig MailboxConfig, folder string) error {
+	// 选择邮箱文件夹，以读写模式打开以便标记已读和执行处理后动作（获取正文使用PEEK，不会改变已读状态）
+	markAsRead := m.markAsRead(mailboxConfig)
+	mbox, err := conn.Select(folder, false)
 	if err != nil {
 		// 检查是否是安全限制错误
 		if strings.Contains(err.Error(), "Unsafe Login") ||
@@ -401,24 +675,25 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 			strings.Contains(err.Error(), "auth") {
 			return fmt.Errorf("邮箱安全限制: %s。请检查邮箱设置，确保已开启IMAP服务并使用正确的授权码", err.Error())
 		}
-		return fmt.Errorf("选择文件夹 %s 失败: %v", m.config.Folder, err)
+		return fmt.Errorf("选择文件夹 %s 失败: %v", folder, err)
 	}
 
-	// 如果邮箱为空，返回
-	if mbox.Messages == 0 {
+	// 获取上次检查的UID，必要时从持久化检查点恢复
+	lastUID, resumed := m.prepareCheckpoint(mailboxConfig, folder, mbox)
+
+	// 如果邮箱为空，或UIDNEXT未超过上次处理的UID，说明没有新邮件
+	if mbox.Messages == 0 || (lastUID > 0 && mbox.UidNext <= uint32(lastUID+1)) {
+		m.commitCheckpoint(mailboxConfig, folder, mbox, lastUID)
 		return nil
 	}
 
-	// 获取上次检查的UID（原子操作）
-	lastUID := m.getAndPrepareLastCheckUID(mailboxConfig.ID)
-
-	// 修复阿里云企业邮箱兼容性问题 - 完全避免使用搜索
+	// 部分服务商（如阿里云企业邮箱）的SEARCH命令不可用，完全避免使用搜索
 	var uids []uint32
 	var searchErr error
 
-	// 针对阿里云企业邮箱：直接获取邮件而不使用搜索
-	if strings.Contains(mailboxConfig.Host, "qiye.aliyun.com") {
-		// 阿里云企业邮箱：直接使用UID范围，避免搜索命令
+	// 服务商不支持搜索：直接获取邮件而不使用搜索
+	if provider := LookupProvider(mailboxConfig.Host); provider != nil && provider.NoSearch {
+		// 直接使用UID范围，避免搜索命令
 		if lastUID > 0 {
 			// 从上次检查的UID+1开始到最新的UID
 			if mbox.UidNext > uint32(lastUID+1) {
@@ -433,8 +708,8 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 				}()
 
 				for msg := range messages {
-					// 只处理监控启动时间之后的邮件
-					if msg.Envelope != nil && msg.Envelope.Date.After(m.startTime) {
+					// 只处理监控启动时间之后的邮件，从检查点恢复时处理停机期间的全部邮件
+					if msg.Envelope != nil && (resumed || msg.Envelope.Date.After(m.startTime)) {
 						uids = append(uids, msg.Uid)
 					}
 				}
@@ -468,7 +743,7 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 				}
 			}
 		}
-		log.Printf("阿里云企业邮箱: 获取到 %d 个符合时间条件的UID（启动时间之后）", len(uids))
+		log.Printf("%s: 获取到 %d 个符合时间条件的UID（启动时间之后）", provider.Name, len(uids))
 	} else {
 		// 其他邮箱：使用搜索逻辑，添加时间过滤
 		var criteria *imap.SearchCriteria
@@ -478,6 +753,10 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 				Uid:   &imap.SeqSet{},
 				Since: m.startTime, // 添加时间过滤
 			}
+			// 从检查点恢复时不限制时间，停机期间到达的邮件也需要处理
+			if resumed {
+				criteria.Since = time.Time{}
+			}
 			criteria.Uid.AddRange(uint32(lastUID+1), mbox.UidNext-1)
 		} else {
 			// 首次检查：只获取监控启动时间之后的邮件
@@ -487,7 +766,7 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 		}
 
 		// 如果只处理未读邮件
-		if m.config.OnlyUnread {
+		if m.onlyUnread(mailboxConfig) {
 			criteria.WithoutFlags = []string{imap.SeenFlag}
 		}
 
@@ -496,14 +775,15 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 		if searchErr != nil {
 			return fmt.Errorf("搜索邮件失败: %v", searchErr)
 		}
-		log.Printf("邮箱 %s: 搜索到 %d 个符合时间条件的UID（启动时间之后）", mailboxConfig.Name, len(uids))
+		log.Printf("邮箱 %s/%s: 搜索到 %d 个符合时间条件的UID（启动时间之后）", mailboxConfig.Name, folder, len(uids))
 	}
 
 	if len(uids) == 0 {
+		m.commitCheckpoint(mailboxConfig, folder, mbox, lastUID)
 		return nil
 	}
 
-	log.Printf("邮箱监控: 在邮箱 %s 中找到 %d 封新邮件", mailboxConfig.Name, len(uids))
+	log.Printf("邮箱监控: 在邮箱 %s 的文件夹 %s 中找到 %d 封新邮件", mailboxConfig.Name, folder, len(uids))
 
 	// 获取邮件详情
 	seqset := &imap.SeqSet{}
@@ -511,8 +791,8 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 		seqset.AddNum(uid)
 	}
 
-	// 获取邮件头和正文
-	items := []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchRFC822Size, imap.FetchRFC822}
+	// 获取邮件头和正文，使用BODY.PEEK避免读写模式下隐式标记已读
+	items := []imap.FetchItem{imap.FetchEnvelope, imap.FetchFlags, imap.FetchRFC822Size, (&imap.BodySectionName{Peek: true}).FetchItem()}
 	messages := make(chan *imap.Message, len(uids))
 
 	done := make(chan error, 1)
@@ -522,17 +802,44 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 
 	// 处理每封邮件
 	var maxUID uint32
+	var firstFailedUID uint32 // 处理失败且仍需重试的最小UID，为0表示全部处理成功
+	markFailed := func(uid uint32) {
+		// 多次失败的邮件不再重试，避免检查点一直停在它之前
+		if m.recordMessageFailure(mailboxConfig, folder, uid) {
+			return
+		}
+		if firstFailedUID == 0 || uid < firstFailedUID {
+			firstFailedUID = uid
+		}
+	}
+	processed := &imap.SeqSet{}
+	pending := make(map[uint32][]MailboxAction)
 	for msg := range messages {
 		if msg.Uid > maxUID {
 			maxUID = msg.Uid
 		}
+		if msg.Envelope == nil {
+			log.Printf("邮箱监控: 邮件 %d 缺少信封信息", msg.Uid)
+			markFailed(msg.Uid)
+			continue
+		}
 
-		emailData := m.convertToEmailData(msg)
+		emailData := m.convertToEmailData(msg, resumed)
 		if emailData != nil {
+			emailData.Resumed = resumed
+			emailData.Folder = folder
 			// 调用处理器处理邮件
 			if m.handler != nil {
-				if err := m.handler.HandleEmail(mailboxConfig.ID, emailData); err != nil {
+				result, err := m.handler.HandleEmail(mailboxConfig.ID, emailData)
+				if err != nil {
 					log.Printf("邮箱监控: 处理邮件失败: %v", err)
+					markFailed(msg.Uid)
+				} else {
+					m.clearMessageFailure(mailboxConfig.ID, folder, msg.Uid)
+					processed.AddNum(msg.Uid)
+					if actions := m.resolveActions(mailboxConfig, result); len(actions) > 0 {
+						pending[msg.Uid] = actions
+					}
 				}
 			}
 		}
@@ -542,44 +849,51 @@ func (m *Monitor) fetchNewEmails(mailboxConfig MailboxConfig) error {
 		return fmt.Errorf("获取邮件失败: %v", err)
 	}
 
-	// 更新最后检查的UID（原子操作，确保单调递增）
-	if maxUID > 0 {
-		m.updateLastCheckUID(mailboxConfig.ID, int(maxUID))
+	// 标记已处理的邮件为已读
+	if markAsRead && !processed.Empty() {
+		item := imap.FormatFlagsOp(imap.AddFlags, true)
+		if err := conn.UidStore(processed, item, []interface{}{imap.SeenFlag}, nil); err != nil {
+			log.Printf("邮箱监控: 标记邮件已读失败: %v", err)
+		}
 	}
 
+	// 执行匹配/未匹配邮件的处理后动作（移动或删除需在读取全部邮件后进行）
+	m.applyIMAPActions(conn, mailboxConfig, folder, pending)
+
+	// 更新并持久化检查点（原子操作，确保单调递增）
+	// 有邮件处理失败时检查点只推进到第一封失败的邮件之前，下次检查时重试，达到失败上限而被跳过的邮件不再阻挡检查点
+	if firstFailedUID > 0 {
+		log.Printf("邮箱 %s/%s: UID %d 处理失败，检查点停在该邮件之前", mailboxConfig.Name, folder, firstFailedUID)
+		if int(firstFailedUID)-1 > lastUID {
+			lastUID = int(firstFailedUID) - 1
+		}
+		if lastUID == 0 {
+			// 尚未建立检查点，提交0会以UIDNEXT为基线而跳过失败的邮件
+			return nil
+		}
+	} else if int(maxUID) > lastUID {
+		lastUID = int(maxUID)
+	}
+	m.commitCheckpoint(mailboxConfig, folder, mbox, lastUID)
+
 	return nil
 }
 
 // convertToEmailData 转换邮件数据
-func (m *Monitor) convertToEmailData(msg *imap.Message) *EmailData {
+// resumed表示从持久化检查点恢复，停机期间到达的邮件不受监控启动时间限制
+func (m *Monitor) convertToEmailData(msg *imap.Message, resumed bool) *EmailData {
 	if msg.Envelope == nil {
 		return nil
 	}
 
-	// 使用北京时间进行时间验证
-	beijingTZ, err := time.LoadLocation("Asia/Shanghai")
-	if err != nil {
-		beijingTZ = time.FixedZone("CST", 8*3600) // 使用固定时区作为后备
-	}
-
-	// 添加调试日志，查看邮件原始时间和转换过程
-	log.Printf("邮件原始时间调试: Date=%v, Location=%v", msg.Envelope.Date, msg.Envelope.Date.Location())
-
-	// 二次时间验证：确保邮件时间在监控启动时间之后（都转换为北京时间）
-	emailTimeBeijing := msg.Envelope.Date.In(beijingTZ)
-	log.Printf("邮件时间转换调试: 原始时间=%s, 北京时间=%s",
-		msg.Envelope.Date.Format("2006-01-02 15:04:05 -0700 MST"),
-		emailTimeBeijing.Format("2006-01-02 15:04:05"))
-
-	if !msg.Envelope.Date.IsZero() {
-		startTimeBeijing := m.startTime.In(beijingTZ)
-
-		if !emailTimeBeijing.After(startTimeBeijing) {
-			log.Printf("邮件时间验证失败(北京时间): 邮件时间=%s, 监控启动时间=%s, 跳过处理",
-				emailTimeBeijing.Format("2006-01-02 15:04:05"),
-				startTimeBeijing.Format("2006-01-02 15:04:05"))
-			return nil
-		}
+	// 二次时间验证：确保邮件时间在监控启动时间之后（按UTC时刻比较，与邮件自带的时区无关）
+	emailTime := msg.Envelope.Date.UTC()
+	if !resumed && !msg.Envelope.Date.IsZero() && !emailTime.After(m.startTime) {
+		loc := timezone.System()
+		log.Printf("邮件时间验证失败: 邮件时间=%s, 监控启动时间=%s, 跳过处理",
+			emailTime.In(loc).Format("2006-01-02 15:04:05 MST"),
+			m.startTime.In(loc).Format("2006-01-02 15:04:05 MST"))
+		return nil
 	}
 
 	emailData := &EmailData{
@@ -588,13 +902,11 @@ func (m *Monitor) convertToEmailData(msg *imap.Message) *EmailData {
 		Size:       uint64(msg.Size),
 		Flags:      msg.Flags,
 		MessageID:  msg.Envelope.MessageId,
-		ReceivedAt: emailTimeBeijing, // 统一使用北京时间
+		ReceivedAt: emailTime, // 统一使用UTC，展示时再转换为渠道/用户时区
 	}
 
-	// 处理发件人
-	if len(msg.Envelope.From) > 0 {
-		emailData.Sender = msg.Envelope.From[0].MailboxName + "@" + msg.Envelope.From[0].HostName
-	}
+	// 处理发件人、收件人
+	emailData.applyEnvelope(msg.Envelope)
 
 	// 提取邮件正文 - 使用专业的邮件解析器
 	rfc822Section := &imap.BodySectionName{}
@@ -619,6 +931,8 @@ func (m *Monitor) convertToEmailData(msg *imap.Message) *EmailData {
 		if len(buf) > 0 {
 			// 使用专业的邮件解析器解析邮件内容
 			if m.parser != nil {
+				emailData.Headers = m.parser.ExtractHeaders(string(buf))
+				emailData.Attachments = m.parser.ExtractAttachments(string(buf))
 				textContent, htmlContent, err := m.parser.ParseContent(string(buf))
 				if err != nil {
 					log.Printf("邮件解析失败: %v", err)
@@ -661,6 +975,8 @@ func (m *Monitor) convertToEmailData(msg *imap.Message) *EmailData {
 				if len(buf) > 0 {
 					// 使用专业的邮件解析器解析邮件内容
 					if m.parser != nil {
+						emailData.Headers = m.parser.ExtractHeaders(string(buf))
+						emailData.Attachments = m.parser.ExtractAttachments(string(buf))
 						textContent, htmlContent, err := m.parser.ParseContent(string(buf))
 						if err != nil {
 							log.Printf("邮件解析失败: %v", err)
@@ -689,9 +1005,8 @@ func (m *Monitor) convertToEmailData(msg *imap.Message) *EmailData {
 		if msg.Envelope.Subject != "" {
 			contentParts = append(contentParts, "主题: "+msg.Envelope.Subject)
 		}
-		if len(msg.Envelope.From) > 0 {
-			from := msg.Envelope.From[0].MailboxName + "@" + msg.Envelope.From[0].HostName
-			contentParts = append(contentParts, "发件人: "+from)
+		if len(emailData.From) > 0 {
+			contentParts = append(contentParts, "发件人: "+emailData.From[0].String())
 		}
 		if !msg.Envelope.Date.IsZero() {
 			contentParts = append(contentParts, "时间: "+msg.Envelope.Date.Format("2006-01-02 15:04:05"))
@@ -724,7 +1039,7 @@ func (m *Monitor) getMailboxMutex(mailboxID uint) *sync.Mutex {
 
 // getAndPrepareLastCheckUID 原子化获取最后检查的UID，并为当前检查做准备
 // 返回上次检查的UID，同时确保同一时间只能有一个goroutine处理同一个邮箱
-func (m *Monitor) getAndPrepareLastCheckUID(mailboxID uint) int {
+func (m *Monitor) getAndPrepareLastCheckUID(mailboxID uint, folder string) int {
 	// 使用邮箱专用锁，确保同一时间只有一个goroutine处理同一个邮箱
 	mailboxMutex := m.getMailboxMutex(mailboxID)
 	mailboxMutex.Lock()
@@ -733,14 +1048,14 @@ func (m *Monitor) getAndPrepareLastCheckUID(mailboxID uint) int {
 	m.mutex.RLock()
 	defer m.mutex.RUnlock()
 
-	if uid, exists := m.lastCheckUID[mailboxID]; exists {
+	if uid, exists := m.lastCheckUID[mailboxID][folder]; exists {
 		return uid
 	}
 	return 0
 }
 
 // updateLastCheckUID 原子化更新最后检查的UID，确保单调递增
-func (m *Monitor) updateLastCheckUID(mailboxID uint, newUID int) {
+func (m *Monitor) updateLastCheckUID(mailboxID uint, folder string, newUID int) {
 	// 使用邮箱专用锁，确保同一时间只有一个goroutine处理同一个邮箱
 	mailboxMutex := m.getMailboxMutex(mailboxID)
 	mailboxMutex.Lock()
@@ -749,39 +1064,38 @@ func (m *Monitor) updateLastCheckUID(mailboxID uint, newUID int) {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
+	folderUIDs, ok := m.lastCheckUID[mailboxID]
+	if !ok {
+		folderUIDs = make(map[string]int)
+		m.lastCheckUID[mailboxID] = folderUIDs
+	}
+
 	// 确保UID是单调递增的，防止并发导致的UID回退
-	if currentUID, exists := m.lastCheckUID[mailboxID]; exists {
+	if currentUID, exists := folderUIDs[folder]; exists {
 		if newUID > currentUID {
-			m.lastCheckUID[mailboxID] = newUID
-			log.Printf("邮箱 %d: UID更新从 %d 到 %d", mailboxID, currentUID, newUID)
+			folderUIDs[folder] = newUID
+			log.Printf("邮箱 %d/%s: UID更新从 %d 到 %d", mailboxID, folder, currentUID, newUID)
 		} else {
-			log.Printf("邮箱 %d: UID未更新，新UID(%d) <= 当前UID(%d)", mailboxID, newUID, currentUID)
+			log.Printf("邮箱 %d/%s: UID未更新，新UID(%d) <= 当前UID(%d)", mailboxID, folder, newUID, currentUID)
 		}
 	} else {
-		m.lastCheckUID[mailboxID] = newUID
-		log.Printf("邮箱 %d: 初始UID设置为 %d", mailboxID, newUID)
+		folderUIDs[folder] = newUID
+		log.Printf("邮箱 %d/%s: 初始UID设置为 %d", mailboxID, folder, newUID)
 	}
 }
 
-// getLastCheckUID 兼容性方法 - 已废弃，仅用于向后兼容
-// 建议使用getAndPrepareLastCheckUID替代
-func (m *Monitor) getLastCheckUID(mailboxID uint) int {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-
-	if uid, exists := m.lastCheckUID[mailboxID]; exists {
-		return uid
+// mailboxFolders 获取邮箱需要监控的文件夹列表，未配置时使用默认文件夹
+func (m *Monitor) mailboxFolders(mailboxConfig MailboxConfig) []string {
+	var folders []string
+	for _, folder := range mailboxConfig.Folders {
+		if folder = strings.TrimSpace(folder); folder != "" {
+			folders = append(folders, folder)
+		}
 	}
-	return 0
-}
-
-// setLastCheckUID 兼容性方法 - 已废弃，仅用于向后兼容
-// 建议使用updateLastCheckUID替代
-func (m *Monitor) setLastCheckUID(mailboxID uint, uid int) {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
-	m.lastCheckUID[mailboxID] = uid
+	if len(folders) == 0 {
+		folders = []string{m.config.Folder}
+	}
+	return folders
 }
 
 // sendIMAPIDInMonitor 发送IMAP ID命令，用于解决126/163邮箱的"Unsafe Login"问题
@@ -0,0 +1,79 @@
+package email
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRestartWorkerDuringFetch 拉取过程中重启邮箱的监控协程，新协程等待旧协程退出后才开始检查，同一封邮件只处理一次
+func TestRestartWorkerDuringFetch(t *testing.T) {
+	srv := startTestIMAPServer(t, false, "INBOX")
+	handler := newRecordingHandler()
+	handler.block = make(chan struct{})
+	monitor := newTestMonitor(handler)
+
+	mailbox := srv.mailboxConfig(1)
+	first := srv.deliver("INBOX", "disk usage 95%")
+	monitor.AddMailbox(mailbox)
+	if err := monitor.Start(); err != nil {
+		t.Fatalf("启动监控失败: %v", err)
+	}
+	defer monitor.Stop()
+
+	// 旧协程阻塞在第一封邮件的处理中时修改配置，触发重启
+	handler.waitEntered(t, 5*time.Second)
+	mailbox.CheckInterval = 20 * time.Millisecond
+	monitor.AddMailbox(mailbox)
+
+	time.Sleep(300 * time.Millisecond)
+	if n := handler.count("INBOX", first); n != 1 {
+		t.Fatalf("旧协程仍在处理时新协程不应重复拉取，实际处理 %d 次", n)
+	}
+	close(handler.block)
+
+	// 新协程开始工作后能处理后续邮件
+	second := srv.deliver("INBOX", "cpu usage 99%")
+	waitFor(t, 5*time.Second, "新协程处理新邮件", func() bool { return handler.count("INBOX", second) == 1 })
+
+	time.Sleep(200 * time.Millisecond)
+	if n := handler.count("INBOX", first); n != 1 {
+		t.Fatalf("第一封邮件应只处理一次，实际处理 %d 次", n)
+	}
+	if handler.overlap {
+		t.Fatal("同一邮箱的邮件不应被并发处理")
+	}
+	if status := monitor.GetStatus(); status["worker_count"] != 1 {
+		t.Fatalf("重启后应只有一个监控协程，实际为 %v", status["worker_count"])
+	}
+}
+
+// TestSkipMessageAfterFailureLimit 一直处理失败的邮件重试到上限后被跳过，检查点越过它继续推进
+func TestSkipMessageAfterFailureLimit(t *testing.T) {
+	srv := startTestIMAPServer(t, false, "INBOX")
+	handler := newRecordingHandler()
+	monitor := newTestMonitor(handler)
+	monitor.config.MessageFailureLimit = 3
+
+	mailbox := srv.mailboxConfig(1)
+	poison := srv.deliver("INBOX", "malformed alert")
+	handler.fail = map[int]bool{int(poison): true}
+	monitor.AddMailbox(mailbox)
+	if err := monitor.Start(); err != nil {
+		t.Fatalf("启动监控失败: %v", err)
+	}
+	defer monitor.Stop()
+
+	waitFor(t, 5*time.Second, "检查点越过失败的邮件", func() bool {
+		return monitor.getAndPrepareLastCheckUID(mailbox.ID, "INBOX") >= int(poison)
+	})
+	next := srv.deliver("INBOX", "disk usage 95%")
+	waitFor(t, 5*time.Second, "处理后续邮件", func() bool { return handler.count("INBOX", next) == 1 })
+
+	if n := handler.count("INBOX", poison); n != 3 {
+		t.Fatalf("失败的邮件应重试到上限3次后跳过，实际处理 %d 次", n)
+	}
+	health := monitor.GetHealth()
+	if len(health) != 1 || health[0].SkippedMessages != 1 || health[0].LastSkipped != "INBOX/1" {
+		t.Fatalf("跳过的邮件应记入健康状态，实际为 %+v", health)
+	}
+}
@@ -8,17 +8,28 @@ import (
 	"log"
 	"mime"
 	"mime/quotedprintable"
+	"net/mail"
 	"net/textproto"
 	"regexp"
 	"strings"
 )
 
 // EmailParser 简化的邮件解析器
-type EmailParser struct{}
+type EmailParser struct {
+	attachmentContentLimit int64 // 保留附件内容的大小上限（字节）
+}
 
 // NewEmailParser 创建新的邮件解析器
 func NewEmailParser() *EmailParser {
-	return &EmailParser{}
+	return &EmailParser{attachmentContentLimit: DefaultAttachmentContentLimit}
+}
+
+// SetAttachmentContentLimit 设置保留附件内容的大小上限，0表示只记录附件元数据
+func (p *EmailParser) SetAttachmentContentLimit(limit int64) {
+	if limit < 0 {
+		limit = 0
+	}
+	p.attachmentContentLimit = limit
 }
 
 // ParseContent 解析邮件内容，提取纯文本和HTML内容
@@ -92,6 +103,60 @@ func (p *EmailParser) ParseContent(rawEmail string) (textContent, htmlContent st
 	return textContent, htmlContent, nil
 }
 
+// ParseMessage 解析完整的RFC822邮件，提取邮件头信息和正文
+func (p *EmailParser) ParseMessage(rawEmail string) (*EmailData, error) {
+	msg, err := mail.ReadMessage(strings.NewReader(rawEmail))
+	if err != nil {
+		return nil, fmt.Errorf("解析邮件头失败: %v", err)
+	}
+
+	decoder := newWordDecoder()
+
+	emailData := &EmailData{
+		Subject:   p.decodeHeader(decoder, msg.Header.Get("Subject")),
+		MessageID: strings.TrimSpace(msg.Header.Get("Message-Id")),
+		Size:      uint64(len(rawEmail)),
+	}
+
+	if date, err := msg.Header.Date(); err == nil {
+		emailData.ReceivedAt = date
+	}
+
+	// 处理发件人、收件人及全部头字段
+	emailData.Headers = p.decodeHeaders(msg.Header)
+	p.parseEnvelope(msg.Header, emailData)
+	if emailData.Sender == "" {
+		emailData.Sender = p.decodeHeader(decoder, msg.Header.Get("From"))
+	}
+
+	// 解析正文
+	textContent, htmlContent, err := p.ParseContent(rawEmail)
+	if err != nil {
+		log.Printf("邮件解析失败: %v", err)
+		textContent = p.fallbackParseContent(rawEmail)
+	}
+	emailData.Content = textContent
+	emailData.HTMLContent = htmlContent
+
+	// 如果没有纯文本内容，但有HTML内容，从HTML中提取文本
+	if emailData.Content == "" && emailData.HTMLContent != "" {
+		emailData.Content = p.stripHTMLTags(emailData.HTMLContent)
+	}
+
+	emailData.Attachments = p.ExtractAttachments(rawEmail)
+
+	return emailData, nil
+}
+
+// decodeHeader 解码RFC 2047编码的邮件头，失败时返回原始值
+func (p *EmailParser) decodeHeader(decoder *mime.WordDecoder, value string) string {
+	decoded, err := decoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
+
 // parseHeaders 解析邮件头
 func (p *EmailParser) parseHeaders(headerStr string) (textproto.MIMEHeader, error) {
 	reader := strings.NewReader(headerStr + "\r\n\r\n")
@@ -214,11 +279,9 @@ func (p *EmailParser) decodeContent(content, encoding string) string {
 	}
 }
 
-// convertCharset 转换字符集（简化实现）
+// convertCharset 将内容从指定字符集转换为UTF-8（支持GBK、GB18030、Big5等）
 func (p *EmailParser) convertCharset(content, charset string) string {
-	// 这里可以根据需要实现字符集转换
-	// 对于常见的UTF-8、GBK等，Go的标准库已经能够处理
-	return content
+	return decodeCharset(content, charset)
 }
 
 // stripHTMLTags 从HTML中提取纯文本
@@ -0,0 +1,469 @@
+package email
+
+import (
+	"crypto/tls"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"emailAlert/pkg/proxy"
+)
+
+// ProtocolPOP3 POP3协议标识
+const ProtocolPOP3 = "POP3"
+
+// pop3Folder POP3邮箱只有收件箱，检查点和邮件使用的文件夹名称
+const pop3Folder = "INBOX"
+
+// pop3DialTimeout POP3连接超时时间
+const pop3DialTimeout = 30 * time.Second
+
+// pop3Conn POP3连接（RFC 1939）
+type pop3Conn struct {
+	conn net.Conn
+	text *textproto.Conn
+}
+
+// pop3Message UIDL列表项
+type pop3Message struct {
+	Number int    // 邮件序号（仅在当前会话内有效）
+	UID    string // 邮件唯一标识（跨会话稳定）
+}
+
+// pop3UID 将UIDL转换为数字UID，仅用于展示
+// 哈希值可能冲突，没有Message-ID时的去重使用完整UIDL（EmailData.UIDL）
+func pop3UID(uidl string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(uidl))
+	return int(hash.Sum32() & 0x7fffffff)
+}
+
+// isPOP3 判断邮箱是否使用POP3协议
+func isPOP3(config MailboxConfig) bool {
+	return strings.EqualFold(config.Protocol, ProtocolPOP3)
+}
+
+// dialPOP3 建立POP3连接并读取服务器问候语
+func dialPOP3(config MailboxConfig) (*pop3Conn, error) {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+	dialer, err := proxy.NewDialer(config.Proxy, pop3DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("代理配置无效: %v", err)
+	}
+
+	mode := tlsMode(config)
+	var tlsConfig *tls.Config
+	if mode != TLSModeNone {
+		if tlsConfig, err = mailboxTLSConfig(config); err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接服务器失败: %v", err)
+	}
+
+	if mode == TLSModeImplicit {
+		tlsConn := tls.Client(conn, tlsConfig)
+		tlsConn.SetDeadline(time.Now().Add(pop3DialTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("连接服务器失败: %v", err)
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
+	c := &pop3Conn{
+		conn: conn,
+		text: textproto.NewConn(conn),
+	}
+
+	// 读取服务器问候语
+	if _, err := c.readResponse(); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("读取服务器问候语失败: %v", err)
+	}
+
+	if mode == TLSModeSTARTTLS {
+		if err := c.startTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// startTLS 使用STLS命令将连接升级为TLS（RFC 2595）
+func (c *pop3Conn) startTLS(tlsConfig *tls.Config) error {
+	if _, err := c.cmd("STLS"); err != nil {
+		return fmt.Errorf("服务器不支持STLS: %v", err)
+	}
+
+	tlsConn := tls.Client(c.conn, tlsConfig)
+	tlsConn.SetDeadline(time.Now().Add(pop3DialTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		return fmt.Errorf("STLS握手失败: %v", err)
+	}
+	tlsConn.SetDeadline(time.Time{})
+
+	c.conn = tlsConn
+	c.text = textproto.NewConn(tlsConn)
+	return nil
+}
+
+// readResponse 读取单行响应，-ERR时返回错误
+func (c *pop3Conn) readResponse() (string, error) {
+	line, err := c.text.ReadLine()
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case strings.HasPrefix(line, "+OK"):
+		return strings.TrimSpace(strings.TrimPrefix(line, "+OK")), nil
+	case strings.HasPrefix(line, "-ERR"):
+		return "", fmt.Errorf("%s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+	default:
+		return "", fmt.Errorf("无法识别的响应: %s", line)
+	}
+}
+
+// cmd 发送命令并读取单行响应
+func (c *pop3Conn) cmd(format string, args ...interface{}) (string, error) {
+	c.conn.SetDeadline(time.Now().Add(pop3DialTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := c.text.PrintfLine(format, args...); err != nil {
+		return "", err
+	}
+	return c.readResponse()
+}
+
+// Login 使用USER/PASS命令登录
+func (c *pop3Conn) Login(username, password string) error {
+	if _, err := c.cmd("USER %s", username); err != nil {
+		return err
+	}
+	if _, err := c.cmd("PASS %s", password); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Auth 使用AUTH命令进行SASL认证，initialResponse为base64编码的初始响应
+func (c *pop3Conn) Auth(mechanism, initialResponse string) error {
+	c.conn.SetDeadline(time.Now().Add(pop3DialTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	if err := c.text.PrintfLine("AUTH %s %s", mechanism, initialResponse); err != nil {
+		return err
+	}
+
+	line, err := c.text.ReadLine()
+	if err != nil {
+		return err
+	}
+	// 认证失败时服务器可能先返回错误详情质询，回复空行后读取最终结果
+	if strings.HasPrefix(line, "+ ") || line == "+" {
+		if err := c.text.PrintfLine(""); err != nil {
+			return err
+		}
+		_, err := c.readResponse()
+		if err == nil {
+			return fmt.Errorf("服务器返回了无法识别的质询: %s", line)
+		}
+		return err
+	}
+	if strings.HasPrefix(line, "-ERR") {
+		return fmt.Errorf("%s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+	}
+	if !strings.HasPrefix(line, "+OK") {
+		return fmt.Errorf("无法识别的响应: %s", line)
+	}
+	return nil
+}
+
+// Stat 获取邮件数量和总大小
+func (c *pop3Conn) Stat() (int, int64, error) {
+	resp, err := c.cmd("STAT")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	fields := strings.Fields(resp)
+	if len(fields) < 2 {
+		return 0, 0, fmt.Errorf("STAT响应格式错误: %s", resp)
+	}
+
+	count, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("STAT响应格式错误: %s", resp)
+	}
+	size, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("STAT响应格式错误: %s", resp)
+	}
+
+	return count, size, nil
+}
+
+// Uidl 获取所有邮件的序号和唯一标识
+func (c *pop3Conn) Uidl() ([]pop3Message, error) {
+	if _, err := c.cmd("UIDL"); err != nil {
+		return nil, err
+	}
+
+	lines, err := c.text.ReadDotLines()
+	if err != nil {
+		return nil, fmt.Errorf("读取UIDL列表失败: %v", err)
+	}
+
+	messages := make([]pop3Message, 0, len(lines))
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		number, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		messages = append(messages, pop3Message{Number: number, UID: fields[1]})
+	}
+
+	return messages, nil
+}
+
+// Retr 获取完整的邮件内容
+func (c *pop3Conn) Retr(number int) (string, error) {
+	if _, err := c.cmd("RETR %d", number); err != nil {
+		return "", err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	raw, err := io.ReadAll(c.text.DotReader())
+	if err != nil {
+		return "", fmt.Errorf("读取邮件内容失败: %v", err)
+	}
+
+	return string(raw), nil
+}
+
+// Dele 标记删除邮件（QUIT后生效）
+func (c *pop3Conn) Dele(number int) error {
+	_, err := c.cmd("DELE %d", number)
+	return err
+}
+
+// Quit 结束会话并提交删除操作
+func (c *pop3Conn) Quit() error {
+	_, err := c.cmd("QUIT")
+	c.Close()
+	return err
+}
+
+// Close 关闭底层连接
+func (c *pop3Conn) Close() error {
+	return c.text.Close()
+}
+
+// validatePOP3Access 验证POP3邮箱是否可以正常访问
+func (m *Monitor) validatePOP3Access(mailboxConfig MailboxConfig) error {
+	conn, err := dialPOP3(mailboxConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Quit()
+
+	if err := pop3Login(conn, mailboxConfig); err != nil {
+		return loginError(err)
+	}
+
+	if _, _, err := conn.Stat(); err != nil {
+		return fmt.Errorf("无法读取邮箱状态: %v", err)
+	}
+
+	if _, err := conn.Uidl(); err != nil {
+		return fmt.Errorf("服务器不支持UIDL命令，无法追踪新邮件: %v", err)
+	}
+
+	return nil
+}
+
+// fetchNewEmailsPOP3 通过POP3获取新邮件
+// 首次检查时只记录已有邮件的UIDL作为基线，之后每次检查处理基线之外的新邮件
+func (m *Monitor) fetchNewEmailsPOP3(mailboxConfig MailboxConfig) error {
+	conn, err := dialPOP3(mailboxConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Quit()
+
+	if err := pop3Login(conn, mailboxConfig); err != nil {
+		return loginError(err)
+	}
+
+	messages, err := conn.Uidl()
+	if err != nil {
+		return fmt.Errorf("获取UIDL列表失败: %v", err)
+	}
+
+	// 使用邮箱专用锁，确保同一时间只有一个goroutine处理同一个邮箱
+	mailboxMutex := m.getMailboxMutex(mailboxConfig.ID)
+	mailboxMutex.Lock()
+	defer mailboxMutex.Unlock()
+
+	seen, resumed, initialized := m.loadPOP3SeenUIDs(mailboxConfig)
+
+	current := make(map[string]bool, len(messages))
+	for _, msg := range messages {
+		current[msg.UID] = true
+	}
+
+	if !initialized {
+		m.savePOP3SeenUIDs(mailboxConfig, current)
+		log.Printf("邮箱 %s: POP3首次检查，记录 %d 封已有邮件作为基线", mailboxConfig.Name, len(messages))
+		return nil
+	}
+
+	var newMessages []pop3Message
+	for _, msg := range messages {
+		if !seen[msg.UID] {
+			newMessages = append(newMessages, msg)
+		}
+	}
+
+	if len(newMessages) > 0 {
+		log.Printf("邮箱监控: 在POP3邮箱 %s 中找到 %d 封新邮件", mailboxConfig.Name, len(newMessages))
+	}
+
+	processed := make(map[string]bool, len(messages))
+	for uid := range seen {
+		if current[uid] {
+			processed[uid] = true
+		}
+	}
+
+	var fetchErr error
+	for _, msg := range newMessages {
+		raw, err := conn.Retr(msg.Number)
+		if err != nil {
+			fetchErr = fmt.Errorf("获取邮件 %s 失败: %v", msg.UID, err)
+			break
+		}
+
+		// 解析失败的邮件重试也无法成功，记为已处理后不再获取，开启获取后删除时照常删除
+		// 处理失败的邮件不记为已处理，也不从服务器删除，下次检查时重试
+		emailData, err := m.parser.ParseMessage(raw)
+		if err != nil {
+			log.Printf("邮箱监控: 解析POP3邮件 %s 失败，跳过该邮件且不再重试: %v", msg.UID, err)
+			processed[msg.UID] = true
+			if mailboxConfig.DeleteAfterFetch {
+				if err := conn.Dele(msg.Number); err != nil {
+					log.Printf("邮箱监控: 删除POP3邮件 %s 失败: %v", msg.UID, err)
+				}
+			}
+			continue
+		}
+		emailData.UID = pop3UID(msg.UID)
+		emailData.UIDL = msg.UID
+		emailData.Folder = pop3Folder
+		emailData.Resumed = resumed
+
+		deleted := false
+		if m.handler != nil {
+			result, err := m.handler.HandleEmail(mailboxConfig.ID, emailData)
+			if err != nil {
+				log.Printf("邮箱监控: 处理邮件 %s 失败: %v", msg.UID, err)
+				continue
+			}
+			deleted = m.applyPOP3Actions(conn, mailboxConfig, msg, m.resolveActions(mailboxConfig, result))
+		}
+		processed[msg.UID] = true
+
+		if mailboxConfig.DeleteAfterFetch && !deleted {
+			if err := conn.Dele(msg.Number); err != nil {
+				log.Printf("邮箱监控: 删除POP3邮件 %s 失败: %v", msg.UID, err)
+			}
+		}
+	}
+
+	// 只保留服务器上仍存在的UIDL，避免集合无限增长
+	m.savePOP3SeenUIDs(mailboxConfig, processed)
+
+	return fetchErr
+}
+
+// loadPOP3SeenUIDs 获取POP3邮箱已处理的UIDL，本次运行首次检查时尝试从持久化检查点恢复
+// 返回的resumed表示UIDL来自检查点，停机期间到达的邮件不受启动时间限制；initialized为false时需要建立基线
+func (m *Monitor) loadPOP3SeenUIDs(mailboxConfig MailboxConfig) (seen map[string]bool, resumed bool, initialized bool) {
+	mailboxID := mailboxConfig.ID
+
+	m.mutex.RLock()
+	seen, initialized = m.pop3SeenUIDs[mailboxID]
+	resumed = m.resumedFolders[mailboxID][pop3Folder]
+	store := m.checkpointStore
+	m.mutex.RUnlock()
+
+	if initialized || store == nil {
+		return seen, resumed, initialized
+	}
+
+	checkpoint, err := store.LoadCheckpoint(mailboxID, pop3Folder)
+	if err != nil {
+		log.Printf("邮箱 %s: 读取POP3检查点失败: %v", mailboxConfig.Name, err)
+		return nil, false, false
+	}
+	if checkpoint == nil || checkpoint.SeenUIDLs == nil {
+		return nil, false, false
+	}
+
+	seen = make(map[string]bool, len(checkpoint.SeenUIDLs))
+	for _, uid := range checkpoint.SeenUIDLs {
+		seen[uid] = true
+	}
+
+	m.mutex.Lock()
+	m.pop3SeenUIDs[mailboxID] = seen
+	if _, ok := m.resumedFolders[mailboxID]; !ok {
+		m.resumedFolders[mailboxID] = make(map[string]bool)
+	}
+	m.resumedFolders[mailboxID][pop3Folder] = true
+	m.mutex.Unlock()
+
+	log.Printf("邮箱 %s: 从检查点恢复，已处理 %d 封POP3邮件", mailboxConfig.Name, len(seen))
+	return seen, true, true
+}
+
+// savePOP3SeenUIDs 更新并持久化POP3邮箱已处理的UIDL
+func (m *Monitor) savePOP3SeenUIDs(mailboxConfig MailboxConfig, seen map[string]bool) {
+	m.mutex.Lock()
+	m.pop3SeenUIDs[mailboxConfig.ID] = seen
+	store := m.checkpointStore
+	m.mutex.Unlock()
+
+	if store == nil {
+		return
+	}
+
+	uids := make([]string, 0, len(seen))
+	for uid := range seen {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	if err := store.SaveCheckpoint(mailboxConfig.ID, pop3Folder, Checkpoint{SeenUIDLs: uids}); err != nil {
+		log.Printf("邮箱 %s: 保存POP3检查点失败: %v", mailboxConfig.Name, err)
+	}
+}
@@ -0,0 +1,229 @@
+package email
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testPOP3Server 内存中的测试POP3服务器，只实现监控器使用的命令
+type testPOP3Server struct {
+	addr     *net.TCPAddr
+	mutex    sync.Mutex
+	messages []*testPOP3Message
+	retrs    map[string]int // 按UIDL统计RETR次数
+}
+
+// testPOP3Message 测试POP3邮件
+type testPOP3Message struct {
+	uidl    string
+	raw     string
+	deleted bool
+}
+
+// startTestPOP3Server 启动测试POP3服务器
+func startTestPOP3Server(t *testing.T) *testPOP3Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听失败: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &testPOP3Server{addr: listener.Addr().(*net.TCPAddr), retrs: make(map[string]int)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// mailboxConfig 返回连接测试服务器的POP3邮箱配置
+func (s *testPOP3Server) mailboxConfig(id uint) MailboxConfig {
+	return MailboxConfig{
+		ID:       id,
+		Name:     "pop3-" + strconv.Itoa(int(id)),
+		Email:    testIMAPUsername,
+		Host:     s.addr.IP.String(),
+		Port:     s.addr.Port,
+		Username: testIMAPUsername,
+		Password: testIMAPPassword,
+		Protocol: ProtocolPOP3,
+		Status:   "active",
+		Proxy:    "direct",
+	}
+}
+
+// deliver 投递一封邮件
+func (s *testPOP3Server) deliver(uidl, raw string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.messages = append(s.messages, &testPOP3Message{uidl: uidl, raw: raw})
+}
+
+// retrCount 返回邮件被获取的次数
+func (s *testPOP3Server) retrCount(uidl string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.retrs[uidl]
+}
+
+// exists 判断邮件是否仍在服务器上
+func (s *testPOP3Server) exists(uidl string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, msg := range s.messages {
+		if msg.uidl == uidl {
+			return true
+		}
+	}
+	return false
+}
+
+// serve 处理一个客户端会话，QUIT时删除标记为删除的邮件
+func (s *testPOP3Server) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	text.PrintfLine("+OK ready")
+
+	s.mutex.Lock()
+	session := append([]*testPOP3Message(nil), s.messages...)
+	s.mutex.Unlock()
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			text.PrintfLine("-ERR empty command")
+			continue
+		}
+		number := 0
+		if len(fields) > 1 {
+			number, _ = strconv.Atoi(fields[1])
+		}
+
+		s.mutex.Lock()
+		switch strings.ToUpper(fields[0]) {
+		case "USER":
+			text.PrintfLine("+OK")
+		case "PASS":
+			if len(fields) > 1 && fields[1] == testIMAPPassword {
+				text.PrintfLine("+OK logged in")
+			} else {
+				text.PrintfLine("-ERR invalid password")
+			}
+		case "UIDL":
+			text.PrintfLine("+OK")
+			w := text.DotWriter()
+			for i, msg := range session {
+				if !msg.deleted {
+					fmt.Fprintf(w, "%d %s\n", i+1, msg.uidl)
+				}
+			}
+			w.Close()
+		case "RETR":
+			if number < 1 || number > len(session) || session[number-1].deleted {
+				text.PrintfLine("-ERR no such message")
+				break
+			}
+			msg := session[number-1]
+			s.retrs[msg.uidl]++
+			text.PrintfLine("+OK")
+			w := text.DotWriter()
+			w.Write([]byte(msg.raw))
+			w.Close()
+		case "DELE":
+			if number < 1 || number > len(session) {
+				text.PrintfLine("-ERR no such message")
+				break
+			}
+			session[number-1].deleted = true
+			text.PrintfLine("+OK")
+		case "QUIT":
+			kept := s.messages[:0]
+			for _, msg := range s.messages {
+				if !msg.deleted {
+					kept = append(kept, msg)
+				}
+			}
+			s.messages = kept
+			text.PrintfLine("+OK bye")
+			s.mutex.Unlock()
+			return
+		default:
+			text.PrintfLine("-ERR unknown command")
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// uidlHandler 按UIDL记录收到的POP3邮件
+type uidlHandler struct {
+	mutex  sync.Mutex
+	counts map[string]int
+}
+
+func (h *uidlHandler) HandleEmail(mailboxID uint, email *EmailData) (*HandleResult, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.counts[email.UIDL]++
+	return &HandleResult{}, nil
+}
+
+func (h *uidlHandler) count(uidl string) int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.counts[uidl]
+}
+
+// TestPOP3UnparseableMessage 无法解析的邮件只获取一次，之后不再重试，开启获取后删除时从服务器删除
+func TestPOP3UnparseableMessage(t *testing.T) {
+	const (
+		bad  = "bad-0001"
+		good = "good-0001"
+	)
+	for _, deleteAfterFetch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("delete=%v", deleteAfterFetch), func(t *testing.T) {
+			srv := startTestPOP3Server(t)
+			handler := &uidlHandler{counts: make(map[string]int)}
+			monitor := newTestMonitor(handler)
+			mailbox := srv.mailboxConfig(1)
+			mailbox.DeleteAfterFetch = deleteAfterFetch
+
+			// 首次检查建立基线
+			if err := monitor.fetchNewEmailsPOP3(mailbox); err != nil {
+				t.Fatalf("建立基线失败: %v", err)
+			}
+
+			srv.deliver(bad, "this line is not a header\n\nbody\n")
+			srv.deliver(good, "From: monitor@example.com\nSubject: disk usage 95%\n\nbody\n")
+			for i := 0; i < 3; i++ {
+				if err := monitor.fetchNewEmailsPOP3(mailbox); err != nil {
+					t.Fatalf("第%d次检查失败: %v", i+1, err)
+				}
+			}
+
+			if n := srv.retrCount(bad); n != 1 {
+				t.Fatalf("无法解析的邮件应只获取一次，实际获取 %d 次", n)
+			}
+			if n := handler.count(good); n != 1 {
+				t.Fatalf("正常邮件应按完整UIDL处理一次，实际处理 %d 次", n)
+			}
+			if srv.exists(bad) == deleteAfterFetch || srv.exists(good) == deleteAfterFetch {
+				t.Fatalf("获取后删除=%v 时邮件保留状态不正确: bad=%v good=%v", deleteAfterFetch, srv.exists(bad), srv.exists(good))
+			}
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// ProviderProfile 邮箱服务商配置，描述特定服务商的兼容性处理和推荐设置
+type ProviderProfile struct {
+	Name         string   `json:"name"`          // 服务商名称
+	HostPatterns []string `json:"host_patterns"` // 服务器地址匹配规则：包含该字符串即匹配，含 * 时按通配符匹配
+	SendID       bool     `json:"send_id"`       // 登录后发送IMAP ID命令（如126/163的"Unsafe Login"限制）
+	NoSearch     bool     `json:"no_search"`     // 服务器SEARCH命令不可用，改为按UID范围获取邮件
+	IMAPHost     string   `json:"imap_host"`     // 推荐的IMAP服务器地址
+	IMAPPort     int      `json:"imap_port"`     // 推荐的IMAP端口
+	POP3Host     string   `json:"pop3_host"`     // 推荐的POP3服务器地址
+	POP3Port     int      `json:"pop3_port"`     // 推荐的POP3端口
+	SSL          bool     `json:"ssl"`           // 是否推荐启用SSL
+	Hints        []string `json:"hints"`         // 诊断时给出的设置建议
+}
+
+// Matches 判断服务器地址是否属于该服务商
+func (p *ProviderProfile) Matches(host string) bool {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if host == "" {
+		return false
+	}
+	for _, pattern := range p.HostPatterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if strings.Contains(pattern, "*") {
+			if ok, _ := path.Match(pattern, host); ok {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverHint 根据协议生成推荐的服务器设置说明
+func (p *ProviderProfile) serverHint(protocol string) string {
+	host, port := p.IMAPHost, p.IMAPPort
+	if strings.EqualFold(protocol, ProtocolPOP3) {
+		host, port = p.POP3Host, p.POP3Port
+	}
+	if host == "" || port == 0 {
+		return ""
+	}
+
+	ssl := "不启用SSL"
+	if p.SSL {
+		ssl = "启用SSL"
+	}
+	return fmt.Sprintf("服务器地址：%s，端口：%d，%s", host, port, ssl)
+}
+
+// validate 校验服务商配置
+func (p *ProviderProfile) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("服务商名称不能为空")
+	}
+	if len(p.HostPatterns) == 0 {
+		return fmt.Errorf("服务商 %s 未配置服务器地址匹配规则", p.Name)
+	}
+	for _, pattern := range p.HostPatterns {
+		if _, err := path.Match(strings.ToLower(pattern), ""); err != nil {
+			return fmt.Errorf("服务商 %s 的匹配规则 %q 无效: %v", p.Name, pattern, err)
+		}
+	}
+	return nil
+}
+
+// ProviderRegistry 邮箱服务商配置注册表，按注册顺序的倒序匹配（后注册的优先）
+type ProviderRegistry struct {
+	mutex    sync.RWMutex
+	profiles []ProviderProfile
+}
+
+// NewProviderRegistry 创建包含内置服务商配置的注册表
+func NewProviderRegistry() *ProviderRegistry {
+	registry := &ProviderRegistry{}
+	for _, profile := range builtinProviders {
+		registry.Register(profile)
+	}
+	return registry
+}
+
+// Register 注册服务商配置，同名配置会被替换
+func (r *ProviderRegistry) Register(profile ProviderProfile) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.profiles {
+		if strings.EqualFold(r.profiles[i].Name, profile.Name) {
+			r.profiles = append(r.profiles[:i], r.profiles[i+1:]...)
+			break
+		}
+	}
+	r.profiles = append(r.profiles, profile)
+}
+
+// Lookup 根据服务器地址查找服务商配置，未匹配时返回nil
+func (r *ProviderRegistry) Lookup(host string) *ProviderProfile {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	for i := len(r.profiles) - 1; i >= 0; i-- {
+		if r.profiles[i].Matches(host) {
+			profile := r.profiles[i]
+			return &profile
+		}
+	}
+	return nil
+}
+
+// List 获取全部服务商配置
+func (r *ProviderRegistry) List() []ProviderProfile {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	profiles := make([]ProviderProfile, len(r.profiles))
+	copy(profiles, r.profiles)
+	return profiles
+}
+
+// LoadFile 从JSON文件加载服务商配置（ProviderProfile数组），文件不存在时忽略
+func (r *ProviderRegistry) LoadFile(filename string) (int, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("读取服务商配置文件失败: %v", err)
+	}
+
+	var profiles []ProviderProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return 0, fmt.Errorf("解析服务商配置文件失败: %v", err)
+	}
+	for i := range profiles {
+		if err := profiles[i].validate(); err != nil {
+			return 0, err
+		}
+	}
+
+	for _, profile := range profiles {
+		r.Register(profile)
+	}
+	return len(profiles), nil
+}
+
+// defaultProviders 全局服务商配置注册表
+var defaultProviders = NewProviderRegistry()
+
+// LookupProvider 在全局注册表中查找服务商配置
+func LookupProvider(host string) *ProviderProfile {
+	return defaultProviders.Lookup(host)
+}
+
+// RegisterProvider 向全局注册表注册服务商配置
+func RegisterProvider(profile ProviderProfile) {
+	defaultProviders.Register(profile)
+}
+
+// ListProviders 获取全局注册表中的全部服务商配置
+func ListProviders() []ProviderProfile {
+	return defaultProviders.List()
+}
+
+// LoadProviderFile 从配置文件加载服务商配置到全局注册表
+func LoadProviderFile(filename string) (int, error) {
+	return defaultProviders.LoadFile(filename)
+}
+
+// shouldSendIMAPID 判断服务器是否需要在登录后发送IMAP ID命令
+func shouldSendIMAPID(host string) bool {
+	profile := LookupProvider(host)
+	return profile != nil && profile.SendID
+}
+
+// builtinProviders 内置服务商配置
+var builtinProviders = []ProviderProfile{
+	{
+		Name:         "Gmail",
+		HostPatterns: []string{"gmail.com"},
+		IMAPHost:     "imap.gmail.com",
+		IMAPPort:     993,
+		POP3Host:     "pop.gmail.com",
+		POP3Port:     995,
+		SSL:          true,
+		Hints: []string{
+			"开启两步验证",
+			"生成应用专用密码",
+			"使用应用专用密码而非Google账号密码",
+		},
+	},
+	{
+		Name:         "QQ邮箱",
+		HostPatterns: []string{"qq.com"},
+		IMAPHost:     "imap.qq.com",
+		IMAPPort:     993,
+		POP3Host:     "pop.qq.com",
+		POP3Port:     995,
+		SSL:          true,
+		Hints: []string{
+			"登录QQ邮箱网页版",
+			"进入设置 > 账户",
+			"开启IMAP/SMTP服务",
+			"生成授权码作为密码",
+		},
+	},
+	{
+		Name:         "163邮箱",
+		HostPatterns: []string{"163.com"},
+		SendID:       true,
+		IMAPHost:     "imap.163.com",
+		IMAPPort:     993,
+		POP3Host:     "pop.163.com",
+		POP3Port:     995,
+		SSL:          true,
+		Hints: []string{
+			"登录163邮箱网页版",
+			"进入设置 > POP3/SMTP/IMAP",
+			"开启IMAP/SMTP服务",
+			"使用授权码作为密码",
+		},
+	},
+	{
+		Name:         "126邮箱",
+		HostPatterns: []string{"126.com"},
+		SendID:       true,
+		IMAPHost:     "imap.126.com",
+		IMAPPort:     993,
+		POP3Host:     "pop.126.com",
+		POP3Port:     995,
+		SSL:          true,
+		Hints: []string{
+			"登录126邮箱网页版",
+			"进入设置 > POP3/SMTP/IMAP",
+			"开启IMAP/SMTP服务",
+			"使用授权码作为密码，而非登录密码",
+		},
+	},
+	{
+		Name:         "阿里云企业邮箱",
+		HostPatterns: []string{"qiye.aliyun.com"},
+		SendID:       true,
+		NoSearch:     true,
+		IMAPHost:     "imap.qiye.aliyun.com",
+		IMAPPort:     993,
+		POP3Host:     "pop.qiye.aliyun.com",
+		POP3Port:     995,
+		SSL:          true,
+		Hints: []string{
+			"在企业邮箱管理后台确认已开启IMAP/POP3服务",
+			"使用邮箱账号和密码（或客户端专用密码）登录",
+		},
+	},
+}
@@ -0,0 +1,21 @@
+package email
+
+import "strings"
+
+// ProtocolSMTP 推送邮箱协议标识，邮件由内置SMTP/LMTP接收服务推送，不主动拉取
+const ProtocolSMTP = "SMTP"
+
+// isPush 判断邮箱是否为推送邮箱
+func isPush(config MailboxConfig) bool {
+	return strings.EqualFold(config.Protocol, ProtocolSMTP)
+}
+
+// IsPushProtocol 判断协议是否为推送方式
+func IsPushProtocol(protocol string) bool {
+	return strings.EqualFold(protocol, ProtocolSMTP)
+}
+
+// isPush 判断客户端对应的邮箱是否为推送邮箱
+func (c *Client) isPush() bool {
+	return isPush(c.mailboxConfig())
+}
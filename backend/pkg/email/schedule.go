@@ -0,0 +1,135 @@
+package email
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// scheduleWindow 每日监控时间段（分钟数，相对于零点）
+type scheduleWindow struct {
+	start int
+	end   int
+}
+
+// parseSchedule 解析监控时间段，格式如 "08:00-12:00,13:30-20:00"
+// 结束时间早于开始时间表示跨越零点，如 "22:00-06:00"
+func parseSchedule(schedule string) ([]scheduleWindow, error) {
+	var windows []scheduleWindow
+	for _, part := range strings.Split(schedule, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		bounds := strings.Split(part, "-")
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("时间段格式错误: %s，应为 HH:MM-HH:MM", part)
+		}
+
+		start, err := parseClock(bounds[0])
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseClock(bounds[1])
+		if err != nil {
+			return nil, err
+		}
+		if start == end {
+			return nil, fmt.Errorf("时间段开始和结束时间不能相同: %s", part)
+		}
+
+		windows = append(windows, scheduleWindow{start: start, end: end})
+	}
+	return windows, nil
+}
+
+// parseClock 解析 HH:MM 格式的时间，返回零点起的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("时间格式错误: %s，应为 HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ValidateSchedule 校验监控时间段配置
+func ValidateSchedule(schedule string) error {
+	_, err := parseSchedule(schedule)
+	return err
+}
+
+// inSchedule 判断当前时间是否在监控时间段内，未配置时间段时始终返回true
+// 时间段按now所在的时区解释，调用方应传入系统时区的当前时间
+func inSchedule(schedule string, now time.Time) bool {
+	windows, err := parseSchedule(schedule)
+	if err != nil || len(windows) == 0 {
+		return true
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	for _, w := range windows {
+		if w.start < w.end {
+			if minute >= w.start && minute < w.end {
+				return true
+			}
+		} else if minute >= w.start || minute < w.end {
+			// 跨越零点的时间段
+			return true
+		}
+	}
+	return false
+}
+
+// checkInterval 获取邮箱的检查间隔，未单独配置时使用全局配置
+func (m *Monitor) checkInterval(mailboxConfig MailboxConfig) time.Duration {
+	if mailboxConfig.CheckInterval > 0 {
+		return mailboxConfig.CheckInterval
+	}
+	return m.config.CheckInterval
+}
+
+// maxRetries 获取邮箱的最大重试次数，未单独配置时使用全局配置
+func (m *Monitor) maxRetries(mailboxConfig MailboxConfig) int {
+	if mailboxConfig.MaxRetries > 0 {
+		return mailboxConfig.MaxRetries
+	}
+	return m.config.MaxRetries
+}
+
+// retryInterval 获取邮箱的重试基础间隔，每次重试按指数退避递增
+func (m *Monitor) retryInterval(mailboxConfig MailboxConfig) time.Duration {
+	if mailboxConfig.RetryInterval > 0 {
+		return mailboxConfig.RetryInterval
+	}
+	return time.Second
+}
+
+// onlyUnread 判断邮箱是否只处理未读邮件，邮箱未单独配置时使用全局配置
+func (m *Monitor) onlyUnread(mailboxConfig MailboxConfig) bool {
+	if mailboxConfig.OnlyUnread != nil {
+		return *mailboxConfig.OnlyUnread
+	}
+	return m.config.OnlyUnread
+}
+
+// markAsRead 判断邮箱处理后是否标记为已读，邮箱未单独配置时使用全局配置
+func (m *Monitor) markAsRead(mailboxConfig MailboxConfig) bool {
+	if mailboxConfig.MarkAsRead != nil {
+		return *mailboxConfig.MarkAsRead
+	}
+	return m.config.MarkAsRead
+}
+
+// currentMailboxConfig 获取邮箱的最新配置，使配置修改无需重启监控即可生效
+func (m *Monitor) currentMailboxConfig(mailboxConfig MailboxConfig) MailboxConfig {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	for _, mb := range m.mailboxes {
+		if mb.ID == mailboxConfig.ID {
+			return mb
+		}
+	}
+	return mailboxConfig
+}
@@ -0,0 +1,82 @@
+package email
+
+import (
+	"testing"
+	"time"
+
+	"emailAlert/pkg/timezone"
+)
+
+// TestInScheduleWindowEdges 时间段按传入时间所在的时区判断，包含开始时间、不包含结束时间
+func TestInScheduleWindowEdges(t *testing.T) {
+	loc, err := timezone.Load("America/New_York")
+	if err != nil {
+		t.Fatalf("加载时区失败: %v", err)
+	}
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, 3, 15, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		schedule string
+		now      time.Time
+		want     bool
+	}{
+		{"08:00-12:00", at(7, 59), false},
+		{"08:00-12:00", at(8, 0), true},
+		{"08:00-12:00", at(11, 59), true},
+		{"08:00-12:00", at(12, 0), false},
+		{"22:00-06:00", at(21, 59), false},
+		{"22:00-06:00", at(22, 0), true},
+		{"22:00-06:00", at(0, 0), true},
+		{"22:00-06:00", at(5, 59), true},
+		{"22:00-06:00", at(6, 0), false},
+		{"08:00-12:00,13:30-20:00", at(12, 30), false},
+		{"08:00-12:00,13:30-20:00", at(13, 30), true},
+		{"", at(3, 0), true},
+	}
+	for _, tt := range tests {
+		if got := inSchedule(tt.schedule, tt.now); got != tt.want {
+			t.Errorf("inSchedule(%q, %s) = %v，期望 %v", tt.schedule, tt.now.Format("15:04 MST"), got, tt.want)
+		}
+	}
+
+	// 同一时刻在UTC下位于时间段外，按系统时区（纽约）换算后位于时间段内
+	now := at(8, 0)
+	if inSchedule("08:00-12:00", now.UTC()) {
+		t.Fatalf("UTC时间 %s 不应在时间段内", now.UTC().Format("15:04"))
+	}
+	if !inSchedule("08:00-12:00", now.UTC().In(loc)) {
+		t.Fatalf("换算为纽约时间 %s 后应在时间段内", now.In(loc).Format("15:04"))
+	}
+}
+
+// TestMailboxReadOverrides 邮箱未单独配置时使用全局配置，单独配置为false时可以关闭全局开启的设置
+func TestMailboxReadOverrides(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		global   bool
+		override *bool
+		want     bool
+	}{
+		{false, nil, false},
+		{true, nil, true},
+		{false, &enabled, true},
+		{true, &disabled, false},
+		{true, &enabled, true},
+	}
+	for _, tt := range tests {
+		config := DefaultMonitorConfig()
+		config.OnlyUnread = tt.global
+		config.MarkAsRead = tt.global
+		monitor := NewMonitor(config, nil)
+		mailbox := MailboxConfig{OnlyUnread: tt.override, MarkAsRead: tt.override}
+
+		if got := monitor.onlyUnread(mailbox); got != tt.want {
+			t.Errorf("全局=%v 邮箱=%v: onlyUnread = %v，期望 %v", tt.global, tt.override, got, tt.want)
+		}
+		if got := monitor.markAsRead(mailbox); got != tt.want {
+			t.Errorf("全局=%v 邮箱=%v: markAsRead = %v，期望 %v", tt.global, tt.override, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package email
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"emailAlert/pkg/proxy"
+
+	"github.com/emersion/go-imap/client"
+)
+
+// TLS连接模式
+const (
+	TLSModeNone     = "none"     // 明文连接
+	TLSModeImplicit = "implicit" // 连接建立后立即进行TLS握手（IMAPS 993 / POP3S 995）
+	TLSModeSTARTTLS = "starttls" // 明文连接后通过STARTTLS/STLS升级为TLS（143 / 110）
+)
+
+// imapDialTimeout IMAP连接超时时间
+const imapDialTimeout = 30 * time.Second
+
+// tlsVersions 支持配置的最低TLS版本
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSOptions 邮箱连接的TLS配置，零值表示按SSL字段使用系统默认设置
+type TLSOptions struct {
+	Mode               string `json:"mode"`                 // 连接模式：none/implicit/starttls，为空时按SSL字段决定
+	CACert             string `json:"ca_cert"`              // 自定义CA证书（PEM内容或文件路径），用于私有CA签发的服务器证书
+	ClientCert         string `json:"client_cert"`          // 客户端证书（PEM内容或文件路径）
+	ClientKey          string `json:"-"`                    // 客户端私钥（PEM内容或文件路径）
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"` // 跳过服务器证书校验，仅用于测试环境
+	MinVersion         string `json:"min_version"`          // 最低TLS版本：1.0/1.1/1.2/1.3，为空时使用默认值
+}
+
+// tlsMode 获取邮箱的TLS连接模式，未配置时按SSL字段决定
+func tlsMode(config MailboxConfig) string {
+	if mode := strings.ToLower(strings.TrimSpace(config.TLS.Mode)); mode != "" {
+		return mode
+	}
+	if config.SSL {
+		return TLSModeImplicit
+	}
+	return TLSModeNone
+}
+
+// ValidateTLSOptions 校验TLS配置，证书内容可以被解析时才视为有效
+func ValidateTLSOptions(opts TLSOptions) error {
+	switch strings.ToLower(strings.TrimSpace(opts.Mode)) {
+	case "", TLSModeNone, TLSModeImplicit, TLSModeSTARTTLS:
+	default:
+		return fmt.Errorf("不支持的TLS模式: %s", opts.Mode)
+	}
+	_, err := newTLSConfig("", opts)
+	return err
+}
+
+// newTLSConfig 根据TLS配置构建tls.Config
+func newTLSConfig(serverName string, opts TLSOptions) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if version := strings.TrimSpace(opts.MinVersion); version != "" {
+		v, ok := tlsVersions[version]
+		if !ok {
+			return nil, fmt.Errorf("不支持的最低TLS版本: %s", version)
+		}
+		tlsConfig.MinVersion = v
+	}
+
+	if strings.TrimSpace(opts.CACert) != "" {
+		caPEM, err := loadPEM(opts.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("读取CA证书失败: %v", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("CA证书中没有有效的PEM证书")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	hasCert := strings.TrimSpace(opts.ClientCert) != ""
+	hasKey := strings.TrimSpace(opts.ClientKey) != ""
+	if hasCert != hasKey {
+		return nil, errors.New("客户端证书和私钥必须同时配置")
+	}
+	if hasCert {
+		certPEM, err := loadPEM(opts.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端证书失败: %v", err)
+		}
+		keyPEM, err := loadPEM(opts.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端私钥失败: %v", err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("解析客户端证书失败: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// mailboxTLSConfig 构建邮箱连接使用的tls.Config
+func mailboxTLSConfig(config MailboxConfig) (*tls.Config, error) {
+	tlsConfig, err := newTLSConfig(config.Host, config.TLS)
+	if err != nil {
+		return nil, fmt.Errorf("TLS配置无效: %v", err)
+	}
+	return tlsConfig, nil
+}
+
+// loadPEM 读取PEM内容，不是PEM格式时视为文件路径
+func loadPEM(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "-----BEGIN") {
+		return []byte(value), nil
+	}
+	return os.ReadFile(value)
+}
+
+// dialIMAP 按邮箱的TLS配置建立IMAP连接（未登录）
+func dialIMAP(config MailboxConfig) (*client.Client, error) {
+	addr := net.JoinHostPort(config.Host, strconv.Itoa(config.Port))
+
+	dialer, err := proxy.NewDialer(config.Proxy, imapDialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("代理配置无效: %v", err)
+	}
+
+	mode := tlsMode(config)
+	if mode == TLSModeNone {
+		conn, err := client.DialWithDialer(dialer, addr)
+		if err != nil {
+			return nil, fmt.Errorf("连接服务器失败: %v", err)
+		}
+		return conn, nil
+	}
+
+	tlsConfig, err := mailboxTLSConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if mode == TLSModeImplicit {
+		conn, err := client.DialWithDialerTLS(dialer, addr, tlsConfig)
+		if err != nil {
+			return nil, fmt.Errorf("连接服务器失败: %v", err)
+		}
+		return conn, nil
+	}
+
+	conn, err := client.DialWithDialer(dialer, addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接服务器失败: %v", err)
+	}
+	// 服务器不支持STARTTLS时不降级为明文，避免密码以明文传输
+	if ok, _ := conn.SupportStartTLS(); !ok {
+		conn.Logout()
+		return nil, errors.New("服务器不支持STARTTLS")
+	}
+	if err := conn.StartTLS(tlsConfig); err != nil {
+		conn.Logout()
+		return nil, fmt.Errorf("STARTTLS握手失败: %v", err)
+	}
+	return conn, nil
+}
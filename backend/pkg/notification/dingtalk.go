@@ -28,6 +28,20 @@ func NewDingTalkNotifier() *DingTalkNotifier {
 	}
 }
 
+// WithProxy 返回使用指定代理发送请求的钉钉通知器，为空时使用全局代理，为direct时直连
+func (d *DingTalkNotifier) WithProxy(proxyURL string) (*DingTalkNotifier, error) {
+	transport, err := proxyTransport(proxyURL, false)
+	if err != nil {
+		return nil, err
+	}
+	return &DingTalkNotifier{
+		client: &http.Client{
+			Timeout:   d.client.Timeout,
+			Transport: transport,
+		},
+	}, nil
+}
+
 // DingTalkRobotMessage 钉钉群机器人消息结构
 type DingTalkRobotMessage struct {
 	MsgType  string                 `json:"msgtype"`
@@ -9,11 +9,15 @@ import (
 	"net/smtp"
 	"strings"
 	"time"
+
+	"emailAlert/pkg/oauth"
+	"emailAlert/pkg/proxy"
 )
 
 // EmailNotifier 邮件通知器
 type EmailNotifier struct {
 	// 可以添加连接池等高级功能
+	proxy string // 代理地址，为空时使用全局代理，为direct时直连
 }
 
 // NewEmailNotifier 创建邮件通知器
@@ -21,24 +25,60 @@ func NewEmailNotifier() *EmailNotifier {
 	return &EmailNotifier{}
 }
 
+// WithProxy 返回通过指定代理连接SMTP服务器的邮件通知器，为空时使用全局代理，为direct时直连
+func (e *EmailNotifier) WithProxy(proxyURL string) (*EmailNotifier, error) {
+	if err := proxy.Validate(proxyURL); err != nil {
+		return nil, err
+	}
+	return &EmailNotifier{proxy: proxyURL}, nil
+}
+
+// dial 建立到SMTP服务器的TCP连接（按代理配置直连或经代理转发）
+func (e *EmailNotifier) dial(addr string, timeout time.Duration) (net.Conn, error) {
+	dialer, err := proxy.NewDialer(e.proxy, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.Dial("tcp", addr)
+}
+
+// dialTLS 建立到SMTP服务器的TLS连接
+func (e *EmailNotifier) dialTLS(addr string, timeout time.Duration, tlsConfig *tls.Config) (net.Conn, error) {
+	conn, err := e.dial(addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, tlsConfig)
+	tlsConn.SetDeadline(time.Now().Add(timeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
 // EmailConfig 邮件配置结构
 type EmailConfig struct {
-	Host     string   `json:"host"`                // SMTP服务器地址
-	Port     int      `json:"port"`                // SMTP端口
-	Username string   `json:"username"`            // 用户名
-	Password string   `json:"password"`            // 密码或应用专用密码
-	SSL      bool     `json:"ssl"`                 // 是否启用SSL/TLS
-	From     string   `json:"from"`                // 发件人地址
-	FromName string   `json:"from_name,omitempty"` // 发件人姓名
-	To       []string `json:"to"`                  // 收件人列表
-	CC       []string `json:"cc,omitempty"`        // 抄送列表
-	BCC      []string `json:"bcc,omitempty"`       // 密送列表
-	Subject  string   `json:"subject,omitempty"`   // 邮件主题模板
-	Template string   `json:"template,omitempty"`  // 邮件内容模板
-	Format   string   `json:"format,omitempty"`    // 邮件格式：text/html/mixed
-	Timeout  int      `json:"timeout,omitempty"`   // 连接超时时间（秒）
-	ReplyTo  string   `json:"reply_to,omitempty"`  // 回复地址
-	Priority int      `json:"priority,omitempty"`  // 优先级：1(高) 2(普通) 3(低)
+	Host     string        `json:"host"`                // SMTP服务器地址
+	Port     int           `json:"port"`                // SMTP端口
+	Username string        `json:"username"`            // 用户名
+	Password string        `json:"password"`            // 密码或应用专用密码
+	AuthType string        `json:"auth_type,omitempty"` // 认证方式：password（默认）/oauth2
+	OAuth    *oauth.Config `json:"oauth,omitempty"`     // OAuth2凭据，AuthType为oauth2时使用
+	SSL      bool          `json:"ssl"`                 // 是否启用SSL/TLS
+	From     string        `json:"from"`                // 发件人地址
+	FromName string        `json:"from_name,omitempty"` // 发件人姓名
+	To       []string      `json:"to"`                  // 收件人列表
+	CC       []string      `json:"cc,omitempty"`        // 抄送列表
+	BCC      []string      `json:"bcc,omitempty"`       // 密送列表
+	Subject  string        `json:"subject,omitempty"`   // 邮件主题模板
+	Template string        `json:"template,omitempty"`  // 邮件内容模板
+	Format   string        `json:"format,omitempty"`    // 邮件格式：text/html/mixed
+	Timeout  int           `json:"timeout,omitempty"`   // 连接超时时间（秒）
+	ReplyTo  string        `json:"reply_to,omitempty"`  // 回复地址
+	Priority int           `json:"priority,omitempty"`  // 优先级：1(高) 2(普通) 3(低)
 }
 
 // EmailMessage 邮件消息结构
@@ -465,11 +505,11 @@ func (e *EmailNotifier) createDirectTLSClient(config *EmailConfig, addr string,
 	}
 
 	// 直接建立TLS连接
-	tlsConn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+	tlsConn, err := e.dialTLS(addr, timeout, tlsConfig)
 	if err != nil {
 		// 如果TLS连接失败，尝试降级到TLS 1.0
 		tlsConfig.MinVersion = tls.VersionTLS10
-		tlsConn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+		tlsConn, err = e.dialTLS(addr, timeout, tlsConfig)
 		if err != nil {
 			return nil, fmt.Errorf("建立TLS连接失败 (端口%d): %v", config.Port, err)
 		}
@@ -483,12 +523,9 @@ func (e *EmailNotifier) createDirectTLSClient(config *EmailConfig, addr string,
 	}
 
 	// 身份验证
-	if config.Username != "" && config.Password != "" {
-		auth := smtp.PlainAuth("", config.Username, config.Password, config.Host)
-		if err = client.Auth(auth); err != nil {
-			client.Close()
-			return nil, fmt.Errorf("SMTP身份验证失败: %v", err)
-		}
+	if err = e.authenticate(client, config); err != nil {
+		client.Close()
+		return nil, err
 	}
 
 	return client, nil
@@ -497,7 +534,7 @@ func (e *EmailNotifier) createDirectTLSClient(config *EmailConfig, addr string,
 // createSTARTTLSClient 创建STARTTLS连接的SMTP客户端（端口25、587等）
 func (e *EmailNotifier) createSTARTTLSClient(config *EmailConfig, addr string, timeout time.Duration) (*smtp.Client, error) {
 	// 建立普通TCP连接
-	conn, err := net.DialTimeout("tcp", addr, timeout)
+	conn, err := e.dial(addr, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("连接SMTP服务器失败: %v", err)
 	}
@@ -538,15 +575,77 @@ func (e *EmailNotifier) createSTARTTLSClient(config *EmailConfig, addr string, t
 	}
 
 	// 身份验证
+	if err = e.authenticate(client, config); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// authenticate 按配置的认证方式进行SMTP身份验证
+func (e *EmailNotifier) authenticate(client *smtp.Client, config *EmailConfig) error {
+	if strings.EqualFold(config.AuthType, oauth.AuthTypeOAuth2) {
+		if config.OAuth == nil {
+			return fmt.Errorf("未配置OAuth2凭据")
+		}
+
+		token, err := oauth.DefaultTokenSource.Token(*config.OAuth)
+		if err != nil {
+			return err
+		}
+
+		username := config.Username
+		if username == "" {
+			username = config.From
+		}
+
+		// 服务器只声明OAUTHBEARER时使用OAUTHBEARER，否则使用XOAUTH2
+		auth := &oauthSMTPAuth{mechanism: "XOAUTH2", response: oauth.XOAuth2Response(username, token)}
+		if ok, mechanisms := client.Extension("AUTH"); ok {
+			mechanisms = strings.ToUpper(mechanisms)
+			if !strings.Contains(mechanisms, "XOAUTH2") && strings.Contains(mechanisms, "OAUTHBEARER") {
+				auth = &oauthSMTPAuth{mechanism: "OAUTHBEARER", response: oauth.OAuthBearerResponse(username, token)}
+			}
+		}
+
+		if err := client.Auth(auth); err != nil {
+			// 令牌可能已被吊销，下次重新获取
+			oauth.DefaultTokenSource.Invalidate(*config.OAuth)
+			return fmt.Errorf("SMTP %s认证失败: %v", auth.mechanism, err)
+		}
+		return nil
+	}
+
 	if config.Username != "" && config.Password != "" {
 		auth := smtp.PlainAuth("", config.Username, config.Password, config.Host)
-		if err = client.Auth(auth); err != nil {
-			client.Close()
-			return nil, fmt.Errorf("SMTP身份验证失败: %v", err)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP身份验证失败: %v", err)
 		}
 	}
+	return nil
+}
 
-	return client, nil
+// oauthSMTPAuth SMTP XOAUTH2/OAUTHBEARER认证
+type oauthSMTPAuth struct {
+	mechanism string
+	response  []byte
+}
+
+// Start 返回认证机制和初始响应，只允许在加密连接上发送令牌
+func (a *oauthSMTPAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS {
+		return "", nil, fmt.Errorf("连接未加密，拒绝发送OAuth2令牌")
+	}
+	return a.mechanism, a.response, nil
+}
+
+// Next 认证失败时服务器会返回错误详情作为质询，回复空响应以结束本次认证
+func (a *oauthSMTPAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return []byte{}, nil
+	}
+	return nil, nil
 }
 
 // createSMTPClientForTest 创建用于测试的SMTP客户端（不进行身份验证）
@@ -571,11 +670,11 @@ func (e *EmailNotifier) createDirectTLSClientForTest(config *EmailConfig, addr s
 	}
 
 	// 直接建立TLS连接
-	tlsConn, err := tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+	tlsConn, err := e.dialTLS(addr, timeout, tlsConfig)
 	if err != nil {
 		// 如果TLS连接失败，尝试降级到TLS 1.0
 		tlsConfig.MinVersion = tls.VersionTLS10
-		tlsConn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", addr, tlsConfig)
+		tlsConn, err = e.dialTLS(addr, timeout, tlsConfig)
 		if err != nil {
 			return nil, fmt.Errorf("建立TLS连接失败 (端口%d): %v", config.Port, err)
 		}
@@ -595,7 +694,7 @@ func (e *EmailNotifier) createDirectTLSClientForTest(config *EmailConfig, addr s
 // createSTARTTLSClientForTest 创建STARTTLS连接的SMTP客户端（测试用，无身份验证）
 func (e *EmailNotifier) createSTARTTLSClientForTest(config *EmailConfig, addr string, timeout time.Duration) (*smtp.Client, error) {
 	// 建立普通TCP连接
-	conn, err := net.DialTimeout("tcp", addr, timeout)
+	conn, err := e.dial(addr, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("连接SMTP服务器失败: %v", err)
 	}
@@ -657,6 +756,17 @@ func (e *EmailNotifier) ValidateConfig(config *EmailConfig) error {
 		return fmt.Errorf("发件人地址不能为空")
 	}
 
+	if strings.EqualFold(config.AuthType, oauth.AuthTypeOAuth2) {
+		if config.OAuth == nil {
+			return fmt.Errorf("认证方式为oauth2时必须配置OAuth2凭据")
+		}
+		if err := config.OAuth.Validate(); err != nil {
+			return err
+		}
+	} else if config.AuthType != "" && config.AuthType != oauth.AuthTypePassword {
+		return fmt.Errorf("不支持的认证方式: %s", config.AuthType)
+	}
+
 	if !e.isValidEmail(config.From) {
 		debugInfo := e.debugEmailValidation(config.From)
 		return fmt.Errorf("发件人地址格式错误: '%s' - %s", config.From, debugInfo)
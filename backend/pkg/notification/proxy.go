@@ -0,0 +1,45 @@
+package notification
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync"
+
+	"emailAlert/pkg/proxy"
+)
+
+// transportKey HTTP Transport缓存键
+type transportKey struct {
+	proxy      string
+	skipVerify bool
+}
+
+var (
+	transportMutex sync.Mutex
+	transports     = make(map[transportKey]*http.Transport)
+)
+
+// proxyTransport 获取代理对应的HTTP Transport，相同的代理配置共享连接池
+// raw为空时使用全局代理，为direct时直连
+func proxyTransport(raw string, skipVerify bool) (*http.Transport, error) {
+	resolved := proxy.Resolve(raw)
+	if resolved == "" {
+		resolved = proxy.Direct
+	}
+	key := transportKey{proxy: resolved, skipVerify: skipVerify}
+
+	transportMutex.Lock()
+	defer transportMutex.Unlock()
+
+	if transport, ok := transports[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := proxy.NewHTTPTransport(resolved)
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: skipVerify}
+	transports[key] = transport
+	return transport, nil
+}
@@ -2,7 +2,6 @@ package notification
 
 import (
 	"bytes"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,21 +13,34 @@ import (
 
 // WebhookNotifier 自定义Webhook通知器
 type WebhookNotifier struct {
-	client *http.Client
+	proxy string // 代理地址，为空时使用全局代理，为direct时直连
 }
 
 // NewWebhookNotifier 创建Webhook通知器
 func NewWebhookNotifier() *WebhookNotifier {
-	return &WebhookNotifier{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{
-					InsecureSkipVerify: false, // 默认验证SSL证书
-				},
-			},
-		},
+	return &WebhookNotifier{}
+}
+
+// WithProxy 返回使用指定代理发送请求的Webhook通知器，为空时使用全局代理，为direct时直连
+func (w *WebhookNotifier) WithProxy(proxyURL string) (*WebhookNotifier, error) {
+	if _, err := proxyTransport(proxyURL, false); err != nil {
+		return nil, err
+	}
+	return &WebhookNotifier{proxy: proxyURL}, nil
+}
+
+// httpClient 按Webhook配置的超时和SSL验证设置创建HTTP客户端
+func (w *WebhookNotifier) httpClient(config *WebhookConfig) (*http.Client, error) {
+	transport, err := proxyTransport(w.proxy, config.SkipSSL) // 默认验证SSL证书
+	if err != nil {
+		return nil, err
 	}
+
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = time.Duration(config.Timeout) * time.Second
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
 }
 
 // WebhookConfig Webhook配置结构
@@ -81,16 +93,14 @@ func (w *WebhookNotifier) SendMessage(config *WebhookConfig, title, content stri
 	if config.ContentType == "" {
 		config.ContentType = "application/json"
 	}
-	if config.Timeout > 0 {
-		w.client.Timeout = time.Duration(config.Timeout) * time.Second
-	}
 	if config.Retries == 0 {
 		config.Retries = 1
 	}
 
-	// 设置SSL验证
-	if transport, ok := w.client.Transport.(*http.Transport); ok {
-		transport.TLSClientConfig.InsecureSkipVerify = config.SkipSSL
+	// 按超时、SSL验证和代理设置创建客户端
+	client, err := w.httpClient(config)
+	if err != nil {
+		return nil, err
 	}
 
 	// 构建消息
@@ -112,7 +122,7 @@ func (w *WebhookNotifier) SendMessage(config *WebhookConfig, title, content stri
 			time.Sleep(time.Duration(i*2) * time.Second) // 指数退避
 		}
 
-		response, lastErr = w.sendRequest(config, message)
+		response, lastErr = w.sendRequest(client, config, message)
 		if lastErr == nil && response.Success {
 			break
 		}
@@ -126,7 +136,7 @@ func (w *WebhookNotifier) SendMessage(config *WebhookConfig, title, content stri
 }
 
 // sendRequest 发送HTTP请求
-func (w *WebhookNotifier) sendRequest(config *WebhookConfig, message *WebhookMessage) (*WebhookResponse, error) {
+func (w *WebhookNotifier) sendRequest(client *http.Client, config *WebhookConfig, message *WebhookMessage) (*WebhookResponse, error) {
 	startTime := time.Now()
 
 	// 构建请求体
@@ -155,7 +165,7 @@ func (w *WebhookNotifier) sendRequest(config *WebhookConfig, message *WebhookMes
 	}
 
 	// 发送请求
-	resp, err := w.client.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return &WebhookResponse{
 			Success:    false,
@@ -23,6 +23,20 @@ func NewWeChatNotifier() *WeChatNotifier {
 	}
 }
 
+// WithProxy 返回使用指定代理发送请求的企业微信通知器，为空时使用全局代理，为direct时直连
+func (w *WeChatNotifier) WithProxy(proxyURL string) (*WeChatNotifier, error) {
+	transport, err := proxyTransport(proxyURL, false)
+	if err != nil {
+		return nil, err
+	}
+	return &WeChatNotifier{
+		client: &http.Client{
+			Timeout:   w.client.Timeout,
+			Transport: transport,
+		},
+	}, nil
+}
+
 // WeChatRobotMessage 企业微信机器人消息结构
 type WeChatRobotMessage struct {
 	MsgType  string               `json:"msgtype"`
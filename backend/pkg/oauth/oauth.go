@@ -0,0 +1,285 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"emailAlert/pkg/proxy"
+)
+
+// AuthTypePassword 使用用户名密码认证
+const AuthTypePassword = "password"
+
+// AuthTypeOAuth2 使用OAuth2 access token认证（SASL XOAUTH2/OAUTHBEARER）
+const AuthTypeOAuth2 = "oauth2"
+
+const (
+	defaultRequestTimeout = 30 * time.Second
+	defaultTokenLifetime  = time.Hour        // 令牌端点未返回expires_in时的默认有效期
+	tokenExpirySkew       = 60 * time.Second // 提前刷新，避免令牌在使用过程中过期
+)
+
+// Config OAuth2凭据配置
+type Config struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+	TokenURL     string `json:"token_url"`       // 令牌端点，如 https://login.microsoftonline.com/common/oauth2/v2.0/token
+	Scope        string `json:"scope,omitempty"` // 可选，多个用空格分隔
+}
+
+// Validate 校验OAuth2凭据配置
+func (c Config) Validate() error {
+	if c.ClientID == "" {
+		return errors.New("OAuth2 client_id不能为空")
+	}
+	if c.RefreshToken == "" {
+		return errors.New("OAuth2 refresh_token不能为空")
+	}
+	if c.TokenURL == "" {
+		return errors.New("OAuth2令牌端点不能为空")
+	}
+	u, err := url.Parse(c.TokenURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("OAuth2令牌端点格式错误: %s", c.TokenURL)
+	}
+	return nil
+}
+
+// key 缓存键，凭据任一项变化都视为新的凭据
+func (c Config) key() string {
+	sum := sha256.Sum256([]byte(c.TokenURL + "\x00" + c.ClientID + "\x00" + c.ClientSecret + "\x00" + c.RefreshToken + "\x00" + c.Scope))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenResponse 令牌端点响应（RFC 6749 5.1/5.2）
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	RefreshToken     string `json:"refresh_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// TokenError 请求令牌端点失败，包括无法访问令牌端点和令牌端点返回的错误
+type TokenError struct {
+	StatusCode int    // HTTP状态码，未收到响应时为0
+	Code       string // 令牌端点返回的错误码，如 invalid_grant
+	err        error
+}
+
+func (e *TokenError) Error() string {
+	return e.err.Error()
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.err
+}
+
+// Rejected 判断令牌端点是否拒绝了凭据（invalid_grant等错误码或4xx状态码），重试无法恢复
+// 网络错误、5xx、限流等暂时性故障返回false
+func (e *TokenError) Rejected() bool {
+	switch e.Code {
+	case "":
+	case "server_error", "temporarily_unavailable":
+		return false
+	default:
+		return true
+	}
+	if e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests {
+		return false
+	}
+	return e.StatusCode >= 400 && e.StatusCode < 500
+}
+
+// IsTransient 判断错误是否为令牌端点的暂时性故障，这类错误不代表凭据失效
+func IsTransient(err error) bool {
+	var tokenErr *TokenError
+	return errors.As(err, &tokenErr) && !tokenErr.Rejected()
+}
+
+// RefreshTokenStore 持久化令牌端点轮换后的refresh token，重启后使用新的令牌
+type RefreshTokenStore interface {
+	// SaveRefreshToken 保存轮换后的refresh token，config.RefreshToken为轮换前的令牌
+	SaveRefreshToken(config Config, refreshToken string) error
+}
+
+// cachedToken 缓存的access token
+type cachedToken struct {
+	accessToken  string
+	expiresAt    time.Time
+	refreshToken string // 令牌端点轮换后的refresh token
+}
+
+// TokenSource 使用refresh token换取access token，并在过期前缓存复用
+type TokenSource struct {
+	httpClient *http.Client
+	now        func() time.Time
+	mutex      sync.Mutex
+	tokens     map[string]*cachedToken
+	store      RefreshTokenStore
+}
+
+// NewTokenSource 创建令牌源，httpClient为nil时使用默认客户端
+func NewTokenSource(httpClient *http.Client) *TokenSource {
+	if httpClient == nil {
+		// 令牌端点按全局代理访问
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = nil
+		transport.DialContext = proxy.DialContext
+		httpClient = &http.Client{Timeout: defaultRequestTimeout, Transport: transport}
+	}
+	return &TokenSource{
+		httpClient: httpClient,
+		now:        time.Now,
+		tokens:     make(map[string]*cachedToken),
+	}
+}
+
+// DefaultTokenSource 默认令牌源，IMAP和SMTP共享同一份令牌缓存
+var DefaultTokenSource = NewTokenSource(nil)
+
+// SetRefreshTokenStore 设置refresh token的持久化存储，令牌端点轮换refresh token时写回
+func (s *TokenSource) SetRefreshTokenStore(store RefreshTokenStore) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.store = store
+}
+
+// Token 获取有效的access token，缓存过期或即将过期时自动刷新
+func (s *TokenSource) Token(config Config) (string, error) {
+	if err := config.Validate(); err != nil {
+		return "", err
+	}
+
+	key := config.key()
+
+	// 持锁刷新，避免同一凭据并发请求令牌端点
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cached := s.tokens[key]
+	if cached != nil && s.now().Add(tokenExpirySkew).Before(cached.expiresAt) {
+		return cached.accessToken, nil
+	}
+
+	refreshToken := config.RefreshToken
+	if cached != nil && cached.refreshToken != "" {
+		refreshToken = cached.refreshToken
+	}
+
+	resp, err := s.refresh(config, refreshToken)
+	if err != nil {
+		return "", err
+	}
+
+	lifetime := defaultTokenLifetime
+	if resp.ExpiresIn > 0 {
+		lifetime = time.Duration(resp.ExpiresIn) * time.Second
+	}
+	token := &cachedToken{
+		accessToken:  resp.AccessToken,
+		expiresAt:    s.now().Add(lifetime),
+		refreshToken: refreshToken,
+	}
+	if resp.RefreshToken != "" && resp.RefreshToken != refreshToken {
+		token.refreshToken = resp.RefreshToken
+		if s.store != nil {
+			previous := config
+			previous.RefreshToken = refreshToken
+			if err := s.store.SaveRefreshToken(previous, resp.RefreshToken); err != nil {
+				log.Printf("保存轮换后的OAuth2 refresh token失败: %v", err)
+			}
+		}
+	}
+	s.tokens[key] = token
+
+	return token.accessToken, nil
+}
+
+// Invalidate 丢弃缓存的access token，认证失败时调用以便下次重新获取
+func (s *TokenSource) Invalidate(config Config) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if cached, ok := s.tokens[config.key()]; ok {
+		cached.accessToken = ""
+		cached.expiresAt = time.Time{}
+	}
+}
+
+// refresh 使用refresh_token授权方式请求令牌端点
+func (s *TokenSource) refresh(config Config, refreshToken string) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", config.ClientID)
+	if config.ClientSecret != "" {
+		form.Set("client_secret", config.ClientSecret)
+	}
+	if config.Scope != "" {
+		form.Set("scope", config.Scope)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("创建令牌请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, &TokenError{err: fmt.Errorf("请求OAuth2令牌端点失败: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, &TokenError{StatusCode: resp.StatusCode, err: fmt.Errorf("读取令牌响应失败: %v", err)}
+	}
+
+	var result tokenResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &TokenError{StatusCode: resp.StatusCode, err: fmt.Errorf("解析令牌响应失败 (HTTP %d): %v", resp.StatusCode, err)}
+	}
+
+	if result.Error != "" {
+		tokenErr := &TokenError{StatusCode: resp.StatusCode, Code: result.Error}
+		if result.ErrorDescription != "" {
+			tokenErr.err = fmt.Errorf("刷新OAuth2令牌失败: %s: %s", result.Error, result.ErrorDescription)
+		} else {
+			tokenErr.err = fmt.Errorf("刷新OAuth2令牌失败: %s", result.Error)
+		}
+		return nil, tokenErr
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &TokenError{StatusCode: resp.StatusCode, err: fmt.Errorf("刷新OAuth2令牌失败: HTTP %d", resp.StatusCode)}
+	}
+	if result.AccessToken == "" {
+		return nil, &TokenError{StatusCode: resp.StatusCode, err: errors.New("令牌响应中缺少access_token")}
+	}
+
+	return &result, nil
+}
+
+// XOAuth2Response 构造SASL XOAUTH2初始响应
+func XOAuth2Response(username, accessToken string) []byte {
+	return []byte("user=" + username + "\x01auth=Bearer " + accessToken + "\x01\x01")
+}
+
+// OAuthBearerResponse 构造SASL OAUTHBEARER初始响应（RFC 7628）
+func OAuthBearerResponse(username, accessToken string) []byte {
+	return []byte("n,a=" + username + ",\x01auth=Bearer " + accessToken + "\x01\x01")
+}
@@ -0,0 +1,407 @@
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tokenEndpoint 测试用的令牌端点，按顺序返回预设的响应并记录收到的请求
+type tokenEndpoint struct {
+	t         *testing.T
+	mutex     sync.Mutex
+	responses []tokenReply
+	requests  []map[string]string
+}
+
+// tokenReply 令牌端点的一次响应
+type tokenReply struct {
+	status int
+	body   string
+}
+
+// start 启动令牌端点，返回令牌URL
+func (e *tokenEndpoint) start() string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			e.t.Errorf("令牌请求方法为 %s，期望POST", r.Method)
+		}
+		if ct := r.Header.Get("Content-Type"); ct != "application/x-www-form-urlencoded" {
+			e.t.Errorf("令牌请求Content-Type为 %s", ct)
+		}
+		if err := r.ParseForm(); err != nil {
+			e.t.Errorf("解析令牌请求失败: %v", err)
+		}
+		form := make(map[string]string)
+		for key := range r.PostForm {
+			form[key] = r.PostForm.Get(key)
+		}
+
+		e.mutex.Lock()
+		e.requests = append(e.requests, form)
+		if len(e.responses) == 0 {
+			e.mutex.Unlock()
+			e.t.Errorf("令牌端点收到多余的请求")
+			http.Error(w, "unexpected request", http.StatusInternalServerError)
+			return
+		}
+		reply := e.responses[0]
+		e.responses = e.responses[1:]
+		e.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(reply.status)
+		fmt.Fprint(w, reply.body)
+	}))
+	e.t.Cleanup(server.Close)
+	return server.URL + "/token"
+}
+
+// requestCount 已收到的请求数
+func (e *tokenEndpoint) requestCount() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return len(e.requests)
+}
+
+// request 第i个请求的表单
+func (e *tokenEndpoint) request(i int) map[string]string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.requests[i]
+}
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// newTestSource 创建使用测试时钟的令牌源
+func newTestSource(clock *fakeClock) *TokenSource {
+	source := NewTokenSource(&http.Client{Timeout: 5 * time.Second})
+	source.now = clock.Now
+	return source
+}
+
+func TestTokenRefreshAndCache(t *testing.T) {
+	endpoint := &tokenEndpoint{t: t, responses: []tokenReply{
+		{status: http.StatusOK, body: `{"access_token":"at-1","token_type":"Bearer","expires_in":3600}`},
+		{status: http.StatusOK, body: `{"access_token":"at-2","token_type":"Bearer","expires_in":3600}`},
+	}}
+	config := Config{
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RefreshToken: "rt-1",
+		TokenURL:     endpoint.start(),
+		Scope:        "https://outlook.office.com/IMAP.AccessAsUser.All offline_access",
+	}
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	source := newTestSource(clock)
+
+	token, err := source.Token(config)
+	if err != nil {
+		t.Fatalf("获取令牌失败: %v", err)
+	}
+	if token != "at-1" {
+		t.Fatalf("令牌为 %s，期望 at-1", token)
+	}
+
+	form := endpoint.request(0)
+	expected := map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": "rt-1",
+		"client_id":     "client",
+		"client_secret": "secret",
+		"scope":         config.Scope,
+	}
+	for key, value := range expected {
+		if form[key] != value {
+			t.Errorf("令牌请求参数 %s 为 %q，期望 %q", key, form[key], value)
+		}
+	}
+
+	// 有效期内直接使用缓存
+	clock.now = clock.now.Add(30 * time.Minute)
+	if token, err = source.Token(config); err != nil || token != "at-1" {
+		t.Fatalf("缓存的令牌为 %s (%v)，期望 at-1", token, err)
+	}
+	if n := endpoint.requestCount(); n != 1 {
+		t.Fatalf("令牌端点收到 %d 次请求，期望1次", n)
+	}
+
+	// 距过期不足提前刷新时间时重新获取
+	clock.now = clock.now.Add(30*time.Minute - tokenExpirySkew + time.Second)
+	if token, err = source.Token(config); err != nil || token != "at-2" {
+		t.Fatalf("刷新后的令牌为 %s (%v)，期望 at-2", token, err)
+	}
+	if n := endpoint.requestCount(); n != 2 {
+		t.Fatalf("令牌端点收到 %d 次请求，期望2次", n)
+	}
+}
+
+func TestTokenRefreshTokenRotation(t *testing.T) {
+	endpoint := &tokenEndpoint{t: t, responses: []tokenReply{
+		{status: http.StatusOK, body: `{"access_token":"at-1","expires_in":60,"refresh_token":"rt-2"}`},
+		{status: http.StatusOK, body: `{"access_token":"at-2","expires_in":3600}`},
+		{status: http.StatusOK, body: `{"access_token":"at-3","expires_in":3600}`},
+	}}
+	config := Config{ClientID: "client", RefreshToken: "rt-1", TokenURL: endpoint.start()}
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	source := newTestSource(clock)
+
+	if _, err := source.Token(config); err != nil {
+		t.Fatalf("获取令牌失败: %v", err)
+	}
+	if _, ok := endpoint.request(0)["client_secret"]; ok {
+		t.Error("未配置client_secret时不应发送该参数")
+	}
+
+	// 有效期短于提前刷新时间，下次获取时使用轮换后的refresh token
+	if token, err := source.Token(config); err != nil || token != "at-2" {
+		t.Fatalf("令牌为 %s (%v)，期望 at-2", token, err)
+	}
+	if got := endpoint.request(1)["refresh_token"]; got != "rt-2" {
+		t.Fatalf("刷新使用的refresh_token为 %s，期望轮换后的 rt-2", got)
+	}
+
+	// 令牌端点未返回新的refresh token时继续使用轮换后的值
+	source.Invalidate(config)
+	if token, err := source.Token(config); err != nil || token != "at-3" {
+		t.Fatalf("令牌为 %s (%v)，期望 at-3", token, err)
+	}
+	if got := endpoint.request(2)["refresh_token"]; got != "rt-2" {
+		t.Fatalf("刷新使用的refresh_token为 %s，期望 rt-2", got)
+	}
+}
+
+// memoryTokenStore 记录令牌源保存的refresh token
+type memoryTokenStore struct {
+	saved []string
+}
+
+func (s *memoryTokenStore) SaveRefreshToken(config Config, refreshToken string) error {
+	s.saved = append(s.saved, config.RefreshToken+"->"+refreshToken)
+	return nil
+}
+
+func TestTokenRefreshTokenPersisted(t *testing.T) {
+	endpoint := &tokenEndpoint{t: t, responses: []tokenReply{
+		{status: http.StatusOK, body: `{"access_token":"at-1","expires_in":3600,"refresh_token":"rt-1"}`},
+		{status: http.StatusOK, body: `{"access_token":"at-2","expires_in":3600,"refresh_token":"rt-2"}`},
+	}}
+	config := Config{ClientID: "client", RefreshToken: "rt-1", TokenURL: endpoint.start()}
+	source := newTestSource(&fakeClock{now: time.Now()})
+	store := &memoryTokenStore{}
+	source.SetRefreshTokenStore(store)
+
+	// 返回的refresh token与当前值相同时无需保存
+	if _, err := source.Token(config); err != nil {
+		t.Fatalf("获取令牌失败: %v", err)
+	}
+	if len(store.saved) != 0 {
+		t.Fatalf("refresh token未变化时不应保存，实际保存 %v", store.saved)
+	}
+
+	// 轮换后按旧值定位记录，保存新值
+	source.Invalidate(config)
+	if _, err := source.Token(config); err != nil {
+		t.Fatalf("获取令牌失败: %v", err)
+	}
+	if len(store.saved) != 1 || store.saved[0] != "rt-1->rt-2" {
+		t.Fatalf("保存的refresh token为 %v，期望 [rt-1->rt-2]", store.saved)
+	}
+}
+
+func TestTokenInvalidate(t *testing.T) {
+	endpoint := &tokenEndpoint{t: t, responses: []tokenReply{
+		{status: http.StatusOK, body: `{"access_token":"at-1","expires_in":3600}`},
+		{status: http.StatusOK, body: `{"access_token":"at-2","expires_in":3600}`},
+	}}
+	config := Config{ClientID: "client", RefreshToken: "rt-1", TokenURL: endpoint.start()}
+	source := newTestSource(&fakeClock{now: time.Now()})
+
+	if token, err := source.Token(config); err != nil || token != "at-1" {
+		t.Fatalf("令牌为 %s (%v)，期望 at-1", token, err)
+	}
+	source.Invalidate(config)
+	if token, err := source.Token(config); err != nil || token != "at-2" {
+		t.Fatalf("失效后的令牌为 %s (%v)，期望 at-2", token, err)
+	}
+
+	// 未缓存的凭据失效不影响其他凭据
+	source.Invalidate(Config{ClientID: "other", RefreshToken: "rt", TokenURL: config.TokenURL})
+	if token, err := source.Token(config); err != nil || token != "at-2" {
+		t.Fatalf("令牌为 %s (%v)，期望缓存的 at-2", token, err)
+	}
+}
+
+func TestTokenDefaultLifetime(t *testing.T) {
+	endpoint := &tokenEndpoint{t: t, responses: []tokenReply{
+		{status: http.StatusOK, body: `{"access_token":"at-1"}`},
+		{status: http.StatusOK, body: `{"access_token":"at-2"}`},
+	}}
+	config := Config{ClientID: "client", RefreshToken: "rt-1", TokenURL: endpoint.start()}
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	source := newTestSource(clock)
+
+	source.Token(config)
+	clock.now = clock.now.Add(defaultTokenLifetime - tokenExpirySkew - time.Second)
+	if token, _ := source.Token(config); token != "at-1" {
+		t.Fatalf("默认有效期内的令牌为 %s，期望 at-1", token)
+	}
+	clock.now = clock.now.Add(2 * time.Second)
+	if token, _ := source.Token(config); token != "at-2" {
+		t.Fatalf("超过默认有效期后的令牌为 %s，期望 at-2", token)
+	}
+}
+
+func TestTokenCacheKeyedByCredentials(t *testing.T) {
+	endpoint := &tokenEndpoint{t: t, responses: []tokenReply{
+		{status: http.StatusOK, body: `{"access_token":"at-a","expires_in":3600}`},
+		{status: http.StatusOK, body: `{"access_token":"at-b","expires_in":3600}`},
+	}}
+	tokenURL := endpoint.start()
+	source := newTestSource(&fakeClock{now: time.Now()})
+
+	a := Config{ClientID: "client", RefreshToken: "rt-a", TokenURL: tokenURL}
+	b := Config{ClientID: "client", RefreshToken: "rt-b", TokenURL: tokenURL}
+	if token, _ := source.Token(a); token != "at-a" {
+		t.Fatalf("凭据a的令牌为 %s", token)
+	}
+	if token, _ := source.Token(b); token != "at-b" {
+		t.Fatalf("凭据b的令牌为 %s", token)
+	}
+	if token, _ := source.Token(a); token != "at-a" {
+		t.Fatalf("凭据a缓存的令牌为 %s", token)
+	}
+}
+
+func TestTokenErrorResponses(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     tokenReply
+		wantErr   string
+		transient bool
+	}{
+		{
+			name:    "OAuth错误及描述",
+			reply:   tokenReply{status: http.StatusBadRequest, body: `{"error":"invalid_grant","error_description":"refresh token expired"}`},
+			wantErr: "invalid_grant: refresh token expired",
+		},
+		{
+			name:    "OAuth错误无描述",
+			reply:   tokenReply{status: http.StatusUnauthorized, body: `{"error":"invalid_client"}`},
+			wantErr: "刷新OAuth2令牌失败: invalid_client",
+		},
+		{
+			name:      "非JSON响应",
+			reply:     tokenReply{status: http.StatusBadGateway, body: `<html>bad gateway</html>`},
+			wantErr:   "解析令牌响应失败 (HTTP 502)",
+			transient: true,
+		},
+		{
+			name:      "非200状态码",
+			reply:     tokenReply{status: http.StatusServiceUnavailable, body: `{}`},
+			wantErr:   "HTTP 503",
+			transient: true,
+		},
+		{
+			name:      "请求过于频繁",
+			reply:     tokenReply{status: http.StatusTooManyRequests, body: `{}`},
+			wantErr:   "HTTP 429",
+			transient: true,
+		},
+		{
+			name:      "令牌端点暂时不可用",
+			reply:     tokenReply{status: http.StatusBadRequest, body: `{"error":"temporarily_unavailable"}`},
+			wantErr:   "temporarily_unavailable",
+			transient: true,
+		},
+		{
+			name:      "缺少access_token",
+			reply:     tokenReply{status: http.StatusOK, body: `{"token_type":"Bearer"}`},
+			wantErr:   "缺少access_token",
+			transient: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := &tokenEndpoint{t: t, responses: []tokenReply{
+				tt.reply,
+				{status: http.StatusOK, body: `{"access_token":"at-ok","expires_in":3600}`},
+			}}
+			config := Config{ClientID: "client", RefreshToken: "rt-1", TokenURL: endpoint.start()}
+			source := newTestSource(&fakeClock{now: time.Now()})
+
+			_, err := source.Token(config)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("期望错误包含 %q，实际为 %v", tt.wantErr, err)
+			}
+			if got := IsTransient(err); got != tt.transient {
+				t.Fatalf("IsTransient(%v) = %v，期望 %v", err, got, tt.transient)
+			}
+
+			// 失败不会写入缓存，下次调用重新请求
+			if token, err := source.Token(config); err != nil || token != "at-ok" {
+				t.Fatalf("重试后的令牌为 %s (%v)，期望 at-ok", token, err)
+			}
+		})
+	}
+}
+
+func TestTokenEndpointUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	tokenURL := server.URL + "/token"
+	server.Close()
+
+	source := newTestSource(&fakeClock{now: time.Now()})
+	_, err := source.Token(Config{ClientID: "client", RefreshToken: "rt-1", TokenURL: tokenURL})
+	if err == nil || !strings.Contains(err.Error(), "请求OAuth2令牌端点失败") {
+		t.Fatalf("期望请求令牌端点失败，实际为 %v", err)
+	}
+	if !IsTransient(err) {
+		t.Fatalf("网络错误应视为临时错误: %v", err)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{ClientID: "client", RefreshToken: "rt", TokenURL: "https://login.example.com/token"}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("有效配置校验失败: %v", err)
+	}
+
+	tests := map[string]Config{
+		"缺少client_id":     {RefreshToken: "rt", TokenURL: valid.TokenURL},
+		"缺少refresh_token": {ClientID: "client", TokenURL: valid.TokenURL},
+		"缺少令牌端点":          {ClientID: "client", RefreshToken: "rt"},
+		"令牌端点协议错误":        {ClientID: "client", RefreshToken: "rt", TokenURL: "ftp://login.example.com/token"},
+		"令牌端点缺少主机":        {ClientID: "client", RefreshToken: "rt", TokenURL: "https:///token"},
+	}
+	for name, config := range tests {
+		if err := config.Validate(); err == nil {
+			t.Errorf("%s: 期望校验失败", name)
+		}
+		// 无效配置不会请求令牌端点
+		if _, err := NewTokenSource(nil).Token(config); err == nil {
+			t.Errorf("%s: 期望获取令牌失败", name)
+		}
+	}
+}
+
+func TestSASLResponses(t *testing.T) {
+	if got := string(XOAuth2Response("user@example.com", "at")); got != "user=user@example.com\x01auth=Bearer at\x01\x01" {
+		t.Errorf("XOAUTH2响应错误: %q", got)
+	}
+	if got := string(OAuthBearerResponse("user@example.com", "at")); got != "n,a=user@example.com,\x01auth=Bearer at\x01\x01" {
+		t.Errorf("OAUTHBEARER响应错误: %q", got)
+	}
+}
@@ -0,0 +1,278 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// proxyDialer 通过SOCKS5或HTTP CONNECT代理建立连接
+type proxyDialer struct {
+	proxyAddr string
+	username  string
+	password  string
+	forward   *net.Dialer
+	timeout   time.Duration
+	handshake func(conn net.Conn, addr string) (net.Conn, error)
+}
+
+// Dial 通过代理连接目标地址
+func (d *proxyDialer) Dial(network, addr string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, addr)
+}
+
+// DialContext 通过代理连接目标地址，握手阶段受超时和ctx控制
+func (d *proxyDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if network != "tcp" && network != "tcp4" && network != "tcp6" {
+		return nil, fmt.Errorf("代理不支持的网络类型: %s", network)
+	}
+
+	conn, err := d.forward.DialContext(ctx, "tcp", d.proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("连接代理服务器 %s 失败: %v", d.proxyAddr, err)
+	}
+
+	deadline := time.Now().Add(d.timeout)
+	ctxDeadline, hasCtxDeadline := ctx.Deadline()
+	if hasCtxDeadline && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	conn.SetDeadline(deadline)
+
+	// ctx取消时关闭连接以中断握手
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	tunnel, err := d.handshake(conn, addr)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// 连接的截止时间取自ctx时，读写超时可能先于ctx报告过期
+		if hasCtxDeadline && !time.Now().Before(ctxDeadline) {
+			return nil, context.DeadlineExceeded
+		}
+		return nil, err
+	}
+
+	conn.SetDeadline(time.Time{})
+	return tunnel, nil
+}
+
+// SOCKS5协议常量（RFC 1928/1929）
+const (
+	socks5Version        = 0x05
+	socks5AuthNone       = 0x00
+	socks5AuthPassword   = 0x02
+	socks5AuthNoAccept   = 0xff
+	socks5CmdConnect     = 0x01
+	socks5AddrIPv4       = 0x01
+	socks5AddrDomain     = 0x03
+	socks5AddrIPv6       = 0x04
+	socks5PasswordStatus = 0x01
+)
+
+// socks5Errors SOCKS5 CONNECT应答错误码说明
+var socks5Errors = map[byte]string{
+	0x01: "代理服务器内部错误",
+	0x02: "代理规则不允许该连接",
+	0x03: "网络不可达",
+	0x04: "主机不可达",
+	0x05: "目标拒绝连接",
+	0x06: "TTL过期",
+	0x07: "不支持的命令",
+	0x08: "不支持的地址类型",
+}
+
+// socks5Handshake 完成SOCKS5认证并请求连接目标地址，域名交由代理服务器解析
+func (d *proxyDialer) socks5Handshake(conn net.Conn, addr string) (net.Conn, error) {
+	if err := d.socks5Connect(conn, addr); err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// socks5Connect 发送SOCKS5认证和CONNECT请求
+func (d *proxyDialer) socks5Connect(conn net.Conn, addr string) error {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("目标地址格式错误: %v", err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return fmt.Errorf("目标端口无效: %s", portStr)
+	}
+
+	// 协商认证方式
+	methods := []byte{socks5AuthNone}
+	if d.username != "" {
+		methods = []byte{socks5AuthNone, socks5AuthPassword}
+	}
+	greeting := append([]byte{socks5Version, byte(len(methods))}, methods...)
+	if _, err := conn.Write(greeting); err != nil {
+		return fmt.Errorf("SOCKS5握手失败: %v", err)
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("SOCKS5握手失败: %v", err)
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("代理服务器不是SOCKS5协议（版本 %d）", reply[0])
+	}
+
+	switch reply[1] {
+	case socks5AuthNone:
+	case socks5AuthPassword:
+		if err := d.socks5Authenticate(conn); err != nil {
+			return err
+		}
+	case socks5AuthNoAccept:
+		return errors.New("SOCKS5代理拒绝了所有认证方式，请检查代理用户名和密码")
+	default:
+		return fmt.Errorf("SOCKS5代理要求不支持的认证方式: %d", reply[1])
+	}
+
+	// 发送CONNECT请求
+	req := []byte{socks5Version, socks5CmdConnect, 0x00}
+	if ip := net.ParseIP(host); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			req = append(req, socks5AddrIPv4)
+			req = append(req, ip4...)
+		} else {
+			req = append(req, socks5AddrIPv6)
+			req = append(req, ip.To16()...)
+		}
+	} else {
+		if len(host) > 255 {
+			return fmt.Errorf("目标主机名过长: %s", host)
+		}
+		req = append(req, socks5AddrDomain, byte(len(host)))
+		req = append(req, host...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("发送SOCKS5连接请求失败: %v", err)
+	}
+
+	// 读取应答：VER REP RSV ATYP BND.ADDR BND.PORT
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("读取SOCKS5连接应答失败: %v", err)
+	}
+	if header[1] != 0x00 {
+		if msg, ok := socks5Errors[header[1]]; ok {
+			return fmt.Errorf("SOCKS5代理连接 %s 失败: %s", addr, msg)
+		}
+		return fmt.Errorf("SOCKS5代理连接 %s 失败: 错误码 %d", addr, header[1])
+	}
+
+	var skip int
+	switch header[3] {
+	case socks5AddrIPv4:
+		skip = net.IPv4len + 2
+	case socks5AddrIPv6:
+		skip = net.IPv6len + 2
+	case socks5AddrDomain:
+		length := make([]byte, 1)
+		if _, err := io.ReadFull(conn, length); err != nil {
+			return fmt.Errorf("读取SOCKS5连接应答失败: %v", err)
+		}
+		skip = int(length[0]) + 2
+	default:
+		return fmt.Errorf("SOCKS5应答包含未知的地址类型: %d", header[3])
+	}
+	if _, err := io.CopyN(io.Discard, conn, int64(skip)); err != nil {
+		return fmt.Errorf("读取SOCKS5连接应答失败: %v", err)
+	}
+
+	return nil
+}
+
+// socks5Authenticate 使用用户名密码认证（RFC 1929）
+func (d *proxyDialer) socks5Authenticate(conn net.Conn) error {
+	if d.username == "" || len(d.username) > 255 || len(d.password) > 255 {
+		return errors.New("SOCKS5代理要求用户名密码认证，但未配置有效的用户名和密码")
+	}
+
+	req := []byte{socks5PasswordStatus, byte(len(d.username))}
+	req = append(req, d.username...)
+	req = append(req, byte(len(d.password)))
+	req = append(req, d.password...)
+	if _, err := conn.Write(req); err != nil {
+		return fmt.Errorf("SOCKS5认证失败: %v", err)
+	}
+
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return fmt.Errorf("SOCKS5认证失败: %v", err)
+	}
+	if reply[1] != 0x00 {
+		return errors.New("SOCKS5认证失败，请检查代理用户名和密码")
+	}
+	return nil
+}
+
+// connectHandshake 通过HTTP CONNECT请求建立隧道
+func (d *proxyDialer) connectHandshake(conn net.Conn, addr string) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.username != "" {
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.username + ":" + d.password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("发送CONNECT请求失败: %v", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		return nil, fmt.Errorf("读取CONNECT应答失败: %v", err)
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		return nil, errors.New("HTTP代理认证失败，请检查代理用户名和密码")
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("HTTP代理连接 %s 失败: %s", addr, resp.Status)
+	}
+
+	// IMAP/SMTP服务器会立即发送问候语，可能已随应答一起读入缓冲区
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读取CONNECT应答后剩余的缓冲数据，再读取底层连接
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// Read 从缓冲区读取数据
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
@@ -0,0 +1,176 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Direct 显式指定直连，用于在配置了全局代理时让单个邮箱或渠道不走代理
+const Direct = "direct"
+
+// defaultDialTimeout 未指定超时时连接代理服务器的超时时间
+const defaultDialTimeout = 30 * time.Second
+
+var (
+	mutex  sync.RWMutex
+	global string
+)
+
+// Dialer 建立TCP连接的拨号器，直连和代理连接使用同一接口
+type Dialer interface {
+	Dial(network, addr string) (net.Conn, error)
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// Parse 解析代理地址，支持 socks5://[user:pass@]host:port 和 http://[user:pass@]host:port（HTTP CONNECT）
+func Parse(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, fmt.Errorf("代理地址格式错误: %v", err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("不支持的代理协议 %q，仅支持socks5和http", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("代理地址缺少主机或端口: %s", Redact(raw))
+	}
+	return u, nil
+}
+
+// Validate 校验代理配置，空字符串表示使用全局代理，direct表示直连
+func Validate(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || strings.EqualFold(raw, Direct) {
+		return nil
+	}
+	_, err := Parse(raw)
+	return err
+}
+
+// SetGlobal 设置全局代理，未单独配置代理的邮箱和渠道使用该代理
+func SetGlobal(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if strings.EqualFold(raw, Direct) {
+		raw = ""
+	}
+	if raw != "" {
+		if _, err := Parse(raw); err != nil {
+			return err
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	global = raw
+	return nil
+}
+
+// Global 获取全局代理地址，未配置时返回空字符串
+func Global() string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return global
+}
+
+// Resolve 获取实际使用的代理地址：空字符串使用全局代理，direct表示直连（返回空字符串）
+func Resolve(raw string) string {
+	raw = strings.TrimSpace(raw)
+	switch {
+	case raw == "":
+		return Global()
+	case strings.EqualFold(raw, Direct):
+		return ""
+	default:
+		return raw
+	}
+}
+
+// Redact 隐藏代理地址中的密码，用于日志和错误信息
+func Redact(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.User == nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+// NewDialer 根据代理配置创建拨号器，未配置代理时直连
+// raw按Resolve规则处理：空字符串使用全局代理，direct表示直连
+func NewDialer(raw string, timeout time.Duration) (Dialer, error) {
+	if timeout <= 0 {
+		timeout = defaultDialTimeout
+	}
+	forward := &net.Dialer{Timeout: timeout}
+
+	raw = Resolve(raw)
+	if raw == "" {
+		return forward, nil
+	}
+
+	u, err := Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &proxyDialer{
+		proxyAddr: u.Host,
+		forward:   forward,
+		timeout:   timeout,
+	}
+	if u.User != nil {
+		d.username = u.User.Username()
+		d.password, _ = u.User.Password()
+	}
+	if strings.EqualFold(u.Scheme, "http") {
+		d.handshake = d.connectHandshake
+	} else {
+		d.handshake = d.socks5Handshake
+	}
+	return d, nil
+}
+
+// NewHTTPTransport 创建通过代理连接的HTTP Transport，代理配置规则同NewDialer
+func NewHTTPTransport(raw string) (*http.Transport, error) {
+	dialer, err := NewDialer(raw, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// 代理由拨号器处理，不再读取HTTP_PROXY等环境变量
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport, nil
+}
+
+// DialContext 按当前的全局代理建立连接，用于进程启动时创建、无法单独配置代理的HTTP客户端
+func DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer, err := NewDialer("", 0)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// Test 通过代理连接目标地址，用于验证代理配置是否可用
+func Test(raw, addr string, timeout time.Duration) error {
+	dialer, err := NewDialer(raw, timeout)
+	if err != nil {
+		return err
+	}
+
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
@@ -0,0 +1,265 @@
+// Package smtpd 内置的SMTP/LMTP接收服务，用于外部系统直接推送告警邮件
+package smtpd
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 默认限制
+const (
+	DefaultMaxMessageBytes = 10 << 20 // 单封邮件大小上限
+	DefaultMaxRecipients   = 50       // 单封邮件收件人数量上限
+	DefaultReadTimeout     = 5 * time.Minute
+)
+
+// Envelope 邮件信封信息
+type Envelope struct {
+	RemoteAddr string   // 客户端地址
+	Helo       string   // HELO/EHLO/LHLO声明的主机名
+	AuthUser   string   // 认证用户名，未认证时为空
+	From       string   // MAIL FROM地址
+	Recipients []string // 已接受的RCPT TO地址
+}
+
+// Backend 邮件投递后端
+type Backend interface {
+	// LookupRecipient 查找收件地址对应的邮箱ID，地址不接收邮件时返回错误
+	LookupRecipient(address string) (uint, error)
+	// Deliver 将邮件投递到指定邮箱
+	Deliver(mailboxID uint, envelope *Envelope, data []byte) error
+}
+
+// Config 接收服务配置
+type Config struct {
+	Hostname          string        // 问候语和Received头中使用的主机名
+	LMTP              bool          // 使用LMTP协议（LHLO、按收件人返回投递结果）
+	MaxMessageBytes   int64         // 单封邮件大小上限，0表示使用默认值
+	MaxRecipients     int           // 单封邮件收件人数量上限，0表示使用默认值
+	Username          string        // 非空时要求客户端认证（AUTH PLAIN/LOGIN）
+	Password          string        // 认证密码
+	AllowedNetworks   []*net.IPNet  // 允许连接的客户端网段，为空表示不限制
+	TLSConfig         *tls.Config   // 非空时支持STARTTLS
+	AllowInsecureAuth bool          // 是否允许在未加密连接上认证
+	ReadTimeout       time.Duration // 读取客户端命令的超时时间，0表示使用默认值
+}
+
+// protocolName 协议名称
+func (c Config) protocolName() string {
+	if c.LMTP {
+		return "LMTP"
+	}
+	return "SMTP"
+}
+
+// helloVerb 客户端应使用的问候命令
+func (c Config) helloVerb() string {
+	if c.LMTP {
+		return "LHLO"
+	}
+	return "EHLO"
+}
+
+// authEnabled 是否启用认证
+func (c Config) authEnabled() bool {
+	return c.Username != ""
+}
+
+// ParseNetworks 解析逗号分隔的网段列表，单个IP按/32（IPv6为/128）处理
+func ParseNetworks(spec string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("无效的IP地址: %s", item)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("无效的网段: %s", item)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Server SMTP/LMTP接收服务
+type Server struct {
+	config  Config
+	backend Backend
+
+	mutex     sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewServer 创建接收服务
+func NewServer(config Config, backend Backend) *Server {
+	if config.Hostname == "" {
+		config.Hostname = "localhost"
+	}
+	if config.MaxMessageBytes <= 0 {
+		config.MaxMessageBytes = DefaultMaxMessageBytes
+	}
+	if config.MaxRecipients <= 0 {
+		config.MaxRecipients = DefaultMaxRecipients
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = DefaultReadTimeout
+	}
+
+	return &Server{
+		config:  config,
+		backend: backend,
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe 监听地址并处理连接，地址包含 "/" 时监听Unix socket（常用于LMTP）
+func (s *Server) ListenAndServe(addr string) error {
+	network := "tcp"
+	if strings.Contains(addr, "/") {
+		network = "unix"
+	}
+
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return fmt.Errorf("监听 %s 失败: %v", addr, err)
+	}
+	return s.Serve(listener)
+}
+
+// Serve 在监听器上接受连接，服务关闭时返回nil
+func (s *Server) Serve(listener net.Listener) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		listener.Close()
+		return errors.New("服务已关闭")
+	}
+	s.listeners = append(s.listeners, listener)
+	s.mutex.Unlock()
+
+	log.Printf("%s接收服务: 监听 %s", s.config.protocolName(), listener.Addr())
+
+	var tempDelay time.Duration
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return nil
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// 临时错误时退避重试
+				if tempDelay == 0 {
+					tempDelay = 5 * time.Millisecond
+				} else if tempDelay *= 2; tempDelay > time.Second {
+					tempDelay = time.Second
+				}
+				time.Sleep(tempDelay)
+				continue
+			}
+			return err
+		}
+		tempDelay = 0
+
+		if !s.allowed(conn.RemoteAddr()) {
+			log.Printf("%s接收服务: 拒绝来自 %s 的连接（不在允许的网段内）", s.config.protocolName(), conn.RemoteAddr())
+			fmt.Fprintf(conn, "554 5.7.1 %s access denied\r\n", s.config.Hostname)
+			conn.Close()
+			continue
+		}
+
+		if !s.trackConn(conn, true) {
+			conn.Close()
+			return nil
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.trackConn(conn, false)
+			newSession(s, conn).serve()
+		}()
+	}
+}
+
+// Close 关闭服务，断开所有连接并等待处理中的会话结束
+func (s *Server) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	for _, listener := range s.listeners {
+		listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mutex.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+// isClosed 判断服务是否已关闭
+func (s *Server) isClosed() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.closed
+}
+
+// trackConn 记录或移除活动连接，服务已关闭时返回false
+func (s *Server) trackConn(conn net.Conn, add bool) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !add {
+		delete(s.conns, conn)
+		return true
+	}
+	if s.closed {
+		return false
+	}
+	s.conns[conn] = struct{}{}
+	return true
+}
+
+// allowed 判断客户端地址是否在允许的网段内，Unix socket连接不受限制
+func (s *Server) allowed(addr net.Addr) bool {
+	if len(s.config.AllowedNetworks) == 0 {
+		return true
+	}
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return true
+	}
+	for _, network := range s.config.AllowedNetworks {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
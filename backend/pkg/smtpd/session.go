@@ -0,0 +1,492 @@
+package smtpd
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCommandErrors 连续错误命令数量上限，超过后断开连接
+const maxCommandErrors = 10
+
+// recipient 已接受的收件人
+type recipient struct {
+	address   string
+	mailboxID uint
+}
+
+// session 单个客户端会话
+type session struct {
+	server   *Server
+	conn     net.Conn
+	text     *textproto.Conn
+	tls      bool
+	helo     string
+	authUser string
+	from     string
+	hasFrom  bool
+	rcpts    []recipient
+	errors   int
+}
+
+// newSession 创建会话
+func newSession(server *Server, conn net.Conn) *session {
+	_, isTLS := conn.(*tls.Conn)
+	return &session{
+		server: server,
+		conn:   conn,
+		text:   textproto.NewConn(conn),
+		tls:    isTLS,
+	}
+}
+
+// serve 处理会话直到客户端退出或连接断开
+func (s *session) serve() {
+	// STARTTLS后s.conn会被替换为TLS连接，退出时移除当前连接的记录
+	defer func() {
+		s.server.trackConn(s.conn, false)
+		s.text.Close()
+	}()
+
+	cfg := s.server.config
+	s.reply(220, "%s emailAlert %s service ready", cfg.Hostname, cfg.protocolName())
+
+	for {
+		s.conn.SetReadDeadline(time.Now().Add(cfg.ReadTimeout))
+		line, err := s.text.ReadLine()
+		if err != nil {
+			if err != io.EOF && !isClosedError(err) {
+				log.Printf("%s接收服务: 读取 %s 的命令失败: %v", cfg.protocolName(), s.conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		verb, args, _ := strings.Cut(line, " ")
+		if !s.handle(strings.ToUpper(verb), strings.TrimSpace(args)) {
+			return
+		}
+		if s.errors >= maxCommandErrors {
+			s.reply(421, "4.7.0 Too many errors, closing connection")
+			return
+		}
+	}
+}
+
+// handle 处理单条命令，返回false表示结束会话
+func (s *session) handle(verb, args string) bool {
+	cfg := s.server.config
+
+	switch verb {
+	case "HELO", "EHLO", "LHLO":
+		s.hello(verb, args)
+	case "STARTTLS":
+		return s.startTLS()
+	case "AUTH":
+		s.auth(args)
+	case "MAIL":
+		s.mail(args)
+	case "RCPT":
+		s.rcpt(args)
+	case "DATA":
+		s.data()
+	case "RSET":
+		s.reset()
+		s.reply(250, "2.0.0 OK")
+	case "NOOP":
+		s.reply(250, "2.0.0 OK")
+	case "VRFY":
+		s.reply(252, "2.5.0 Cannot VRFY user, but will accept message")
+	case "QUIT":
+		s.reply(221, "2.0.0 %s closing connection", cfg.Hostname)
+		return false
+	default:
+		s.fail(502, "5.5.2 Command not recognized")
+	}
+	return true
+}
+
+// hello 处理HELO/EHLO/LHLO
+func (s *session) hello(verb, args string) {
+	cfg := s.server.config
+	if cfg.LMTP != (verb == "LHLO") {
+		s.fail(500, "5.5.1 This is an %s server, use %s", cfg.protocolName(), cfg.helloVerb())
+		return
+	}
+	if args == "" {
+		s.fail(501, "5.5.4 Domain/address argument required")
+		return
+	}
+
+	s.reset()
+	s.helo = args
+
+	if verb == "HELO" {
+		s.reply(250, "%s", cfg.Hostname)
+		return
+	}
+
+	lines := []string{
+		cfg.Hostname,
+		"PIPELINING",
+		"8BITMIME",
+		"ENHANCEDSTATUSCODES",
+		"SIZE " + strconv.FormatInt(cfg.MaxMessageBytes, 10),
+	}
+	if cfg.TLSConfig != nil && !s.tls {
+		lines = append(lines, "STARTTLS")
+	}
+	if cfg.authEnabled() && (s.tls || cfg.AllowInsecureAuth) {
+		lines = append(lines, "AUTH PLAIN LOGIN")
+	}
+	s.replyLines(250, lines)
+}
+
+// startTLS 处理STARTTLS，返回false表示握手失败需结束会话
+func (s *session) startTLS() bool {
+	cfg := s.server.config
+	if cfg.TLSConfig == nil || s.tls {
+		s.fail(502, "5.5.1 STARTTLS not available")
+		return true
+	}
+
+	s.reply(220, "2.0.0 Ready to start TLS")
+
+	tlsConn := tls.Server(s.conn, cfg.TLSConfig)
+	s.conn.SetDeadline(time.Now().Add(cfg.ReadTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		log.Printf("%s接收服务: 与 %s 的TLS握手失败: %v", cfg.protocolName(), s.conn.RemoteAddr(), err)
+		return false
+	}
+	s.conn.SetDeadline(time.Time{})
+
+	// 握手后重新开始会话，客户端需再次发送EHLO
+	s.server.trackConn(s.conn, false)
+	s.conn = tlsConn
+	s.server.trackConn(s.conn, true)
+	s.text = textproto.NewConn(tlsConn)
+	s.tls = true
+	s.helo = ""
+	s.authUser = ""
+	s.reset()
+	return true
+}
+
+// auth 处理AUTH PLAIN/LOGIN
+func (s *session) auth(args string) {
+	cfg := s.server.config
+	switch {
+	case !cfg.authEnabled():
+		s.fail(502, "5.5.1 AUTH not supported")
+		return
+	case !s.tls && !cfg.AllowInsecureAuth:
+		s.fail(538, "5.7.11 Encryption required for requested authentication mechanism")
+		return
+	case s.helo == "":
+		s.fail(503, "5.5.1 Send EHLO first")
+		return
+	case s.authUser != "":
+		s.fail(503, "5.5.1 Already authenticated")
+		return
+	case s.hasFrom:
+		s.fail(503, "5.5.1 AUTH not permitted during a mail transaction")
+		return
+	}
+
+	mechanism, initial, _ := strings.Cut(args, " ")
+	var username, password string
+	var ok bool
+
+	switch strings.ToUpper(mechanism) {
+	case "PLAIN":
+		response := initial
+		if response == "" {
+			if response, ok = s.challenge(""); !ok {
+				return
+			}
+		}
+		decoded, err := base64.StdEncoding.DecodeString(response)
+		if err != nil {
+			s.fail(501, "5.5.2 Invalid base64 data")
+			return
+		}
+		// authzid \0 authcid \0 passwd
+		parts := strings.Split(string(decoded), "\x00")
+		if len(parts) != 3 {
+			s.fail(501, "5.5.2 Invalid PLAIN response")
+			return
+		}
+		username, password = parts[1], parts[2]
+	case "LOGIN":
+		if username, ok = s.challengeDecoded("Username:"); !ok {
+			return
+		}
+		if password, ok = s.challengeDecoded("Password:"); !ok {
+			return
+		}
+	default:
+		s.fail(504, "5.5.4 Unrecognized authentication type")
+		return
+	}
+
+	userOK := subtle.ConstantTimeCompare([]byte(username), []byte(cfg.Username)) == 1
+	passOK := subtle.ConstantTimeCompare([]byte(password), []byte(cfg.Password)) == 1
+	if !userOK || !passOK {
+		log.Printf("%s接收服务: %s 认证失败（用户名: %s）", cfg.protocolName(), s.conn.RemoteAddr(), username)
+		s.fail(535, "5.7.8 Authentication credentials invalid")
+		return
+	}
+
+	s.authUser = username
+	s.reply(235, "2.7.0 Authentication successful")
+}
+
+// challenge 发送334质询并读取客户端响应，客户端以 "*" 取消时返回false
+func (s *session) challenge(prompt string) (string, bool) {
+	s.reply(334, "%s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+	line, err := s.text.ReadLine()
+	if err != nil {
+		return "", false
+	}
+	if strings.TrimSpace(line) == "*" {
+		s.fail(501, "5.0.0 Authentication cancelled")
+		return "", false
+	}
+	return strings.TrimSpace(line), true
+}
+
+// challengeDecoded 发送质询并解码base64响应
+func (s *session) challengeDecoded(prompt string) (string, bool) {
+	response, ok := s.challenge(prompt)
+	if !ok {
+		return "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(response)
+	if err != nil {
+		s.fail(501, "5.5.2 Invalid base64 data")
+		return "", false
+	}
+	return string(decoded), true
+}
+
+// mail 处理MAIL FROM
+func (s *session) mail(args string) {
+	cfg := s.server.config
+	switch {
+	case s.helo == "":
+		s.fail(503, "5.5.1 Send %s first", cfg.helloVerb())
+		return
+	case s.hasFrom:
+		s.fail(503, "5.5.1 Sender already specified")
+		return
+	case cfg.authEnabled() && s.authUser == "":
+		s.fail(530, "5.7.0 Authentication required")
+		return
+	}
+
+	address, params, ok := parsePath(args, "FROM:")
+	if !ok {
+		s.fail(501, "5.5.4 Syntax: MAIL FROM:<address>")
+		return
+	}
+
+	for _, param := range params {
+		key, value, _ := strings.Cut(param, "=")
+		if strings.EqualFold(key, "SIZE") {
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				s.fail(501, "5.5.4 Invalid SIZE parameter")
+				return
+			}
+			if size > cfg.MaxMessageBytes {
+				s.fail(552, "5.3.4 Message size exceeds fixed limit of %d bytes", cfg.MaxMessageBytes)
+				return
+			}
+		}
+	}
+
+	s.from = address
+	s.hasFrom = true
+	s.reply(250, "2.1.0 Sender OK")
+}
+
+// rcpt 处理RCPT TO
+func (s *session) rcpt(args string) {
+	cfg := s.server.config
+	if !s.hasFrom {
+		s.fail(503, "5.5.1 Need MAIL before RCPT")
+		return
+	}
+	if len(s.rcpts) >= cfg.MaxRecipients {
+		s.fail(452, "4.5.3 Too many recipients")
+		return
+	}
+
+	address, _, ok := parsePath(args, "TO:")
+	if !ok || address == "" {
+		s.fail(501, "5.5.4 Syntax: RCPT TO:<address>")
+		return
+	}
+
+	mailboxID, err := s.server.backend.LookupRecipient(address)
+	if err != nil {
+		s.fail(550, "5.1.1 <%s>: Recipient address rejected: %v", address, err)
+		return
+	}
+
+	s.rcpts = append(s.rcpts, recipient{address: address, mailboxID: mailboxID})
+	s.reply(250, "2.1.5 Recipient OK")
+}
+
+// data 处理DATA，读取邮件内容并投递
+func (s *session) data() {
+	cfg := s.server.config
+	if !s.hasFrom || len(s.rcpts) == 0 {
+		s.fail(503, "5.5.1 Need RCPT before DATA")
+		return
+	}
+
+	s.reply(354, "Start mail input; end with <CRLF>.<CRLF>")
+
+	// 多读一个字节以判断是否超过大小上限
+	reader := s.text.DotReader()
+	var body bytes.Buffer
+	n, err := io.Copy(&body, io.LimitReader(reader, cfg.MaxMessageBytes+1))
+	if err == nil && n > cfg.MaxMessageBytes {
+		io.Copy(io.Discard, reader)
+		s.reset()
+		s.fail(552, "5.3.4 Message size exceeds fixed limit of %d bytes", cfg.MaxMessageBytes)
+		return
+	}
+	if err != nil {
+		log.Printf("%s接收服务: 读取 %s 的邮件内容失败: %v", cfg.protocolName(), s.conn.RemoteAddr(), err)
+		s.reset()
+		s.reply(451, "4.3.0 Error reading message")
+		return
+	}
+
+	envelope := &Envelope{
+		RemoteAddr: s.conn.RemoteAddr().String(),
+		Helo:       s.helo,
+		AuthUser:   s.authUser,
+		From:       s.from,
+	}
+	for _, rcpt := range s.rcpts {
+		envelope.Recipients = append(envelope.Recipients, rcpt.address)
+	}
+	data := append([]byte(s.receivedHeader()), body.Bytes()...)
+
+	// LMTP按收件人逐个返回投递结果，SMTP只要有一个收件人投递成功即接受
+	delivered := 0
+	for _, rcpt := range s.rcpts {
+		err := s.server.backend.Deliver(rcpt.mailboxID, envelope, data)
+		if err != nil {
+			log.Printf("%s接收服务: 投递到 %s 失败: %v", cfg.protocolName(), rcpt.address, err)
+		} else {
+			delivered++
+		}
+		if cfg.LMTP {
+			if err != nil {
+				s.reply(451, "4.3.0 <%s>: Delivery failed", rcpt.address)
+			} else {
+				s.reply(250, "2.0.0 <%s>: Delivered", rcpt.address)
+			}
+		}
+	}
+	if !cfg.LMTP {
+		if delivered == 0 {
+			s.reply(451, "4.3.0 Delivery failed")
+		} else {
+			s.reply(250, "2.0.0 Message accepted for delivery")
+		}
+	}
+
+	s.reset()
+}
+
+// receivedHeader 生成Received头，记录投递路径
+func (s *session) receivedHeader() string {
+	cfg := s.server.config
+	with := cfg.protocolName()
+	if strings.HasPrefix(with, "SMTP") {
+		with = "ESMTP"
+	}
+	if s.tls {
+		with += "S"
+	}
+	if s.authUser != "" {
+		with += "A"
+	}
+	// DotReader输出的邮件内容以LF换行，Received头保持一致
+	return fmt.Sprintf("Received: from %s (%s)\n\tby %s (emailAlert) with %s;\n\t%s\n",
+		s.helo, s.conn.RemoteAddr(), cfg.Hostname, with, time.Now().Format(time.RFC1123Z))
+}
+
+// reset 清除当前邮件事务
+func (s *session) reset() {
+	s.from = ""
+	s.hasFrom = false
+	s.rcpts = nil
+}
+
+// reply 发送单行响应
+func (s *session) reply(code int, format string, args ...interface{}) {
+	s.text.PrintfLine("%d %s", code, fmt.Sprintf(format, args...))
+}
+
+// replyLines 发送多行响应
+func (s *session) replyLines(code int, lines []string) {
+	for i, line := range lines {
+		separator := "-"
+		if i == len(lines)-1 {
+			separator = " "
+		}
+		s.text.PrintfLine("%d%s%s", code, separator, line)
+	}
+}
+
+// fail 发送错误响应并累计错误次数
+func (s *session) fail(code int, format string, args ...interface{}) {
+	s.errors++
+	s.reply(code, format, args...)
+}
+
+// parsePath 解析 "FROM:<address> PARAM=VALUE ..." 形式的参数
+func parsePath(args, prefix string) (string, []string, bool) {
+	if len(args) < len(prefix) || !strings.EqualFold(args[:len(prefix)], prefix) {
+		return "", nil, false
+	}
+	rest := strings.TrimSpace(args[len(prefix):])
+	if !strings.HasPrefix(rest, "<") {
+		return "", nil, false
+	}
+	end := strings.Index(rest, ">")
+	if end < 0 {
+		return "", nil, false
+	}
+
+	address := strings.TrimSpace(rest[1:end])
+	// 去掉源路由（如 <@a,@b:user@c>）
+	if strings.HasPrefix(address, "@") {
+		if idx := strings.Index(address, ":"); idx >= 0 {
+			address = address[idx+1:]
+		}
+	}
+	return address, strings.Fields(rest[end+1:]), true
+}
+
+// isClosedError 判断是否为连接关闭或超时导致的错误
+func isClosedError(err error) bool {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return true
+	}
+	return strings.Contains(err.Error(), "use of closed network connection")
+}
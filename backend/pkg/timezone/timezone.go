@@ -0,0 +1,79 @@
+package timezone
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	// 内嵌时区数据库，精简容器中没有系统时区数据时也能加载IANA时区
+	_ "time/tzdata"
+)
+
+// DefaultName 默认的系统时区
+const DefaultName = "Asia/Shanghai"
+
+var (
+	mutex  sync.RWMutex
+	system = mustLoad(DefaultName)
+)
+
+// Load 加载IANA时区（如 Asia/Shanghai、America/New_York、UTC）
+func Load(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("时区不能为空")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区 %q: %v", name, err)
+	}
+	return loc, nil
+}
+
+// Validate 校验时区名称，空字符串表示使用系统时区
+func Validate(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return nil
+	}
+	_, err := Load(name)
+	return err
+}
+
+// SetSystem 设置系统时区，用于日志、状态展示和未配置时区的渠道/用户
+func SetSystem(loc *time.Location) {
+	if loc == nil {
+		return
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	system = loc
+}
+
+// System 获取系统时区
+func System() *time.Location {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return system
+}
+
+// Resolve 获取展示用的时区，名称为空或无效时使用系统时区
+func Resolve(name string) *time.Location {
+	if strings.TrimSpace(name) == "" {
+		return System()
+	}
+	loc, err := Load(name)
+	if err != nil {
+		return System()
+	}
+	return loc
+}
+
+// mustLoad 加载默认时区，失败时使用UTC+8固定时区作为后备
+func mustLoad(name string) *time.Location {
+	loc, err := Load(name)
+	if err != nil {
+		return time.FixedZone("CST", 8*3600)
+	}
+	return loc
+}